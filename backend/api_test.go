@@ -0,0 +1,891 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/config"
+	"uniswap-campus-marketplace/handlers"
+	"uniswap-campus-marketplace/health"
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/middleware"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/ratelimit"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/router"
+	"uniswap-campus-marketplace/services"
+	"uniswap-campus-marketplace/signedurl"
+	"uniswap-campus-marketplace/storage"
+)
+
+// testAPI is the full HTTP stack wired to in-memory repositories and a
+// local store in a temporary directory.
+type testAPI struct {
+	t     *testing.T
+	rt    *router.Router
+	auth  *services.AuthService
+	users *repository.MemoryUserRepository
+}
+
+// routeRecorder collects the routes requested while TestEveryRouteIsExercised
+// runs the route tests; the tests do not run in parallel.
+var routeRecorder map[string]bool
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	users := repository.NewMemoryUserRepository()
+	listings := repository.NewMemoryListingRepository(users)
+	reports := repository.NewMemoryReportRepository()
+	uploads := repository.NewMemoryUploadRepository(users)
+	moderationRepo := repository.NewMemoryModerationRepository(uploads)
+	txManager := repository.NewMemoryTxManager(users, listings, reports, uploads, moderationRepo)
+	store := storage.NewLocal(t.TempDir(), "/uploads/")
+
+	authService := services.NewAuthService(users, "test-secret")
+	moderationService := services.NewModerationService(moderationRepo, uploads, listings, reports, users, txManager, 10)
+	listingService := services.NewListingService(listings, uploads, txManager, moderationService)
+	reportService := services.NewReportService(reports, listings)
+	uploadService := services.NewUploadService(uploads, moderationRepo, store, 100<<20, true)
+	accountService := services.NewAccountService(users, listings, reports, uploads, txManager, uploadService, store)
+	idempotencyService := services.NewIdempotencyService(repository.NewMemoryIdempotencyRepository(), 24*time.Hour)
+
+	a := &app{
+		cfg:              &config.Config{},
+		health:           health.NewChecker(time.Second),
+		rateLimits:       ratelimit.NewMemoryStore(),
+		optionalAuth:     middleware.OptionalAuth(authService),
+		requireModerator: middleware.RequireModerator(authService),
+		idempotent:       middleware.Idempotency(idempotencyService),
+	}
+	rt := router.New(middleware.Auth(authService))
+	a.registerRoutes(
+		rt,
+		handlers.NewAuthHandler(authService, handlers.SessionOptions{Enabled: true}),
+		handlers.NewListingHandler(listingService, reportService),
+		handlers.NewUploadHandler(uploadService, store, signedurl.NewSigner("test")),
+		handlers.NewModerationHandler(moderationService),
+		handlers.NewAccountHandler(accountService, handlers.SessionOptions{Enabled: true}),
+	)
+
+	return &testAPI{t: t, rt: rt, auth: authService, users: users}
+}
+
+// do serves req and records the route it exercised, so that the suite can
+// check that every route is covered.
+func (api *testAPI) do(route string, req *http.Request) *httptest.ResponseRecorder {
+	api.t.Helper()
+	if routeRecorder != nil {
+		routeRecorder[route] = true
+	}
+	rec := httptest.NewRecorder()
+	api.rt.ServeHTTP(rec, req)
+	return rec
+}
+
+// user registers an account through the service, bypassing the register
+// rate limit, and returns its token and ID.
+func (api *testAPI) user(email string, moderator bool) (string, int64) {
+	api.t.Helper()
+	resp, err := api.auth.Register(context.Background(), models.RegisterRequest{
+		FullName: "Test User",
+		Email:    email,
+		Password: "correct horse",
+	})
+	if err != nil {
+		api.t.Fatalf("register %s: %v", email, err)
+	}
+	api.users.SetModerator(resp.User.ID, moderator)
+	return resp.Token, resp.User.ID
+}
+
+func jsonRequest(method, path, token string, body interface{}) *http.Request {
+	var reader io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func uploadRequest(token, filename string, content []byte, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		_ = mw.WriteField(name, value)
+	}
+	if content != nil {
+		part, _ := mw.CreateFormFile("file", filename)
+		_, _ = part.Write(content)
+	}
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads/image", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(255 - 4*x), uint8(5 * y), 80, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// expect checks the status and, for errors, the error code of rec, and
+// decodes the data of a success envelope into data when it is not nil.
+func expect(t *testing.T, rec *httptest.ResponseRecorder, status int, code apierror.Code, data interface{}) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+	if code != "" {
+		var body struct {
+			Code apierror.Code `json:"code"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode error body: %v", err)
+		}
+		if body.Code != code {
+			t.Errorf("code = %s, want %s", body.Code, code)
+		}
+	}
+	if data != nil {
+		var body struct {
+			Success bool            `json:"success"`
+			Data    json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if !body.Success {
+			t.Errorf("success = false; body: %s", rec.Body.String())
+		}
+		if err := json.Unmarshal(body.Data, data); err != nil {
+			t.Fatalf("decode data: %v", err)
+		}
+	}
+}
+
+func TestSystemRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	for _, route := range []string{"/health", "/health/live", "/health/ready", "/metrics", "/api/openapi.json", "/api/docs"} {
+		t.Run(route, func(t *testing.T) {
+			rec := api.do("GET "+route, httptest.NewRequest(http.MethodGet, route, nil))
+			expect(t, rec, http.StatusOK, "", nil)
+		})
+	}
+}
+
+func TestAuthRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	const register, login, logout, me = "POST /api/auth/register", "POST /api/auth/login", "POST /api/auth/logout", "GET /api/auth/me"
+
+	t.Run("register", func(t *testing.T) {
+		rec := api.do(register, jsonRequest(http.MethodPost, "/api/auth/register", "", models.RegisterRequest{
+			FullName: "Ada Lovelace", Email: "ada@example.edu", Password: "correct horse",
+		}))
+		expect(t, rec, http.StatusCreated, "", nil)
+	})
+	t.Run("register invalid", func(t *testing.T) {
+		rec := api.do(register, jsonRequest(http.MethodPost, "/api/auth/register", "", models.RegisterRequest{Email: "nope"}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("register duplicate", func(t *testing.T) {
+		rec := api.do(register, jsonRequest(http.MethodPost, "/api/auth/register", "", models.RegisterRequest{
+			FullName: "Ada Again", Email: "ADA@example.edu", Password: "correct horse",
+		}))
+		expect(t, rec, http.StatusConflict, apierror.CodeEmailTaken, nil)
+	})
+	t.Run("register malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader("{"))
+		expect(t, api.do(register, req), http.StatusBadRequest, apierror.CodeInvalidRequestBody, nil)
+	})
+
+	var session struct {
+		Token string `json:"token"`
+	}
+	t.Run("login", func(t *testing.T) {
+		rec := api.do(login, jsonRequest(http.MethodPost, "/api/auth/login", "", models.LoginRequest{Email: "ada@example.edu", Password: "correct horse"}))
+		expect(t, rec, http.StatusOK, "", &session)
+		if session.Token == "" {
+			t.Error("login returned no token")
+		}
+		if len(rec.Result().Cookies()) == 0 {
+			t.Error("no session cookies set")
+		}
+	})
+	t.Run("login wrong password", func(t *testing.T) {
+		rec := api.do(login, jsonRequest(http.MethodPost, "/api/auth/login", "", models.LoginRequest{Email: "ada@example.edu", Password: "wrong"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeInvalidCredentials, nil)
+	})
+
+	t.Run("me", func(t *testing.T) {
+		var user models.User
+		expect(t, api.do(me, jsonRequest(http.MethodGet, "/api/auth/me", session.Token, nil)), http.StatusOK, "", &user)
+		if user.Email != "ada@example.edu" {
+			t.Errorf("me = %+v, want ada@example.edu", user)
+		}
+	})
+	t.Run("me without credentials", func(t *testing.T) {
+		expect(t, api.do(me, jsonRequest(http.MethodGet, "/api/auth/me", "", nil)), http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+	t.Run("me with malformed header", func(t *testing.T) {
+		req := jsonRequest(http.MethodGet, "/api/auth/me", "", nil)
+		req.Header.Set("Authorization", "Token abc")
+		expect(t, api.do(me, req), http.StatusUnauthorized, apierror.CodeAuthHeaderInvalid, nil)
+	})
+	t.Run("me with invalid token", func(t *testing.T) {
+		expect(t, api.do(me, jsonRequest(http.MethodGet, "/api/auth/me", "not-a-jwt", nil)), http.StatusUnauthorized, apierror.CodeTokenInvalid, nil)
+	})
+	t.Run("me with session cookie", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+		req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: session.Token})
+		expect(t, api.do(me, req), http.StatusOK, "", nil)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		expect(t, api.do(logout, jsonRequest(http.MethodPost, "/api/auth/logout", "", nil)), http.StatusOK, "", nil)
+	})
+}
+
+func TestListingRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	token, _ := api.user("ada@example.edu", false)
+	reporterToken, _ := api.user("bob@example.edu", false)
+	const list, create, get, report = "GET /api/listings", "POST /api/listings", "GET /api/listings/{id}", "POST /api/listings/{id}/report"
+	const remove = "DELETE /api/listings/{id}"
+
+	var uploaded models.UploadedImage
+	expect(t, api.do("POST /api/uploads/image", uploadRequest(token, "bike.png", testPNG(t), nil)), http.StatusCreated, "", &uploaded)
+
+	var listing models.Listing
+	t.Run("create", func(t *testing.T) {
+		rec := api.do(create, jsonRequest(http.MethodPost, "/api/listings", token, models.CreateListingRequest{
+			Title: "Road bike", Description: "Barely used", Price: 120, Category: "Other", ImageURLs: []string{uploaded.URL},
+		}))
+		expect(t, rec, http.StatusCreated, "", &listing)
+		if len(listing.Images) != 1 || listing.Images[0].Variants.Thumb == "" {
+			t.Errorf("listing images = %+v, want one with variants", listing.Images)
+		}
+	})
+	t.Run("create invalid", func(t *testing.T) {
+		rec := api.do(create, jsonRequest(http.MethodPost, "/api/listings", token, models.CreateListingRequest{Category: "Cars"}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("create with wrong field type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/listings", strings.NewReader(`{"price":"free"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		expect(t, api.do(create, req), http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("create unauthenticated", func(t *testing.T) {
+		rec := api.do(create, jsonRequest(http.MethodPost, "/api/listings", "", models.CreateListingRequest{Title: "Bike"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+	t.Run("create with session cookie but no CSRF token", func(t *testing.T) {
+		req := jsonRequest(http.MethodPost, "/api/listings", "", models.CreateListingRequest{Title: "Bike"})
+		req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: token})
+		expect(t, api.do(create, req), http.StatusForbidden, apierror.CodeCSRFTokenInvalid, nil)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var listings []models.Listing
+		expect(t, api.do(list, httptest.NewRequest(http.MethodGet, "/api/listings?search=road", nil)), http.StatusOK, "", &listings)
+		if len(listings) != 1 || listings[0].ID != listing.ID {
+			t.Errorf("listings = %+v, want the created listing", listings)
+		}
+	})
+
+	path := "/api/listings/" + strconv.FormatInt(listing.ID, 10)
+	t.Run("get", func(t *testing.T) {
+		var got models.Listing
+		expect(t, api.do(get, httptest.NewRequest(http.MethodGet, path, nil)), http.StatusOK, "", &got)
+		if got.Title != "Road bike" {
+			t.Errorf("listing = %+v", got)
+		}
+	})
+	t.Run("get missing", func(t *testing.T) {
+		expect(t, api.do(get, httptest.NewRequest(http.MethodGet, "/api/listings/999", nil)), http.StatusNotFound, apierror.CodeListingNotFound, nil)
+	})
+	t.Run("get invalid id", func(t *testing.T) {
+		expect(t, api.do(get, httptest.NewRequest(http.MethodGet, "/api/listings/abc", nil)), http.StatusNotFound, apierror.CodeNotFound, nil)
+	})
+	t.Run("wrong method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		api.rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, path, nil))
+		expect(t, rec, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, nil)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		rec := api.do(report, jsonRequest(http.MethodPost, path+"/report", reporterToken, models.CreateReportRequest{Reason: "scam"}))
+		expect(t, rec, http.StatusCreated, "", nil)
+	})
+	t.Run("report missing reason", func(t *testing.T) {
+		rec := api.do(report, jsonRequest(http.MethodPost, path+"/report", reporterToken, models.CreateReportRequest{}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("report missing listing", func(t *testing.T) {
+		rec := api.do(report, jsonRequest(http.MethodPost, "/api/listings/999/report", reporterToken, models.CreateReportRequest{Reason: "scam"}))
+		expect(t, rec, http.StatusNotFound, apierror.CodeListingNotFound, nil)
+	})
+	t.Run("report unauthenticated", func(t *testing.T) {
+		rec := api.do(report, jsonRequest(http.MethodPost, path+"/report", "", models.CreateReportRequest{Reason: "scam"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+
+	t.Run("delete someone else's listing", func(t *testing.T) {
+		expect(t, api.do(remove, jsonRequest(http.MethodDelete, path, reporterToken, nil)), http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("delete unauthenticated", func(t *testing.T) {
+		expect(t, api.do(remove, jsonRequest(http.MethodDelete, path, "", nil)), http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+	t.Run("delete", func(t *testing.T) {
+		expect(t, api.do(remove, jsonRequest(http.MethodDelete, path, token, nil)), http.StatusOK, "", nil)
+		expect(t, api.do(get, httptest.NewRequest(http.MethodGet, path, nil)), http.StatusNotFound, apierror.CodeListingNotFound, nil)
+		expect(t, api.do(remove, jsonRequest(http.MethodDelete, path, token, nil)), http.StatusNotFound, apierror.CodeListingNotFound, nil)
+	})
+}
+
+func TestListingUpdateRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	token, _ := api.user("ada@example.edu", false)
+	otherToken, _ := api.user("bob@example.edu", false)
+	const get, update = "GET /api/listings/{id}", "PUT /api/listings/{id}"
+
+	var listing models.Listing
+	rec := api.do("POST /api/listings", jsonRequest(http.MethodPost, "/api/listings", token, models.CreateListingRequest{
+		Title: "Road bike", Price: 120, Category: "Other",
+	}))
+	expect(t, rec, http.StatusCreated, "", &listing)
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("create ETag = %q, want \"1\"", etag)
+	}
+	path := "/api/listings/" + strconv.FormatInt(listing.ID, 10)
+	edit := models.UpdateListingRequest{Title: "Road bike, new tyres", Price: 140, Category: "Other"}
+	updateRequest := func(token, ifMatch string, body interface{}) *http.Request {
+		req := jsonRequest(http.MethodPut, path, token, body)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return req
+	}
+
+	t.Run("get sends ETag", func(t *testing.T) {
+		rec := api.do(get, httptest.NewRequest(http.MethodGet, path, nil))
+		expect(t, rec, http.StatusOK, "", nil)
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), etag)
+		}
+	})
+	t.Run("get not modified", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", `"7", W/`+etag)
+		rec := api.do(get, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+			t.Errorf("status = %d, body = %q, ETag = %q; want an empty 304 with the ETag", rec.Code, rec.Body, rec.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("update without If-Match", func(t *testing.T) {
+		expect(t, api.do(update, updateRequest(token, "", edit)), http.StatusPreconditionRequired, apierror.CodePreconditionRequired, nil)
+	})
+	t.Run("update with malformed If-Match", func(t *testing.T) {
+		expect(t, api.do(update, updateRequest(token, `W/"1"`, edit)), http.StatusPreconditionFailed, apierror.CodePreconditionFailed, nil)
+	})
+	t.Run("update someone else's listing", func(t *testing.T) {
+		expect(t, api.do(update, updateRequest(otherToken, etag, edit)), http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("update invalid", func(t *testing.T) {
+		rec := api.do(update, updateRequest(token, etag, models.UpdateListingRequest{Category: "Cars"}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("update unauthenticated", func(t *testing.T) {
+		expect(t, api.do(update, updateRequest("", etag, edit)), http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+	t.Run("update", func(t *testing.T) {
+		var updated models.Listing
+		rec := api.do(update, updateRequest(token, etag, edit))
+		expect(t, rec, http.StatusOK, "", &updated)
+		if updated.Title != edit.Title || updated.Version != 2 || rec.Header().Get("ETag") != `"2"` {
+			t.Errorf("updated = %+v, ETag %q; want version 2", updated, rec.Header().Get("ETag"))
+		}
+
+		// The old copy is stale now.
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		expect(t, api.do(get, req), http.StatusOK, "", nil)
+	})
+	t.Run("update from a stale copy", func(t *testing.T) {
+		edit.Title = "Overwritten"
+		expect(t, api.do(update, updateRequest(token, etag, edit)), http.StatusPreconditionFailed, apierror.CodePreconditionFailed, nil)
+	})
+	t.Run("update any version", func(t *testing.T) {
+		var updated models.Listing
+		edit.Title = "Road bike, serviced"
+		rec := api.do(update, updateRequest(token, "*", edit))
+		expect(t, rec, http.StatusOK, "", &updated)
+		if updated.Title != edit.Title || updated.Version != 3 {
+			t.Errorf("updated = %+v, want version 3", updated)
+		}
+	})
+	t.Run("update missing listing", func(t *testing.T) {
+		for _, ifMatch := range []string{etag, "*"} {
+			req := jsonRequest(http.MethodPut, "/api/listings/999", token, edit)
+			req.Header.Set("If-Match", ifMatch)
+			expect(t, api.do(update, req), http.StatusNotFound, apierror.CodeListingNotFound, nil)
+		}
+	})
+}
+
+func TestIdempotencyRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	token, _ := api.user("ada@example.edu", false)
+	otherToken, _ := api.user("bob@example.edu", false)
+	const list, create, report = "GET /api/listings", "POST /api/listings", "POST /api/listings/{id}/report"
+
+	bike := models.CreateListingRequest{Title: "Road bike", Price: 120, Category: "Other"}
+	withKey := func(req *http.Request, key string) *http.Request {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		return req
+	}
+	listingCount := func(t *testing.T) int {
+		t.Helper()
+		var listings []models.Listing
+		expect(t, api.do(list, httptest.NewRequest(http.MethodGet, "/api/listings", nil)), http.StatusOK, "", &listings)
+		return len(listings)
+	}
+
+	var first models.Listing
+	t.Run("create", func(t *testing.T) {
+		rec := api.do(create, withKey(jsonRequest(http.MethodPost, "/api/listings", token, bike), "create-1"))
+		expect(t, rec, http.StatusCreated, "", &first)
+		if rec.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+			t.Errorf("first response marked as replayed")
+		}
+	})
+	t.Run("retry replays the first response", func(t *testing.T) {
+		var replayed models.Listing
+		rec := api.do(create, withKey(jsonRequest(http.MethodPost, "/api/listings", token, bike), "create-1"))
+		expect(t, rec, http.StatusCreated, "", &replayed)
+		if replayed.ID != first.ID || rec.Header().Get(middleware.IdempotentReplayedHeader) != "true" || rec.Header().Get("ETag") != `"1"` {
+			t.Errorf("replayed = %+v, headers %v; want listing %d replayed with its ETag", replayed, rec.Header(), first.ID)
+		}
+		if n := listingCount(t); n != 1 {
+			t.Errorf("listings = %d, want 1", n)
+		}
+	})
+	t.Run("key reused with a different body", func(t *testing.T) {
+		lamp := models.CreateListingRequest{Title: "Desk lamp", Price: 15, Category: "Other"}
+		rec := api.do(create, withKey(jsonRequest(http.MethodPost, "/api/listings", token, lamp), "create-1"))
+		expect(t, rec, http.StatusConflict, apierror.CodeIdempotencyKeyReused, nil)
+	})
+	t.Run("same key from another user", func(t *testing.T) {
+		var other models.Listing
+		expect(t, api.do(create, withKey(jsonRequest(http.MethodPost, "/api/listings", otherToken, bike), "create-1")), http.StatusCreated, "", &other)
+		if other.ID == first.ID {
+			t.Errorf("another user's request replayed listing %d", first.ID)
+		}
+	})
+	t.Run("invalid key", func(t *testing.T) {
+		rec := api.do(create, withKey(jsonRequest(http.MethodPost, "/api/listings", token, bike), "has spaces"))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("without a key", func(t *testing.T) {
+		expect(t, api.do(create, jsonRequest(http.MethodPost, "/api/listings", token, bike)), http.StatusCreated, "", nil)
+		if n := listingCount(t); n != 3 {
+			t.Errorf("listings = %d, want 3", n)
+		}
+	})
+
+	path := "/api/listings/" + strconv.FormatInt(first.ID, 10) + "/report"
+	t.Run("report retried", func(t *testing.T) {
+		var filed, replayed models.Report
+		expect(t, api.do(report, withKey(jsonRequest(http.MethodPost, path, otherToken, models.CreateReportRequest{Reason: "scam"}), "report-1")), http.StatusCreated, "", &filed)
+		expect(t, api.do(report, withKey(jsonRequest(http.MethodPost, path, otherToken, models.CreateReportRequest{Reason: "scam"}), "report-1")), http.StatusCreated, "", &replayed)
+		if replayed.ID != filed.ID {
+			t.Errorf("replayed report %d, want %d", replayed.ID, filed.ID)
+		}
+	})
+	t.Run("failed request is replayed too", func(t *testing.T) {
+		req := func() *http.Request {
+			return withKey(jsonRequest(http.MethodPost, path, otherToken, models.CreateReportRequest{}), "report-2")
+		}
+		expect(t, api.do(report, req()), http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+		rec := api.do(report, req())
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+		if rec.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+			t.Errorf("validation failure not replayed")
+		}
+	})
+}
+
+func TestUploadRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	token, _ := api.user("ada@example.edu", false)
+	otherToken, otherID := api.user("bob@example.edu", false)
+	const upload, sign, file, private = "POST /api/uploads/image", "POST /api/uploads/private/sign", "GET /uploads/{key...}", "GET /uploads/private/{key...}"
+
+	var public models.UploadedImage
+	t.Run("upload", func(t *testing.T) {
+		expect(t, api.do(upload, uploadRequest(token, "photo.png", testPNG(t), nil)), http.StatusCreated, "", &public)
+		if !strings.HasPrefix(public.URL, "/uploads/") || public.Width != 64 || public.Height != 48 {
+			t.Errorf("uploaded = %+v", public)
+		}
+	})
+	t.Run("serve public upload", func(t *testing.T) {
+		rec := api.do(file, httptest.NewRequest(http.MethodGet, public.URL, nil))
+		expect(t, rec, http.StatusOK, "", nil)
+		if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+			t.Errorf("content type = %q, want image/png", ct)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=3600, must-revalidate" {
+			t.Errorf("cache control = %q, want a bounded max-age", cc)
+		}
+	})
+	t.Run("serve missing upload", func(t *testing.T) {
+		rec := api.do(file, httptest.NewRequest(http.MethodGet, "/uploads/0123456789abcdef0123456789abcdef.png", nil))
+		expect(t, rec, http.StatusNotFound, apierror.CodeNotFound, nil)
+	})
+	t.Run("post to upload", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		api.rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, public.URL, nil))
+		expect(t, rec, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, nil)
+	})
+	t.Run("upload not an image", func(t *testing.T) {
+		rec := api.do(upload, uploadRequest(token, "photo.png", []byte("definitely not a picture"), nil))
+		expect(t, rec, http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMedia, nil)
+	})
+	t.Run("upload without file", func(t *testing.T) {
+		expect(t, api.do(upload, uploadRequest(token, "", nil, nil)), http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("upload invalid visibility", func(t *testing.T) {
+		rec := api.do(upload, uploadRequest(token, "photo.png", testPNG(t), map[string]string{"visibility": "secret"}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("upload unauthenticated", func(t *testing.T) {
+		expect(t, api.do(upload, uploadRequest("", "photo.png", testPNG(t), nil)), http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+
+	var privateUpload models.UploadedImage
+	t.Run("upload private", func(t *testing.T) {
+		rec := api.do(upload, uploadRequest(token, "id.png", testPNG(t), map[string]string{"visibility": "private"}))
+		expect(t, rec, http.StatusCreated, "", &privateUpload)
+		if privateUpload.Key == "" || privateUpload.ExpiresAt == nil {
+			t.Errorf("private upload = %+v, want key and expiry", privateUpload)
+		}
+	})
+
+	t.Run("serve private upload", func(t *testing.T) {
+		rec := api.do(private, jsonRequest(http.MethodGet, privateUpload.URL, token, nil))
+		expect(t, rec, http.StatusOK, "", nil)
+		if cc := rec.Header().Get("Cache-Control"); cc != "private, no-store" {
+			t.Errorf("cache control = %q", cc)
+		}
+	})
+	t.Run("serve private upload to another user", func(t *testing.T) {
+		rec := api.do(private, jsonRequest(http.MethodGet, privateUpload.URL, otherToken, nil))
+		expect(t, rec, http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("serve private upload unsigned", func(t *testing.T) {
+		path := "/uploads/" + privateUpload.Key
+		expect(t, api.do(private, jsonRequest(http.MethodGet, path, token, nil)), http.StatusForbidden, apierror.CodeSignedURLInvalid, nil)
+	})
+
+	t.Run("sign for another user", func(t *testing.T) {
+		var signed models.SignedURL
+		rec := api.do(sign, jsonRequest(http.MethodPost, "/api/uploads/private/sign", token, models.SignURLRequest{Key: privateUpload.Key, UserID: &otherID}))
+		expect(t, rec, http.StatusOK, "", &signed)
+		expect(t, api.do(private, jsonRequest(http.MethodGet, signed.URL, otherToken, nil)), http.StatusOK, "", nil)
+	})
+	t.Run("sign someone else's upload", func(t *testing.T) {
+		rec := api.do(sign, jsonRequest(http.MethodPost, "/api/uploads/private/sign", otherToken, models.SignURLRequest{Key: privateUpload.Key}))
+		expect(t, rec, http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("sign unknown key", func(t *testing.T) {
+		rec := api.do(sign, jsonRequest(http.MethodPost, "/api/uploads/private/sign", token, models.SignURLRequest{Key: "private/1/missing.png"}))
+		expect(t, rec, http.StatusNotFound, apierror.CodeNotFound, nil)
+	})
+	t.Run("sign without key", func(t *testing.T) {
+		rec := api.do(sign, jsonRequest(http.MethodPost, "/api/uploads/private/sign", token, models.SignURLRequest{}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("sign unauthenticated", func(t *testing.T) {
+		rec := api.do(sign, jsonRequest(http.MethodPost, "/api/uploads/private/sign", "", models.SignURLRequest{Key: privateUpload.Key}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+}
+
+func TestModerationRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	sellerToken, _ := api.user("ada@example.edu", false)
+	scammerToken, _ := api.user("eve@example.edu", false)
+	moderatorToken, _ := api.user("mod@example.edu", true)
+	const queue, dismiss, remove = "GET /api/moderation/queue", "POST /api/moderation/matches/{id}/dismiss", "POST /api/moderation/images/remove"
+
+	// The same photo on two sellers' listings is flagged, twice over so that
+	// one match can be dismissed and the other resolved by removal.
+	var images []models.UploadedImage
+	for _, token := range []string{sellerToken, scammerToken, scammerToken} {
+		var uploaded models.UploadedImage
+		expect(t, api.do("POST /api/uploads/image", uploadRequest(token, "bike.png", testPNG(t), nil)), http.StatusCreated, "", &uploaded)
+		rec := api.do("POST /api/listings", jsonRequest(http.MethodPost, "/api/listings", token, models.CreateListingRequest{
+			Title: "Road bike", Price: 100, Category: "Other", ImageURLs: []string{uploaded.URL},
+		}))
+		expect(t, rec, http.StatusCreated, "", nil)
+		images = append(images, uploaded)
+	}
+
+	var items []models.ModerationQueueItem
+	t.Run("queue", func(t *testing.T) {
+		expect(t, api.do(queue, jsonRequest(http.MethodGet, "/api/moderation/queue", moderatorToken, nil)), http.StatusOK, "", &items)
+		if len(items) != 2 || len(items[0].ImageMatches) != 1 || len(items[1].ImageMatches) != 1 {
+			t.Fatalf("queue = %+v, want two listings with a match each", items)
+		}
+	})
+	t.Run("queue as regular user", func(t *testing.T) {
+		expect(t, api.do(queue, jsonRequest(http.MethodGet, "/api/moderation/queue", sellerToken, nil)), http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("queue unauthenticated", func(t *testing.T) {
+		expect(t, api.do(queue, jsonRequest(http.MethodGet, "/api/moderation/queue", "", nil)), http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+
+	t.Run("dismiss", func(t *testing.T) {
+		path := "/api/moderation/matches/" + strconv.FormatInt(items[0].ImageMatches[0].ID, 10) + "/dismiss"
+		expect(t, api.do(dismiss, jsonRequest(http.MethodPost, path, moderatorToken, nil)), http.StatusOK, "", nil)
+		expect(t, api.do(dismiss, jsonRequest(http.MethodPost, path, moderatorToken, nil)), http.StatusNotFound, apierror.CodeNotFound, nil)
+	})
+	t.Run("dismiss as regular user", func(t *testing.T) {
+		path := "/api/moderation/matches/" + strconv.FormatInt(items[1].ImageMatches[0].ID, 10) + "/dismiss"
+		expect(t, api.do(dismiss, jsonRequest(http.MethodPost, path, scammerToken, nil)), http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodPost, "/api/moderation/images/remove", moderatorToken, models.RemoveImageRequest{ImageURL: images[0].URL, Reason: "stock photo"}))
+		expect(t, rec, http.StatusOK, "", nil)
+
+		var left []models.ModerationQueueItem
+		expect(t, api.do(queue, jsonRequest(http.MethodGet, "/api/moderation/queue", moderatorToken, nil)), http.StatusOK, "", &left)
+		if len(left) != 0 {
+			t.Errorf("queue = %+v, want empty", left)
+		}
+
+		rec = api.do("POST /api/uploads/image", uploadRequest(scammerToken, "bike.png", testPNG(t), nil))
+		expect(t, rec, http.StatusUnprocessableEntity, apierror.CodeImageBlocked, nil)
+
+		for _, url := range []string{images[0].URL, imaging.VariantFilename(images[0].URL, "thumb")} {
+			rec = api.do("GET /uploads/{key...}", httptest.NewRequest(http.MethodGet, url, nil))
+			expect(t, rec, http.StatusGone, apierror.CodeImageRemoved, nil)
+		}
+	})
+	t.Run("remove invalid URL", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodPost, "/api/moderation/images/remove", moderatorToken, models.RemoveImageRequest{ImageURL: "https://example.com/x.png"}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("remove as regular user", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodPost, "/api/moderation/images/remove", scammerToken, models.RemoveImageRequest{ImageURL: images[1].URL}))
+		expect(t, rec, http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+}
+
+func TestModerationDeleteRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	sellerToken, sellerID := api.user("ada@example.edu", false)
+	moderatorToken, _ := api.user("mod@example.edu", true)
+	const deleteListing, restoreListing = "DELETE /api/moderation/listings/{id}", "POST /api/moderation/listings/{id}/restore"
+	const deleteUser, restoreUser = "DELETE /api/moderation/users/{id}", "POST /api/moderation/users/{id}/restore"
+
+	var listing models.Listing
+	rec := api.do("POST /api/listings", jsonRequest(http.MethodPost, "/api/listings", sellerToken, models.CreateListingRequest{
+		Title: "Road bike", Price: 100, Category: "Other",
+	}))
+	expect(t, rec, http.StatusCreated, "", &listing)
+	listingPath := "/api/moderation/listings/" + strconv.FormatInt(listing.ID, 10)
+	userPath := "/api/moderation/users/" + strconv.FormatInt(sellerID, 10)
+	listingVisible := func() bool {
+		rec := api.do("GET /api/listings/{id}", httptest.NewRequest(http.MethodGet, "/api/listings/"+strconv.FormatInt(listing.ID, 10), nil))
+		return rec.Code == http.StatusOK
+	}
+
+	t.Run("delete listing as regular user", func(t *testing.T) {
+		expect(t, api.do(deleteListing, jsonRequest(http.MethodDelete, listingPath, sellerToken, nil)), http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("delete and restore listing", func(t *testing.T) {
+		expect(t, api.do(deleteListing, jsonRequest(http.MethodDelete, listingPath, moderatorToken, nil)), http.StatusOK, "", nil)
+		if listingVisible() {
+			t.Fatal("deleted listing is still visible")
+		}
+
+		var restored models.Listing
+		expect(t, api.do(restoreListing, jsonRequest(http.MethodPost, listingPath+"/restore", moderatorToken, nil)), http.StatusOK, "", &restored)
+		if restored.ID != listing.ID || !listingVisible() {
+			t.Errorf("restored = %+v, want the listing back", restored)
+		}
+	})
+	t.Run("restore live listing", func(t *testing.T) {
+		expect(t, api.do(restoreListing, jsonRequest(http.MethodPost, listingPath+"/restore", moderatorToken, nil)), http.StatusNotFound, apierror.CodeListingNotFound, nil)
+	})
+
+	t.Run("delete user as regular user", func(t *testing.T) {
+		expect(t, api.do(deleteUser, jsonRequest(http.MethodDelete, userPath, sellerToken, nil)), http.StatusForbidden, apierror.CodeForbidden, nil)
+	})
+	t.Run("delete user", func(t *testing.T) {
+		expect(t, api.do(deleteUser, jsonRequest(http.MethodDelete, userPath, moderatorToken, nil)), http.StatusOK, "", nil)
+		if listingVisible() {
+			t.Error("listing of a deleted user is still visible")
+		}
+		rec := api.do("POST /api/auth/login", jsonRequest(http.MethodPost, "/api/auth/login", "", models.LoginRequest{Email: "ada@example.edu", Password: "correct horse"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeInvalidCredentials, nil)
+	})
+	t.Run("deleted user's token", func(t *testing.T) {
+		rec := api.do("POST /api/listings", jsonRequest(http.MethodPost, "/api/listings", sellerToken, models.CreateListingRequest{
+			Title: "Another bike", Price: 90, Category: "Other",
+		}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeTokenInvalid, nil)
+	})
+	t.Run("restore listing of deleted user", func(t *testing.T) {
+		expect(t, api.do(restoreListing, jsonRequest(http.MethodPost, listingPath+"/restore", moderatorToken, nil)), http.StatusConflict, apierror.CodeConflict, nil)
+	})
+	t.Run("restore user", func(t *testing.T) {
+		var restored models.User
+		expect(t, api.do(restoreUser, jsonRequest(http.MethodPost, userPath+"/restore", moderatorToken, nil)), http.StatusOK, "", &restored)
+		if restored.ID != sellerID || !listingVisible() {
+			t.Errorf("restored = %+v, want the user and their listing back", restored)
+		}
+	})
+	t.Run("restore live user", func(t *testing.T) {
+		expect(t, api.do(restoreUser, jsonRequest(http.MethodPost, userPath+"/restore", moderatorToken, nil)), http.StatusNotFound, apierror.CodeUserNotFound, nil)
+	})
+	t.Run("delete missing user", func(t *testing.T) {
+		expect(t, api.do(deleteUser, jsonRequest(http.MethodDelete, "/api/moderation/users/999", moderatorToken, nil)), http.StatusNotFound, apierror.CodeUserNotFound, nil)
+	})
+}
+
+func TestAccountRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	token, _ := api.user("ada@example.edu", false)
+	const export, remove = "GET /api/auth/me/export", "DELETE /api/auth/me"
+
+	var listing models.Listing
+	rec := api.do("POST /api/listings", jsonRequest(http.MethodPost, "/api/listings", token, models.CreateListingRequest{
+		Title: "Road bike", Price: 100, Category: "Other",
+	}))
+	expect(t, rec, http.StatusCreated, "", &listing)
+
+	t.Run("export json", func(t *testing.T) {
+		var data models.AccountExport
+		expect(t, api.do(export, jsonRequest(http.MethodGet, "/api/auth/me/export?format=json", token, nil)), http.StatusOK, "", &data)
+		if data.Profile.Email != "ada@example.edu" || len(data.Listings) != 1 || data.Listings[0].ID != listing.ID {
+			t.Errorf("export = %+v, want the profile and the listing", data)
+		}
+	})
+	t.Run("export archive", func(t *testing.T) {
+		rec := api.do(export, jsonRequest(http.MethodGet, "/api/auth/me/export", token, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+			t.Fatalf("status = %d, content type = %q; want 200 application/zip", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment;") {
+			t.Errorf("Content-Disposition = %q, want an attachment", rec.Header().Get("Content-Disposition"))
+		}
+		archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil || len(archive.File) == 0 || archive.File[0].Name != "account.json" {
+			t.Errorf("archive = %v, %v; want account.json first", archive, err)
+		}
+	})
+	t.Run("export unknown format", func(t *testing.T) {
+		rec := api.do(export, jsonRequest(http.MethodGet, "/api/auth/me/export?format=xml", token, nil))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("export without credentials", func(t *testing.T) {
+		expect(t, api.do(export, jsonRequest(http.MethodGet, "/api/auth/me/export", "", nil)), http.StatusUnauthorized, apierror.CodeAuthHeaderMissing, nil)
+	})
+
+	t.Run("delete without password", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodDelete, "/api/auth/me", token, models.DeleteAccountRequest{}))
+		expect(t, rec, http.StatusBadRequest, apierror.CodeValidationFailed, nil)
+	})
+	t.Run("delete with wrong password", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodDelete, "/api/auth/me", token, models.DeleteAccountRequest{Password: "wrong"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeInvalidCredentials, nil)
+	})
+	t.Run("delete", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodDelete, "/api/auth/me", token, models.DeleteAccountRequest{Password: "correct horse"}))
+		expect(t, rec, http.StatusOK, "", nil)
+		if len(rec.Result().Cookies()) == 0 {
+			t.Error("session cookies not cleared")
+		}
+
+		rec = api.do("GET /api/listings/{id}", httptest.NewRequest(http.MethodGet, "/api/listings/"+strconv.FormatInt(listing.ID, 10), nil))
+		expect(t, rec, http.StatusNotFound, apierror.CodeListingNotFound, nil)
+		rec = api.do("POST /api/auth/login", jsonRequest(http.MethodPost, "/api/auth/login", "", models.LoginRequest{Email: "ada@example.edu", Password: "correct horse"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeInvalidCredentials, nil)
+	})
+	t.Run("token after delete", func(t *testing.T) {
+		rec := api.do("GET /api/auth/me", jsonRequest(http.MethodGet, "/api/auth/me", token, nil))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeTokenInvalid, nil)
+		rec = api.do(export, jsonRequest(http.MethodGet, "/api/auth/me/export", token, nil))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeTokenInvalid, nil)
+	})
+	t.Run("delete twice", func(t *testing.T) {
+		rec := api.do(remove, jsonRequest(http.MethodDelete, "/api/auth/me", token, models.DeleteAccountRequest{Password: "correct horse"}))
+		expect(t, rec, http.StatusUnauthorized, apierror.CodeTokenInvalid, nil)
+	})
+}
+
+// TestEveryRouteIsExercised runs the route tests above and fails when a
+// registered route was not requested by any of them.
+func TestEveryRouteIsExercised(t *testing.T) {
+	covered := make(map[string]bool)
+	for name, test := range map[string]func(*testing.T){
+		"system":            TestSystemRoutes,
+		"auth":              TestAuthRoutes,
+		"listings":          TestListingRoutes,
+		"listing updates":   TestListingUpdateRoutes,
+		"idempotency":       TestIdempotencyRoutes,
+		"uploads":           TestUploadRoutes,
+		"moderation":        TestModerationRoutes,
+		"moderation delete": TestModerationDeleteRoutes,
+		"account":           TestAccountRoutes,
+	} {
+		t.Run(name, func(t *testing.T) {
+			routeRecorder = covered
+			defer func() { routeRecorder = nil }()
+			test(t)
+		})
+	}
+
+	for _, route := range newTestAPI(t).rt.Routes() {
+		if key := route.Method + " " + route.Pattern; !covered[key] {
+			t.Errorf("route %s has no API test", key)
+		}
+	}
+}
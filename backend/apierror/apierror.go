@@ -0,0 +1,231 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/services"
+	"uniswap-campus-marketplace/signedurl"
+	"uniswap-campus-marketplace/validation"
+)
+
+// Code is a stable, machine-readable error identifier. Clients should branch
+// on it rather than on the human-readable message.
+type Code string
+
+const (
+	CodeValidationFailed      Code = "VALIDATION_FAILED"
+	CodeInvalidRequestBody    Code = "INVALID_REQUEST_BODY"
+	CodeInvalidMultipartForm  Code = "INVALID_MULTIPART_FORM"
+	CodeUnsupportedMedia      Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeInvalidImage          Code = "INVALID_IMAGE"
+	CodeImageTooLarge         Code = "IMAGE_TOO_LARGE"
+	CodeFileTooLarge          Code = "FILE_TOO_LARGE"
+	CodeUploadQuotaExceeded   Code = "UPLOAD_QUOTA_EXCEEDED"
+	CodeImageBlocked          Code = "IMAGE_BLOCKED"
+	CodeImageRemoved          Code = "IMAGE_REMOVED"
+	CodeAuthHeaderMissing     Code = "AUTH_HEADER_MISSING"
+	CodeAuthHeaderInvalid     Code = "AUTH_HEADER_INVALID"
+	CodeTokenInvalid          Code = "TOKEN_INVALID"
+	CodeTokenExpired          Code = "TOKEN_EXPIRED"
+	CodeUnauthorized          Code = "UNAUTHORIZED"
+	CodeCSRFTokenInvalid      Code = "CSRF_TOKEN_INVALID"
+	CodeForbidden             Code = "FORBIDDEN"
+	CodeSignedURLInvalid      Code = "SIGNED_URL_INVALID"
+	CodeSignedURLExpired      Code = "SIGNED_URL_EXPIRED"
+	CodeInvalidCredentials    Code = "INVALID_CREDENTIALS"
+	CodeEmailTaken            Code = "EMAIL_TAKEN"
+	CodeUserNotFound          Code = "USER_NOT_FOUND"
+	CodeListingNotFound       Code = "LISTING_NOT_FOUND"
+	CodeNotFound              Code = "NOT_FOUND"
+	CodeConflict              Code = "CONFLICT"
+	CodePreconditionFailed    Code = "PRECONDITION_FAILED"
+	CodePreconditionRequired  Code = "PRECONDITION_REQUIRED"
+	CodeIdempotencyKeyReused  Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProgress Code = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeMethodNotAllowed      Code = "METHOD_NOT_ALLOWED"
+	CodeRateLimited           Code = "RATE_LIMITED"
+	CodeAccountLocked         Code = "ACCOUNT_LOCKED"
+	CodeInternal              Code = "INTERNAL_ERROR"
+)
+
+// Error is an API error: the HTTP status, stable code and message sent to
+// the client.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Details []validation.FieldError
+	// Meta carries machine-readable context such as a remaining allowance.
+	Meta map[string]any
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// WithMessage returns a copy of e carrying a more specific message.
+func (e *Error) WithMessage(message string) *Error {
+	clone := *e
+	clone.Message = message
+	return &clone
+}
+
+// WithDetails returns a copy of e carrying field-level details.
+func (e *Error) WithDetails(details []validation.FieldError) *Error {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// WithMeta returns a copy of e carrying machine-readable context.
+func (e *Error) WithMeta(meta map[string]any) *Error {
+	clone := *e
+	clone.Meta = meta
+	return &clone
+}
+
+// The error catalog. Every error the API answers with is one of these,
+// possibly with a more specific message or details.
+var (
+	ErrValidation            = New(http.StatusBadRequest, CodeValidationFailed, "validation failed")
+	ErrInvalidRequestBody    = New(http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+	ErrInvalidMultipartForm  = New(http.StatusBadRequest, CodeInvalidMultipartForm, "invalid multipart form")
+	ErrUnsupportedMedia      = New(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, "only JPEG, PNG, WebP and GIF images are accepted")
+	ErrInvalidImage          = New(http.StatusBadRequest, CodeInvalidImage, "file is not a valid image")
+	ErrImageTooLarge         = New(http.StatusRequestEntityTooLarge, CodeImageTooLarge, "image dimensions exceed the allowed maximum")
+	ErrFileTooLarge          = New(http.StatusRequestEntityTooLarge, CodeFileTooLarge, "file exceeds the maximum upload size")
+	ErrUploadQuotaExceeded   = New(http.StatusRequestEntityTooLarge, CodeUploadQuotaExceeded, "upload storage quota exceeded")
+	ErrImageBlocked          = New(http.StatusUnprocessableEntity, CodeImageBlocked, "this image was removed by a moderator and cannot be used again")
+	ErrImageRemoved          = New(http.StatusGone, CodeImageRemoved, "this image was removed by a moderator")
+	ErrAuthHeaderMissing     = New(http.StatusUnauthorized, CodeAuthHeaderMissing, "authorization header is required")
+	ErrAuthHeaderInvalid     = New(http.StatusUnauthorized, CodeAuthHeaderInvalid, "invalid authorization header format")
+	ErrTokenInvalid          = New(http.StatusUnauthorized, CodeTokenInvalid, "invalid token")
+	ErrTokenExpired          = New(http.StatusUnauthorized, CodeTokenExpired, "token has expired")
+	ErrUnauthorized          = New(http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
+	ErrCSRFTokenInvalid      = New(http.StatusForbidden, CodeCSRFTokenInvalid, "missing or invalid CSRF token")
+	ErrForbidden             = New(http.StatusForbidden, CodeForbidden, "you do not have access to this resource")
+	ErrSignedURLInvalid      = New(http.StatusForbidden, CodeSignedURLInvalid, "invalid or missing URL signature")
+	ErrSignedURLExpired      = New(http.StatusForbidden, CodeSignedURLExpired, "this link has expired")
+	ErrInvalidCredentials    = New(http.StatusUnauthorized, CodeInvalidCredentials, "invalid email or password")
+	ErrEmailTaken            = New(http.StatusConflict, CodeEmailTaken, "email already exists")
+	ErrUserNotFound          = New(http.StatusNotFound, CodeUserNotFound, "user not found")
+	ErrListingNotFound       = New(http.StatusNotFound, CodeListingNotFound, "listing not found")
+	ErrNotFound              = New(http.StatusNotFound, CodeNotFound, "resource not found")
+	ErrConflict              = New(http.StatusConflict, CodeConflict, "request conflicts with the current state of the resource")
+	ErrPreconditionFailed    = New(http.StatusPreconditionFailed, CodePreconditionFailed, "the resource was changed since you read it; fetch it again and retry")
+	ErrPreconditionRequired  = New(http.StatusPreconditionRequired, CodePreconditionRequired, "an If-Match header with the resource's ETag is required")
+	ErrIdempotencyKeyReused  = New(http.StatusConflict, CodeIdempotencyKeyReused, "this Idempotency-Key was already used for a different request")
+	ErrIdempotencyInProgress = New(http.StatusConflict, CodeIdempotencyInProgress, "a request with this Idempotency-Key is still being processed; retry shortly")
+	ErrMethodNotAllowed      = New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	ErrRateLimited           = New(http.StatusTooManyRequests, CodeRateLimited, "too many requests, try again later")
+	ErrAccountLocked         = New(http.StatusTooManyRequests, CodeAccountLocked, "account temporarily locked after repeated failed logins")
+	ErrInternal              = New(http.StatusInternalServerError, CodeInternal, "internal server error")
+)
+
+// Catalog lists every error in the catalog, e.g. for API documentation.
+func Catalog() []*Error {
+	return []*Error{
+		ErrValidation,
+		ErrInvalidRequestBody,
+		ErrInvalidMultipartForm,
+		ErrUnsupportedMedia,
+		ErrInvalidImage,
+		ErrImageTooLarge,
+		ErrFileTooLarge,
+		ErrUploadQuotaExceeded,
+		ErrImageBlocked,
+		ErrImageRemoved,
+		ErrAuthHeaderMissing,
+		ErrAuthHeaderInvalid,
+		ErrTokenInvalid,
+		ErrTokenExpired,
+		ErrUnauthorized,
+		ErrCSRFTokenInvalid,
+		ErrForbidden,
+		ErrSignedURLInvalid,
+		ErrSignedURLExpired,
+		ErrInvalidCredentials,
+		ErrEmailTaken,
+		ErrUserNotFound,
+		ErrListingNotFound,
+		ErrNotFound,
+		ErrConflict,
+		ErrPreconditionFailed,
+		ErrPreconditionRequired,
+		ErrIdempotencyKeyReused,
+		ErrIdempotencyInProgress,
+		ErrMethodNotAllowed,
+		ErrRateLimited,
+		ErrAccountLocked,
+		ErrInternal,
+	}
+}
+
+// sentinels maps service and repository errors onto the catalog. It is the
+// only place that knows about both layers' errors.
+var sentinels = []struct {
+	err    error
+	apiErr *Error
+}{
+	{repository.ErrEmailAlreadyExists, ErrEmailTaken},
+	{repository.ErrUserNotFound, ErrUserNotFound},
+	{repository.ErrListingNotFound, ErrListingNotFound},
+	{repository.ErrListingVersionMismatch, ErrPreconditionFailed},
+	{repository.ErrUploadNotFound, ErrNotFound},
+	{repository.ErrUploadQuotaExceeded, ErrUploadQuotaExceeded},
+	{repository.ErrUploadInUse, ErrConflict.WithMessage("an image was deleted or used by another listing in the meantime")},
+	{repository.ErrImageMatchNotFound, ErrNotFound},
+	{repository.ErrSellerNotFound, ErrUserNotFound},
+	{repository.ErrInvalidPrice, ErrValidation.WithMessage("price must not be negative")},
+	{services.ErrInvalidCredentials, ErrInvalidCredentials},
+	{services.ErrWrongPassword, ErrInvalidCredentials.WithMessage("password is incorrect")},
+	{services.ErrAccountLocked, ErrAccountLocked},
+	{services.ErrTokenExpired, ErrTokenExpired},
+	{services.ErrTokenInvalid, ErrTokenInvalid},
+	{services.ErrImageBlocked, ErrImageBlocked},
+	{services.ErrImageRemoved, ErrImageRemoved},
+	{services.ErrNotListingOwner, ErrForbidden.WithMessage("only the seller can change this listing")},
+	{services.ErrIdempotencyKeyReused, ErrIdempotencyKeyReused},
+	{services.ErrIdempotencyInProgress, ErrIdempotencyInProgress},
+	{services.ErrSellerDeleted, ErrConflict.WithMessage("the seller's account is deleted; restore it first")},
+	{imaging.ErrUnsupportedFormat, ErrUnsupportedMedia},
+	{imaging.ErrInvalidImage, ErrInvalidImage},
+	{imaging.ErrImageTooLarge, ErrImageTooLarge},
+	{signedurl.ErrInvalid, ErrSignedURLInvalid},
+	{signedurl.ErrExpired, ErrSignedURLExpired},
+	{signedurl.ErrWrongUser, ErrForbidden},
+	// Violations of constraints without a domain error of their own; they
+	// come last so that the domain errors above take precedence.
+	{repository.ErrUniqueViolation, ErrConflict},
+	{repository.ErrForeignKeyViolation, ErrConflict},
+	{repository.ErrCheckViolation, ErrValidation},
+}
+
+// FromError translates err into a catalog error, returning fallback when err
+// is not a known sentinel.
+func FromError(err error, fallback *Error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		return ErrValidation.WithDetails(fieldErrs)
+	}
+
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return s.apiErr
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,84 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"uniswap-campus-marketplace/validation"
+)
+
+const problemContentType = "application/problem+json"
+
+// envelope is the error form of the API's standard response envelope.
+type envelope struct {
+	Success bool                    `json:"success"`
+	Error   string                  `json:"error"`
+	Code    Code                    `json:"code"`
+	Details []validation.FieldError `json:"details,omitempty"`
+	Meta    map[string]any          `json:"meta,omitempty"`
+}
+
+// problem is an RFC 7807 problem details document with the error code, field
+// details and meta as extension members.
+type problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail"`
+	Instance string                  `json:"instance,omitempty"`
+	Code     Code                    `json:"code"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+	Meta     map[string]any          `json:"meta,omitempty"`
+}
+
+// Write sends e to the client. Clients that list application/problem+json in
+// Accept receive RFC 7807 problem details; everyone else gets the standard
+// envelope.
+func Write(w http.ResponseWriter, r *http.Request, e *Error) {
+	var (
+		contentType = "application/json"
+		payload     interface{}
+	)
+
+	if wantsProblem(r) {
+		contentType = problemContentType
+		payload = problem{
+			Type:     "urn:uniswap:error:" + string(e.Code),
+			Title:    http.StatusText(e.Status),
+			Status:   e.Status,
+			Detail:   e.Message,
+			Instance: r.URL.Path,
+			Code:     e.Code,
+			Errors:   e.Details,
+			Meta:     e.Meta,
+		}
+	} else {
+		payload = envelope{
+			Success: false,
+			Error:   e.Message,
+			Code:    e.Code,
+			Details: e.Details,
+			Meta:    e.Meta,
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(e.Status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func wantsProblem(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(mediaRange, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), problemContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
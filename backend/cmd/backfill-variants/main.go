@@ -0,0 +1,130 @@
+// Command backfill-variants generates missing image variants for uploads in
+// the configured storage, e.g. for images uploaded before variants existed
+// or after a new variant size is added. Run it from the backend directory:
+//
+//	go run ./cmd/backfill-variants [-dry-run]
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+
+	"uniswap-campus-marketplace/config"
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/logging"
+	"uniswap-campus-marketplace/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report missing variants without writing them")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("load config", err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.LogLevel)
+	if err != nil {
+		fatal("configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	store, err := storage.New(cfg.Storage())
+	if err != nil {
+		fatal("configure storage", err)
+	}
+
+	ctx := context.Background()
+	objects, err := store.List(ctx, "")
+	if err != nil {
+		fatal("list uploads", err)
+	}
+
+	existing := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		existing[obj.Key] = true
+	}
+
+	var generated, failed int
+	for _, obj := range objects {
+		if imaging.IsVariantFilename(obj.Key) {
+			continue
+		}
+		ext := path.Ext(obj.Key)
+		if ext != ".jpg" && ext != ".png" {
+			slog.Info("backfill: skipping unsupported file", "key", obj.Key)
+			continue
+		}
+
+		n, err := backfill(ctx, store, obj.Key, ext, existing, *dryRun)
+		if err != nil {
+			slog.Error("backfill: failed", "key", obj.Key, "err", err)
+			failed++
+			continue
+		}
+		generated += n
+	}
+
+	slog.Info("backfill: done", "generated", generated, "failed", failed, "dry_run", *dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// backfill stores the variants of one original that do not exist yet and
+// returns how many it stored.
+func backfill(ctx context.Context, store storage.Storage, key, ext string, existing map[string]bool, dryRun bool) (int, error) {
+	var missing []string
+	for _, v := range imaging.Variants {
+		if !existing[imaging.VariantFilename(key, v.Name)] {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	if dryRun {
+		slog.Info("backfill: missing variants", "key", key, "variants", missing)
+		return len(missing), nil
+	}
+
+	body, _, err := store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	img, err := imaging.Decode(data, imaging.DefaultLimits)
+	if err != nil {
+		return 0, err
+	}
+	variants, err := imaging.GenerateVariants(img, ext)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range missing {
+		variant := variants[name]
+		if err := store.Put(ctx, imaging.VariantFilename(key, name), bytes.NewReader(variant.Data), variant.ContentType); err != nil {
+			return 0, fmt.Errorf("store %s: %w", name, err)
+		}
+	}
+	slog.Info("backfill: generated variants", "key", key, "variants", missing)
+	return len(missing), nil
+}
+
+func fatal(message string, err error) {
+	slog.Error(message, "err", err)
+	os.Exit(1)
+}
@@ -5,12 +5,27 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"uniswap-campus-marketplace/storage"
+)
+
+// Database drivers, by their database/sql names.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
 // Config stores runtime configuration loaded from environment variables.
 type Config struct {
-	Port       string
+	Port string
+	// DBDriver is "postgres" or "sqlite". SQLite keeps everything in the
+	// single file DBPath, or in memory for ":memory:", and ignores the
+	// other DB settings.
+	DBDriver   string
+	DBPath     string
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -18,6 +33,84 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 	JWTSecret  string
+	LogLevel   string
+	UploadDir  string
+
+	// StorageBackend is "local" (files under UploadDir) or "s3". With a
+	// StoragePublicURL, clients are redirected to it for uploaded objects
+	// instead of the API serving them.
+	StorageBackend   string
+	StoragePublicURL string
+	S3Endpoint       string
+	S3Region         string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	// UploadQuotaBytes caps the storage one user's uploads may take.
+	// Uploads never attached to a listing are deleted once older than
+	// UploadOrphanMaxAge, checked every UploadSweepInterval.
+	UploadQuotaBytes    int64
+	UploadOrphanMaxAge  time.Duration
+	UploadSweepInterval time.Duration
+	// ImageMatchMaxDistance is the largest Hamming distance between two
+	// perceptual hashes treated as the same picture. BlockRemovedImages
+	// refuses uploads identical to images moderators removed.
+	ImageMatchMaxDistance int
+	BlockRemovedImages    bool
+	// UploadSigningSecret keys signed URLs to private uploads. It defaults
+	// to JWTSecret.
+	UploadSigningSecret string
+	// Soft-deleted users and listings are deleted for good once they have
+	// been deleted for DeletedRetention, checked every
+	// RetentionSweepInterval.
+	DeletedRetention       time.Duration
+	RetentionSweepInterval time.Duration
+	// IdempotencyKeyTTL is how long the response to a request sent with an
+	// Idempotency-Key is replayed; older keys are deleted every
+	// RetentionSweepInterval.
+	IdempotencyKeyTTL time.Duration
+
+	// TrustProxyHeaders takes the client address from X-Forwarded-For for
+	// rate limiting. Enable only behind a proxy that sets the header.
+	// TrustedProxyHops is how many proxies append to X-Forwarded-For in
+	// front of the server. TrustRealIP prefers X-Real-IP instead; enable it
+	// only when the proxy overwrites whatever X-Real-IP the client sent.
+	TrustProxyHeaders bool
+	TrustedProxyHops  int
+	TrustRealIP       bool
+
+	// AutoMigrate applies pending database migrations on startup.
+	AutoMigrate bool
+	// ShutdownDrainDelay is how long readiness reports not-ready before the
+	// server stops accepting connections.
+	ShutdownDrainDelay time.Duration
+
+	// JobWorkers background jobs run at once, idle workers looking for
+	// due jobs every JobPollInterval. On shutdown running jobs get
+	// JobDrainTimeout to finish. Finished jobs are deleted once older than
+	// JobRetention.
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobDrainTimeout time.Duration
+	JobRetention    time.Duration
+
+	// TracingExporter is "none", "stdout", "stdout-file" or "otlp-file".
+	// The stdout exporters write stdouttrace JSON, "otlp-file" writes
+	// OTLP/JSON lines a collector can ingest; TracingFile is the path spans
+	// are appended to by the file exporters.
+	TracingExporter string
+	TracingFile     string
+
+	// CORSAllowedOrigins are the browser origins allowed to call the API,
+	// e.g. the frontend dev server.
+	CORSAllowedOrigins []string
+	// SessionCookies makes login also start a cookie session, protected by
+	// a double-submit CSRF token, for browser clients.
+	SessionCookies bool
+	// SecureCookies marks session cookies Secure; HSTS sends
+	// Strict-Transport-Security. Enable both when served over HTTPS only.
+	SecureCookies bool
+	HSTS          bool
 }
 
 func Load() (*Config, error) {
@@ -28,6 +121,8 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		Port:       getConfigValue(fileValues, "PORT", "8080"),
+		DBDriver:   getConfigValue(fileValues, "DB_DRIVER", DriverPostgres),
+		DBPath:     getConfigValue(fileValues, "DB_PATH", "uniswap.db"),
 		DBHost:     getConfigValue(fileValues, "DB_HOST", "localhost"),
 		DBPort:     getConfigValue(fileValues, "DB_PORT", "5432"),
 		DBUser:     getConfigValue(fileValues, "DB_USER", "postgres"),
@@ -35,20 +130,186 @@ func Load() (*Config, error) {
 		DBName:     getConfigValue(fileValues, "DB_NAME", "uniswap"),
 		DBSSLMode:  getConfigValue(fileValues, "DB_SSLMODE", "disable"),
 		JWTSecret:  getConfigValue(fileValues, "JWT_SECRET", ""),
+		LogLevel:   getConfigValue(fileValues, "LOG_LEVEL", "info"),
+		UploadDir:  getConfigValue(fileValues, "UPLOAD_DIR", "uploads"),
+
+		StorageBackend:   getConfigValue(fileValues, "STORAGE_BACKEND", "local"),
+		StoragePublicURL: getConfigValue(fileValues, "STORAGE_PUBLIC_URL", ""),
+		S3Endpoint:       getConfigValue(fileValues, "S3_ENDPOINT", ""),
+		S3Region:         getConfigValue(fileValues, "S3_REGION", "us-east-1"),
+		S3Bucket:         getConfigValue(fileValues, "S3_BUCKET", ""),
+		S3AccessKey:      getConfigValue(fileValues, "S3_ACCESS_KEY", ""),
+		S3SecretKey:      getConfigValue(fileValues, "S3_SECRET_KEY", ""),
+
+		UploadSigningSecret: getConfigValue(fileValues, "UPLOAD_SIGNING_SECRET", ""),
+
+		TracingExporter: getConfigValue(fileValues, "TRACING_EXPORTER", "none"),
+		TracingFile:     getConfigValue(fileValues, "TRACING_FILE", "traces.jsonl"),
+	}
+
+	cfg.AutoMigrate, err = strconv.ParseBool(getConfigValue(fileValues, "DB_AUTO_MIGRATE", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("DB_AUTO_MIGRATE: %w", err)
+	}
+
+	cfg.TrustProxyHeaders, err = strconv.ParseBool(getConfigValue(fileValues, "TRUST_PROXY_HEADERS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("TRUST_PROXY_HEADERS: %w", err)
+	}
+
+	cfg.TrustedProxyHops, err = strconv.Atoi(getConfigValue(fileValues, "TRUSTED_PROXY_HOPS", "1"))
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXY_HOPS: %w", err)
+	}
+	if cfg.TrustedProxyHops < 1 {
+		return nil, fmt.Errorf("TRUSTED_PROXY_HOPS: must be at least 1")
+	}
+
+	cfg.TrustRealIP, err = strconv.ParseBool(getConfigValue(fileValues, "TRUST_REAL_IP", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("TRUST_REAL_IP: %w", err)
+	}
+
+	cfg.ShutdownDrainDelay, err = time.ParseDuration(getConfigValue(fileValues, "SHUTDOWN_DRAIN_DELAY", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("SHUTDOWN_DRAIN_DELAY: %w", err)
+	}
+
+	cfg.JobWorkers, err = strconv.Atoi(getConfigValue(fileValues, "JOB_WORKERS", "4"))
+	if err != nil {
+		return nil, fmt.Errorf("JOB_WORKERS: %w", err)
+	}
+	if cfg.JobWorkers < 1 {
+		return nil, fmt.Errorf("JOB_WORKERS: must be at least 1")
+	}
+
+	cfg.JobPollInterval, err = time.ParseDuration(getConfigValue(fileValues, "JOB_POLL_INTERVAL", "1s"))
+	if err != nil {
+		return nil, fmt.Errorf("JOB_POLL_INTERVAL: %w", err)
+	}
+	if cfg.JobPollInterval <= 0 {
+		return nil, fmt.Errorf("JOB_POLL_INTERVAL: must be positive")
+	}
+
+	cfg.JobDrainTimeout, err = time.ParseDuration(getConfigValue(fileValues, "JOB_DRAIN_TIMEOUT", "20s"))
+	if err != nil {
+		return nil, fmt.Errorf("JOB_DRAIN_TIMEOUT: %w", err)
+	}
+
+	cfg.JobRetention, err = time.ParseDuration(getConfigValue(fileValues, "JOB_RETENTION", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("JOB_RETENTION: %w", err)
+	}
+
+	cfg.UploadQuotaBytes, err = strconv.ParseInt(getConfigValue(fileValues, "UPLOAD_QUOTA_BYTES", "104857600"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("UPLOAD_QUOTA_BYTES: %w", err)
 	}
 
-	if cfg.DBPassword == "" {
-		return nil, fmt.Errorf("DB_PASSWORD is required")
+	cfg.UploadOrphanMaxAge, err = time.ParseDuration(getConfigValue(fileValues, "UPLOAD_ORPHAN_MAX_AGE", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("UPLOAD_ORPHAN_MAX_AGE: %w", err)
+	}
+
+	cfg.UploadSweepInterval, err = time.ParseDuration(getConfigValue(fileValues, "UPLOAD_SWEEP_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("UPLOAD_SWEEP_INTERVAL: %w", err)
+	}
+	if cfg.UploadSweepInterval <= 0 {
+		return nil, fmt.Errorf("UPLOAD_SWEEP_INTERVAL: must be positive")
+	}
+
+	cfg.DeletedRetention, err = time.ParseDuration(getConfigValue(fileValues, "DELETED_RETENTION", "720h"))
+	if err != nil {
+		return nil, fmt.Errorf("DELETED_RETENTION: %w", err)
+	}
+
+	cfg.RetentionSweepInterval, err = time.ParseDuration(getConfigValue(fileValues, "RETENTION_SWEEP_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("RETENTION_SWEEP_INTERVAL: %w", err)
+	}
+	if cfg.RetentionSweepInterval <= 0 {
+		return nil, fmt.Errorf("RETENTION_SWEEP_INTERVAL: must be positive")
+	}
+
+	cfg.IdempotencyKeyTTL, err = time.ParseDuration(getConfigValue(fileValues, "IDEMPOTENCY_KEY_TTL", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("IDEMPOTENCY_KEY_TTL: %w", err)
+	}
+
+	cfg.ImageMatchMaxDistance, err = strconv.Atoi(getConfigValue(fileValues, "IMAGE_MATCH_MAX_DISTANCE", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("IMAGE_MATCH_MAX_DISTANCE: %w", err)
+	}
+
+	cfg.BlockRemovedImages, err = strconv.ParseBool(getConfigValue(fileValues, "BLOCK_REMOVED_IMAGES", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("BLOCK_REMOVED_IMAGES: %w", err)
+	}
+
+	cfg.CORSAllowedOrigins = splitList(getConfigValue(fileValues, "CORS_ALLOWED_ORIGINS", "http://localhost:5173"))
+
+	cfg.SessionCookies, err = strconv.ParseBool(getConfigValue(fileValues, "SESSION_COOKIES", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("SESSION_COOKIES: %w", err)
+	}
+
+	cfg.SecureCookies, err = strconv.ParseBool(getConfigValue(fileValues, "SECURE_COOKIES", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("SECURE_COOKIES: %w", err)
+	}
+
+	cfg.HSTS, err = strconv.ParseBool(getConfigValue(fileValues, "HSTS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("HSTS: %w", err)
+	}
+
+	switch cfg.DBDriver {
+	case DriverPostgres:
+		if cfg.DBPassword == "" {
+			return nil, fmt.Errorf("DB_PASSWORD is required")
+		}
+	case DriverSQLite:
+	default:
+		return nil, fmt.Errorf("DB_DRIVER: must be %q or %q", DriverPostgres, DriverSQLite)
 	}
 
 	if cfg.JWTSecret == "" {
 		return nil, fmt.Errorf("JWT_SECRET is required")
 	}
+	if cfg.UploadSigningSecret == "" {
+		cfg.UploadSigningSecret = cfg.JWTSecret
+	}
 
 	return cfg, nil
 }
 
+// Storage returns the upload storage configuration. Without a public URL
+// objects are served by the API under /uploads/.
+func (c *Config) Storage() storage.Config {
+	baseURL := c.StoragePublicURL
+	if baseURL == "" {
+		baseURL = "/uploads/"
+	} else if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	return storage.Config{
+		Backend:     c.StorageBackend,
+		BaseURL:     baseURL,
+		LocalDir:    c.UploadDir,
+		S3Endpoint:  c.S3Endpoint,
+		S3Region:    c.S3Region,
+		S3Bucket:    c.S3Bucket,
+		S3AccessKey: c.S3AccessKey,
+		S3SecretKey: c.S3SecretKey,
+	}
+}
+
 func (c *Config) DatabaseDSN() string {
+	if c.DBDriver == DriverSQLite {
+		return SQLiteDSN(c.DBPath)
+	}
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.DBHost,
@@ -77,6 +338,17 @@ func getConfigValue(fileValues map[string]string, key, fallback string) string {
 	return fallback
 }
 
+// splitList parses a comma-separated config value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func loadResourceEnv(path string) (map[string]string, error) {
 	values := make(map[string]string)
 
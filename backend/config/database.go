@@ -7,19 +7,28 @@ import (
 	"time"
 )
 
-// Register a PostgreSQL driver in main.go before calling OpenDB.
+// Register the database drivers in main.go before calling OpenDB.
 // Example:
 // import _ "github.com/lib/pq"
+// import _ "modernc.org/sqlite"
 
-func OpenDB(ctx context.Context, dsn string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsn)
+func OpenDB(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	db.SetMaxOpenConns(20)
-	db.SetMaxIdleConns(10)
-	db.SetConnMaxLifetime(30 * time.Minute)
+	if driver == DriverSQLite {
+		// SQLite takes one writer at a time, and every connection to
+		// ":memory:" would get a database of its own, so a single
+		// connection is kept open for good.
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+	} else {
+		db.SetMaxOpenConns(20)
+		db.SetMaxIdleConns(10)
+		db.SetConnMaxLifetime(30 * time.Minute)
+	}
 
 	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -31,3 +40,10 @@ func OpenDB(ctx context.Context, dsn string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// SQLiteDSN returns the DSN of the SQLite database at path. Foreign keys are
+// off by default in SQLite; timestamps are written in a format SQLite's own
+// date functions understand.
+func SQLiteDSN(path string) string {
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+}
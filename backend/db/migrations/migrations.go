@@ -0,0 +1,162 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Files are named NNNN_description.sql and applied in version order, each in
+// its own transaction. Each database has a directory of its own, named after
+// its database/sql driver; a migration added to one needs its counterpart,
+// with the same version, in the other.
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// existsQueries report whether schema_migrations exists, per driver.
+var existsQueries = map[string]string{
+	"postgres": `SELECT to_regclass('schema_migrations') IS NOT NULL`,
+	"sqlite":   `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`,
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+// All returns every embedded migration for driver in version order.
+func All(driver string) ([]Migration, error) {
+	if _, ok := existsQueries[driver]; !ok {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+
+	paths, err := fs.Glob(files, driver+"/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimPrefix(path, driver+"/")
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", name)
+		}
+
+		body, err := files.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    strings.TrimSuffix(name, ".sql"),
+			SQL:     string(body),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Pending returns the migrations not yet recorded in schema_migrations.
+func Pending(ctx context.Context, db *sql.DB, driver string) ([]Migration, error) {
+	all, err := All(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(ctx, db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, m := range all {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Apply runs every pending migration.
+func Apply(ctx context.Context, db *sql.DB, driver string) error {
+	const createTable = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	pending, err := Pending(ctx, db, driver)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if err := apply(ctx, db, m); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "migrations: applied", "version", m.Version, "name", m.Name)
+	}
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin migration %s: %w", m.Name, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("run migration %s: %w", m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		return fmt.Errorf("record migration %s: %w", m.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB, driver string) (map[int64]bool, error) {
+	applied := make(map[int64]bool)
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, existsQueries[driver]).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,19 @@
+-- Bookkeeping for uploaded files: ownership for quotas, a content hash, and
+-- whether a listing uses the file so orphans can be swept.
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    storage_key TEXT NOT NULL UNIQUE,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    sha256 CHAR(64) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    visibility VARCHAR(10) NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'private')),
+    listing_id BIGINT REFERENCES listings(id) ON DELETE SET NULL,
+    attached_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_owner_id ON uploads(owner_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_sha256 ON uploads(sha256);
+CREATE INDEX IF NOT EXISTS idx_uploads_unattached ON uploads(created_at) WHERE attached_at IS NULL;
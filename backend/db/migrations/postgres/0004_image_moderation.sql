@@ -0,0 +1,31 @@
+-- Perceptual hashes of uploads, near-duplicate matches between different
+-- sellers' listings for the moderation queue, and hashes of images removed
+-- by moderators so they cannot simply be uploaded again.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS phash BIGINT;
+
+CREATE TABLE IF NOT EXISTS image_matches (
+    id BIGSERIAL PRIMARY KEY,
+    listing_id BIGINT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+    upload_id BIGINT NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    matched_upload_id BIGINT NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    distance SMALLINT NOT NULL CHECK (distance BETWEEN 0 AND 64),
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'removed')),
+    resolved_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (upload_id, matched_upload_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_matches_open ON image_matches(created_at) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_image_matches_matched_upload_id ON image_matches(matched_upload_id);
+
+CREATE TABLE IF NOT EXISTS blocked_images (
+    sha256 CHAR(64) PRIMARY KEY,
+    phash BIGINT,
+    reason TEXT NOT NULL DEFAULT '',
+    blocked_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- Listings carry a version, bumped by every change, for optimistic
+-- concurrency: an update names the version it was based on and fails if
+-- the listing has moved on since.
+
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
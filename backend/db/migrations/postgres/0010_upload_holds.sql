@@ -0,0 +1,4 @@
+-- An upload holding an image a moderator removed is kept as evidence: it
+-- is taken off its listing but, unlike other detached uploads, never swept.
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS held_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Progressive account lockout after repeated failed logins
+
+ALTER TABLE users ADD COLUMN failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
@@ -0,0 +1,8 @@
+-- Soft deletes: deleted users and listings are hidden but kept, together
+-- with the reports against them, until the retention job purges them.
+
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE listings ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_listings_deleted_at ON listings(deleted_at) WHERE deleted_at IS NOT NULL;
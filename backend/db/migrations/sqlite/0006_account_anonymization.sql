@@ -0,0 +1,5 @@
+-- Accounts closed by their owners are anonymized rather than deleted, so
+-- the reports they filed stay with the moderators. anonymized_at keeps them
+-- out of the retention purge and of moderator restores.
+
+ALTER TABLE users ADD COLUMN anonymized_at TIMESTAMP;
@@ -0,0 +1,25 @@
+-- The background job queue. Workers claim due pending jobs and hold them
+-- until locked_until; a running job whose lock ran out belonged to a worker
+-- that died and is claimed again. Jobs that keep failing end up dead, kept
+-- for inspection until purged with the succeeded ones. dedupe_key stops
+-- scheduled jobs from being enqueued twice; a key stays taken after its
+-- job finishes, until the job is purged.
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CONSTRAINT jobs_status_check CHECK (status IN ('pending', 'running', 'succeeded', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL CONSTRAINT jobs_max_attempts_check CHECK (max_attempts > 0),
+    run_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    last_error TEXT,
+    dedupe_key VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON jobs(locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs(finished_at) WHERE finished_at IS NOT NULL;
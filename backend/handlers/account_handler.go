@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/middleware"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/services"
+	"uniswap-campus-marketplace/validation"
+)
+
+// Export formats. The ZIP archive carries the uploaded files as well.
+const (
+	exportFormatZIP  = "zip"
+	exportFormatJSON = "json"
+)
+
+// exportWriteTimeout replaces the server's write timeout for an archive
+// download, which can hold every image the user ever uploaded.
+const exportWriteTimeout = 5 * time.Minute
+
+type AccountHandler struct {
+	accountService *services.AccountService
+	sessions       SessionOptions
+}
+
+func NewAccountHandler(accountService *services.AccountService, sessions SessionOptions) *AccountHandler {
+	return &AccountHandler{accountService: accountService, sessions: sessions}
+}
+
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = exportFormatZIP
+	}
+	v := validation.New()
+	v.OneOf("format", format, []string{exportFormatZIP, exportFormatJSON})
+	if err := v.Err(); err != nil {
+		writeServiceError(w, r, err, "invalid export format")
+		return
+	}
+
+	export, err := h.accountService.Export(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to export account")
+		return
+	}
+	for i := range export.Listings {
+		withImageVariants(&export.Listings[i])
+	}
+
+	if format == exportFormatJSON {
+		writeSuccess(w, http.StatusOK, export)
+		return
+	}
+
+	// Not every writer supports deadlines; the server timeout then applies.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	filename := fmt.Sprintf("uniswap-account-%d-%s.zip", userID, export.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := h.accountService.WriteArchive(r.Context(), w, export); err != nil {
+		// The status is already sent; all that is left is to cut the
+		// archive short, which the client sees as a corrupt download.
+		slog.ErrorContext(r.Context(), "account_handler.export: writing archive failed", "user_id", userID, "err", err)
+	}
+}
+
+// DeleteAccount closes the caller's account and ends their cookie session.
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.accountService.Delete(r.Context(), userID, req); err != nil {
+		writeServiceError(w, r, err, "failed to delete account")
+		return
+	}
+
+	middleware.ClearSessionCookies(w, h.sessions.Secure)
+	writeSuccess(w, http.StatusOK, map[string]string{
+		"message": "account deleted",
+	})
+}
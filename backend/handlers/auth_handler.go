@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"uniswap-campus-marketplace/apierror"
 	"uniswap-campus-marketplace/middleware"
 	"uniswap-campus-marketplace/models"
 	"uniswap-campus-marketplace/repository"
@@ -14,111 +17,106 @@ import (
 
 type AuthHandler struct {
 	authService *services.AuthService
+	sessions    SessionOptions
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+// SessionOptions controls cookie sessions. When Enabled, login also sets
+// the session and CSRF cookies so browsers can authenticate without keeping
+// the bearer token in script-readable storage.
+type SessionOptions struct {
+	Enabled bool
+	Secure  bool
 }
 
-func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	log.Printf("auth_handler.register: request method=%s path=%s", r.Method, r.URL.Path)
+func NewAuthHandler(authService *services.AuthService, sessions SessionOptions) *AuthHandler {
+	return &AuthHandler{authService: authService, sessions: sessions}
+}
 
-	if r.Method != http.MethodPost {
-		log.Printf("auth_handler.register: invalid method method=%s", r.Method)
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	slog.DebugContext(r.Context(), "auth_handler.register: request", "method", r.Method, "path", r.URL.Path)
 
 	var req models.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("auth_handler.register: decode failed err=%v", err)
-		writeError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeJSON(w, r, &req) {
+		slog.WarnContext(r.Context(), "auth_handler.register: decode failed")
 		return
 	}
 
 	_, err := h.authService.Register(r.Context(), req)
 	if err != nil {
-		log.Printf("auth_handler.register: service failed email=%s err=%v", req.Email, err)
-		switch {
-		case errors.Is(err, services.ErrValidation):
-			writeError(w, http.StatusBadRequest, err.Error())
-		case errors.Is(err, repository.ErrEmailAlreadyExists):
-			writeError(w, http.StatusConflict, "email already exists")
-		default:
-			writeError(w, http.StatusInternalServerError, "failed to register user")
-		}
+		slog.WarnContext(r.Context(), "auth_handler.register: service failed", "email", req.Email, "err", err)
+		writeServiceError(w, r, err, "failed to register user")
 		return
 	}
 
-	log.Printf("auth_handler.register: success email=%s", req.Email)
+	slog.DebugContext(r.Context(), "auth_handler.register: success", "email", req.Email)
 	writeSuccess(w, http.StatusCreated, map[string]string{
 		"message": "registration successful",
 	})
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	log.Printf("auth_handler.login: request method=%s path=%s", r.Method, r.URL.Path)
-
-	if r.Method != http.MethodPost {
-		log.Printf("auth_handler.login: invalid method method=%s", r.Method)
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
+	slog.DebugContext(r.Context(), "auth_handler.login: request", "method", r.Method, "path", r.URL.Path)
 
 	var req models.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("auth_handler.login: decode failed err=%v", err)
-		writeError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeJSON(w, r, &req) {
+		slog.WarnContext(r.Context(), "auth_handler.login: decode failed")
 		return
 	}
 
 	result, err := h.authService.Login(r.Context(), req)
 	if err != nil {
-		log.Printf("auth_handler.login: service failed email=%s err=%v", req.Email, err)
-		switch {
-		case errors.Is(err, services.ErrValidation):
-			writeError(w, http.StatusBadRequest, err.Error())
-		case errors.Is(err, services.ErrInvalidCredentials):
-			writeError(w, http.StatusUnauthorized, "invalid email or password")
-		default:
-			writeError(w, http.StatusInternalServerError, "failed to login")
+		slog.WarnContext(r.Context(), "auth_handler.login: service failed", "email", req.Email, "err", err)
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			retryAfter := int(math.Ceil(time.Until(lockedErr.Until).Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
 		}
+		writeServiceError(w, r, err, "failed to login")
 		return
 	}
 
-	log.Printf("auth_handler.login: success email=%s", req.Email)
+	if h.sessions.Enabled {
+		middleware.SetSessionCookies(w, result.Token, time.Now().Add(services.TokenTTL), h.sessions.Secure)
+	}
+
+	slog.DebugContext(r.Context(), "auth_handler.login: success", "email", req.Email)
 	writeSuccess(w, http.StatusOK, map[string]string{
 		"token": result.Token,
 	})
 }
 
-func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
-	log.Printf("auth_handler.me: request method=%s path=%s", r.Method, r.URL.Path)
+// Logout ends a cookie session. Bearer tokens are stateless and simply
+// discarded by the client.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	slog.DebugContext(r.Context(), "auth_handler.logout: request", "method", r.Method, "path", r.URL.Path)
 
-	if r.Method != http.MethodGet {
-		log.Printf("auth_handler.me: invalid method method=%s", r.Method)
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
+	middleware.ClearSessionCookies(w, h.sessions.Secure)
+	writeSuccess(w, http.StatusOK, map[string]string{
+		"message": "logged out",
+	})
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	slog.DebugContext(r.Context(), "auth_handler.me: request", "method", r.Method, "path", r.URL.Path)
 
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok || userID <= 0 {
-		log.Printf("auth_handler.me: missing or invalid user id in context")
-		writeError(w, http.StatusUnauthorized, "unauthorized")
+		slog.WarnContext(r.Context(), "auth_handler.me: missing or invalid user id in context")
+		writeError(w, r, apierror.ErrUnauthorized)
 		return
 	}
 
 	user, err := h.authService.GetUserByID(r.Context(), userID)
 	if err != nil {
-		log.Printf("auth_handler.me: service failed user_id=%d err=%v", userID, err)
+		slog.WarnContext(r.Context(), "auth_handler.me: service failed", "user_id", userID, "err", err)
 		if errors.Is(err, repository.ErrUserNotFound) {
-			writeError(w, http.StatusUnauthorized, "unauthorized")
+			writeError(w, r, apierror.ErrUnauthorized)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to fetch user")
+		writeError(w, r, apierror.ErrInternal.WithMessage("failed to fetch user"))
 		return
 	}
 
-	log.Printf("auth_handler.me: success user_id=%d", userID)
+	slog.DebugContext(r.Context(), "auth_handler.me: success", "user_id", userID)
 	writeSuccess(w, http.StatusOK, user)
 }
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// listingETag is the entity tag of a listing: its version, which every
+// change to the listing bumps.
+func listingETag(listing *models.Listing) string {
+	return `"` + strconv.FormatInt(listing.Version, 10) + `"`
+}
+
+// ifMatchVersion reads the listing version an If-Match header asks for:
+// zero for "*", which matches whatever version is current, or the version
+// named by a single strong tag as sent in ETag. Anything else reports false.
+func ifMatchVersion(header string) (int64, bool) {
+	tag := strings.TrimSpace(header)
+	if tag == "*" {
+		return 0, true
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// noneMatch reports whether an If-None-Match header lists etag, comparing
+// weakly as GET requires.
+func noneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified answers a conditional GET whose representation the
+// client already has.
+func writeNotModified(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -1,14 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 
+	"uniswap-campus-marketplace/apierror"
 	"uniswap-campus-marketplace/models"
-	"uniswap-campus-marketplace/repository"
 	"uniswap-campus-marketplace/services"
 )
 
@@ -24,148 +21,167 @@ func NewListingHandler(listingService *services.ListingService, reportService *s
 	}
 }
 
-func (h *ListingHandler) Listings(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodPost:
-		h.createListing(w, r)
-	case http.MethodGet:
-		h.getAllListings(w, r)
-	default:
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+func (h *ListingHandler) CreateListing(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
 	}
-}
 
-func (h *ListingHandler) ListingByIDRoutes(w http.ResponseWriter, r *http.Request) {
-	listingID, isReportRoute, ok := parseListingPath(r.URL.Path)
-	if !ok {
-		writeError(w, http.StatusNotFound, "resource not found")
+	var req models.CreateListingRequest
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if isReportRoute {
-		if r.Method != http.MethodPost {
-			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-			return
-		}
-		h.reportListing(w, r, listingID)
+	result, err := h.listingService.Create(r.Context(), userID, req)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to create listing")
 		return
 	}
 
-	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	withImageVariants(result)
+	w.Header().Set("ETag", listingETag(result))
+	writeSuccess(w, http.StatusCreated, result)
+}
+
+func (h *ListingHandler) GetAllListings(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	listings, err := h.listingService.GetAll(r.Context(), search)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to fetch listings")
 		return
 	}
 
-	h.getListingByID(w, r, listingID)
+	for i := range listings {
+		withImageVariants(&listings[i])
+	}
+	writeSuccess(w, http.StatusOK, listings)
 }
 
-func (h *ListingHandler) createListing(w http.ResponseWriter, r *http.Request) {
-	userID, ok := userIDFromContext(r)
+func (h *ListingHandler) GetListingByID(w http.ResponseWriter, r *http.Request) {
+	listingID, ok := listingIDFromPath(r)
 	if !ok {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
+		writeError(w, r, apierror.ErrNotFound)
 		return
 	}
 
-	var req models.CreateListingRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+	listing, err := h.listingService.GetByID(r.Context(), listingID)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to fetch listing")
 		return
 	}
 
-	result, err := h.listingService.Create(r.Context(), userID, req)
-	if err != nil {
-		switch {
-		case errors.Is(err, services.ErrValidation):
-			writeError(w, http.StatusBadRequest, err.Error())
-		default:
-			writeError(w, http.StatusInternalServerError, "failed to create listing")
-		}
+	// Caches may keep the listing but must revalidate it, which costs a 304
+	// while it is unchanged.
+	etag := listingETag(listing)
+	w.Header().Set("Cache-Control", "no-cache")
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		writeNotModified(w, etag)
 		return
 	}
 
-	writeSuccess(w, http.StatusCreated, result)
+	withImageVariants(listing)
+	w.Header().Set("ETag", etag)
+	writeSuccess(w, http.StatusOK, listing)
 }
 
-func (h *ListingHandler) getAllListings(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	listings, err := h.listingService.GetAll(r.Context(), search)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to fetch listings")
+// UpdateListing replaces a listing. The If-Match header must carry the ETag
+// the client's edit is based on, so that it cannot silently overwrite a
+// change made since.
+func (h *ListingHandler) UpdateListing(w http.ResponseWriter, r *http.Request) {
+	listingID, ok := listingIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
 		return
 	}
 
-	writeSuccess(w, http.StatusOK, listings)
-}
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
 
-func (h *ListingHandler) getListingByID(w http.ResponseWriter, r *http.Request, listingID int64) {
-	listing, err := h.listingService.GetByID(r.Context(), listingID)
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, r, apierror.ErrPreconditionRequired)
+		return
+	}
+	version, ok := ifMatchVersion(ifMatch)
+	if !ok {
+		writeError(w, r, apierror.ErrPreconditionFailed)
+		return
+	}
+
+	var req models.UpdateListingRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	listing, err := h.listingService.Update(r.Context(), userID, listingID, version, req)
 	if err != nil {
-		switch {
-		case errors.Is(err, repository.ErrListingNotFound):
-			writeError(w, http.StatusNotFound, "listing not found")
-		default:
-			writeError(w, http.StatusInternalServerError, "failed to fetch listing")
-		}
+		writeServiceError(w, r, err, "failed to update listing")
 		return
 	}
 
+	withImageVariants(listing)
+	w.Header().Set("ETag", listingETag(listing))
 	writeSuccess(w, http.StatusOK, listing)
 }
 
-func (h *ListingHandler) reportListing(w http.ResponseWriter, r *http.Request, listingID int64) {
+func (h *ListingHandler) ReportListing(w http.ResponseWriter, r *http.Request) {
+	listingID, ok := listingIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
+		writeError(w, r, apierror.ErrUnauthorized)
 		return
 	}
 
 	var req models.CreateReportRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
 	report, err := h.reportService.Create(r.Context(), listingID, userID, req)
 	if err != nil {
-		switch {
-		case errors.Is(err, services.ErrValidation):
-			writeError(w, http.StatusBadRequest, err.Error())
-		case errors.Is(err, repository.ErrListingNotFound):
-			writeError(w, http.StatusNotFound, "listing not found")
-		default:
-			writeError(w, http.StatusInternalServerError, "failed to report listing")
-		}
+		writeServiceError(w, r, err, "failed to report listing")
 		return
 	}
 
 	writeSuccess(w, http.StatusCreated, report)
 }
 
-func parseListingPath(path string) (int64, bool, bool) {
-	trimmed := strings.TrimPrefix(path, "/api/listings/")
-	if trimmed == path || trimmed == "" {
-		return 0, false, false
+func (h *ListingHandler) DeleteListing(w http.ResponseWriter, r *http.Request) {
+	listingID, ok := listingIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
 	}
 
-	parts := strings.Split(strings.Trim(trimmed, "/"), "/")
-	if len(parts) == 0 || parts[0] == "" {
-		return 0, false, false
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
 	}
 
-	id, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil || id <= 0 {
-		return 0, false, false
+	if err := h.listingService.Delete(r.Context(), userID, listingID); err != nil {
+		writeServiceError(w, r, err, "failed to delete listing")
+		return
 	}
 
-	// Allowed routes:
-	// /api/listings/{id}
-	// /api/listings/{id}/report
-	if len(parts) == 1 {
-		return id, false, true
-	}
-	if len(parts) == 2 && parts[1] == "report" {
-		return id, true, true
-	}
+	writeSuccess(w, http.StatusOK, map[string]string{"message": "listing deleted"})
+}
 
-	return 0, false, false
+// listingIDFromPath reads the {id} wildcard of /api/listings/{id} and
+// /api/moderation/listings/{id} routes.
+func listingIDFromPath(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/services"
+)
+
+type ModerationHandler struct {
+	moderationService *services.ModerationService
+}
+
+func NewModerationHandler(moderationService *services.ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderationService: moderationService}
+}
+
+func (h *ModerationHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	queue, err := h.moderationService.Queue(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err, "failed to fetch moderation queue")
+		return
+	}
+
+	for i := range queue {
+		withImageVariants(&queue[i].Listing)
+	}
+	writeSuccess(w, http.StatusOK, queue)
+}
+
+func (h *ModerationHandler) DismissMatch(w http.ResponseWriter, r *http.Request) {
+	matchID, ok := matchIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
+	moderatorID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.moderationService.DismissMatch(r.Context(), moderatorID, matchID); err != nil {
+		writeServiceError(w, r, err, "failed to dismiss image match")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, map[string]string{"message": "image match dismissed"})
+}
+
+func (h *ModerationHandler) RemoveImage(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req models.RemoveImageRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	blocked, err := h.moderationService.RemoveImage(r.Context(), moderatorID, req)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to remove image")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, blocked)
+}
+
+func (h *ModerationHandler) DeleteListing(w http.ResponseWriter, r *http.Request) {
+	listingID, ok := listingIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
+	moderatorID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.moderationService.DeleteListing(r.Context(), moderatorID, listingID); err != nil {
+		writeServiceError(w, r, err, "failed to delete listing")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, map[string]string{"message": "listing deleted"})
+}
+
+func (h *ModerationHandler) RestoreListing(w http.ResponseWriter, r *http.Request) {
+	listingID, ok := listingIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
+	moderatorID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	listing, err := h.moderationService.RestoreListing(r.Context(), moderatorID, listingID)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to restore listing")
+		return
+	}
+
+	withImageVariants(listing)
+	writeSuccess(w, http.StatusOK, listing)
+}
+
+func (h *ModerationHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
+	moderatorID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.moderationService.DeleteUser(r.Context(), moderatorID, userID); err != nil {
+		writeServiceError(w, r, err, "failed to delete user")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, map[string]string{"message": "user deleted"})
+}
+
+func (h *ModerationHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromPath(r)
+	if !ok {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
+	moderatorID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	user, err := h.moderationService.RestoreUser(r.Context(), moderatorID, userID)
+	if err != nil {
+		writeServiceError(w, r, err, "failed to restore user")
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, user)
+}
+
+// matchIDFromPath reads the {id} wildcard of /api/moderation/matches/{id}
+// routes.
+func matchIDFromPath(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// userIDFromPath reads the {id} wildcard of /api/moderation/users/{id}
+// routes.
+func userIDFromPath(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/validation"
+)
+
+// decodeJSON decodes the request body into dst. When the body cannot be
+// decoded it answers 400 itself, naming the offending field when a value has
+// the wrong JSON type, and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if err == nil {
+		return true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		writeError(w, r, apierror.ErrValidation.WithDetails([]validation.FieldError{{
+			Field:   typeErr.Field,
+			Code:    validation.CodeInvalidType,
+			Message: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String())),
+		}}))
+		return false
+	}
+
+	writeError(w, r, apierror.ErrInvalidRequestBody)
+	return false
+}
+
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "float32", "float64", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "number"
+	case "slice", "array":
+		return "list"
+	default:
+		return "object"
+	}
+}
@@ -2,13 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+
+	"uniswap-campus-marketplace/apierror"
 )
 
 type apiResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
 }
 
 func writeSuccess(w http.ResponseWriter, status int, data interface{}) {
@@ -18,11 +20,19 @@ func writeSuccess(w http.ResponseWriter, status int, data interface{}) {
 	})
 }
 
-func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, apiResponse{
-		Success: false,
-		Error:   message,
-	})
+// writeError answers with an error from the apierror catalog.
+func writeError(w http.ResponseWriter, r *http.Request, apiErr *apierror.Error) {
+	apierror.Write(w, r, apiErr)
+}
+
+// writeServiceError maps a service or repository error through the catalog.
+// Unknown errors become a 500 carrying message.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	apiErr := apierror.FromError(err, apierror.ErrInternal.WithMessage(message))
+	if apiErr.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "handler: request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+	}
+	apierror.Write(w, r, apiErr)
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload apiResponse) {
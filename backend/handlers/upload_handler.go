@@ -1,71 +1,315 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/services"
+	"uniswap-campus-marketplace/signedurl"
+	"uniswap-campus-marketplace/storage"
+	"uniswap-campus-marketplace/validation"
 )
 
-type UploadHandler struct{}
+type UploadHandler struct {
+	uploadService *services.UploadService
+	store         storage.Storage
+	signer        *signedurl.Signer
+}
 
-func NewUploadHandler() *UploadHandler {
-	return &UploadHandler{}
+func NewUploadHandler(uploadService *services.UploadService, store storage.Storage, signer *signedurl.Signer) *UploadHandler {
+	return &UploadHandler{uploadService: uploadService, store: store, signer: signer}
 }
 
+// Uploads are public by default: listing photos served to anyone and cached
+// by browsers and CDNs. Private uploads (report evidence, chat attachments)
+// are only served through short-lived signed URLs.
+const signedURLTTL = 15 * time.Minute
+
+// maxImageBytes caps the size of an uploaded file; the multipart envelope
+// may add a little on top.
+const (
+	maxImageBytes   = 10 << 20
+	maxRequestBytes = maxImageBytes + 1<<20
+)
+
+// UploadImage stores an image after checking what it really is: the format
+// is detected from the content, never from the file name, and the pixels are
+// decoded and re-encoded so metadata such as GPS coordinates is dropped.
 func (h *UploadHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
 		return
 	}
 
-	if _, ok := userIDFromContext(r); !ok {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	if err := r.ParseMultipartForm(maxRequestBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, apierror.ErrFileTooLarge)
+			return
+		}
+		writeError(w, r, apierror.ErrInvalidMultipartForm)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid multipart form")
+	visibility := r.FormValue("visibility")
+	if visibility == "" {
+		visibility = models.UploadPublic
+	}
+	v := validation.New()
+	v.OneOf("visibility", visibility, []string{models.UploadPublic, models.UploadPrivate})
+	if err := v.Err(); err != nil {
+		writeServiceError(w, r, err, "invalid visibility")
 		return
 	}
+	visibility = strings.ToLower(visibility)
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "file is required")
+		writeError(w, r, apierror.ErrValidation.WithDetails([]validation.FieldError{{
+			Field:   "file",
+			Code:    validation.CodeRequired,
+			Message: "file is required",
+		}}))
 		return
 	}
 	defer file.Close()
 
-	uploadsDir := "uploads"
-	if err := os.MkdirAll(uploadsDir, 0o755); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to prepare upload directory")
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "upload_handler.upload_image: read file failed", "err", err)
+		writeError(w, r, apierror.ErrInvalidMultipartForm)
+		return
+	}
+	if len(data) > maxImageBytes {
+		writeError(w, r, apierror.ErrFileTooLarge)
 		return
 	}
 
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = ".bin"
+	decoded, img, err := imaging.Process(data, imaging.DefaultLimits)
+	if err != nil {
+		slog.WarnContext(r.Context(), "upload_handler.upload_image: image rejected", "filename", header.Filename, "size", header.Size, "err", err)
+		writeError(w, r, apierror.FromError(err, apierror.ErrInvalidImage))
+		return
 	}
 
-	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), strings.ToLower(ext))
-	dstPath := filepath.Join(uploadsDir, filename)
+	var variants map[string]*imaging.Image
+	if visibility == models.UploadPublic {
+		variants, err = imaging.GenerateVariants(decoded, img.Extension)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "upload_handler.upload_image: generate variants failed", "err", err)
+			writeError(w, r, apierror.ErrInternal.WithMessage("failed to process image"))
+			return
+		}
+	}
 
-	dst, err := os.Create(dstPath)
+	upload, err := h.uploadService.Save(r.Context(), userID, visibility, img, variants)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to save file")
+		slog.WarnContext(r.Context(), "upload_handler.upload_image: save failed", "user_id", userID, "err", err)
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			writeError(w, r, apierror.ErrUploadQuotaExceeded.WithMeta(map[string]any{
+				"quota_bytes":     quotaErr.Quota,
+				"used_bytes":      quotaErr.Used,
+				"requested_bytes": quotaErr.Requested,
+				"remaining_bytes": quotaErr.Remaining(),
+			}))
+			return
+		}
+		writeServiceError(w, r, err, "failed to save file")
 		return
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to save file")
+	url := uploadURLPrefix + upload.Key
+	if visibility == models.UploadPrivate {
+		// Private uploads have no variants; every size points at the
+		// signed original.
+		signed, expires := h.signer.Sign(url, upload.Key, userID, signedURLTTL)
+		writeSuccess(w, http.StatusCreated, models.UploadedImage{
+			URL:       signed,
+			Key:       upload.Key,
+			ExpiresAt: &expires,
+			Width:     img.Width,
+			Height:    img.Height,
+			Variants:  models.ImageVariants{Thumb: signed, Card: signed, Full: signed},
+		})
 		return
 	}
 
-	writeSuccess(w, http.StatusCreated, map[string]string{
-		"url": "/" + filepath.ToSlash(dstPath),
+	writeSuccess(w, http.StatusCreated, models.UploadedImage{
+		URL:      url,
+		Width:    img.Width,
+		Height:   img.Height,
+		Variants: imageVariants(url),
 	})
 }
+
+// SignURL issues a fresh signed URL for one of the caller's private uploads,
+// bound to the caller or to the user they share it with.
+func (h *UploadHandler) SignURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		writeError(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req models.SignURLRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	v := validation.New()
+	v.Required("key", req.Key)
+	if req.UserID != nil && *req.UserID <= 0 {
+		v.Add("user_id", validation.CodeOutOfRange, "must be a positive user id")
+	}
+	if err := v.Err(); err != nil {
+		writeServiceError(w, r, err, "invalid request")
+		return
+	}
+
+	upload, err := h.uploadService.GetByKey(r.Context(), req.Key)
+	if err != nil {
+		slog.WarnContext(r.Context(), "upload_handler.sign_url: lookup failed", "key", req.Key, "err", err)
+		writeServiceError(w, r, err, "failed to sign url")
+		return
+	}
+	if upload.OwnerID != userID || upload.Visibility != models.UploadPrivate {
+		writeError(w, r, apierror.ErrForbidden)
+		return
+	}
+
+	boundTo := userID
+	if req.UserID != nil {
+		boundTo = *req.UserID
+	}
+	url, expires := h.signer.Sign(uploadURLPrefix+req.Key, req.Key, boundTo, signedURLTTL)
+	writeSuccess(w, http.StatusOK, models.SignedURL{URL: url, ExpiresAt: expires})
+}
+
+// uploadURLPrefix is where uploads are served by File. Listings store these
+// URLs, so they stay valid whichever storage backend holds the objects.
+const uploadURLPrefix = "/uploads/"
+
+// imageVariants returns the variant URLs of an uploaded image URL.
+func imageVariants(url string) models.ImageVariants {
+	if !strings.HasPrefix(url, uploadURLPrefix) {
+		return models.ImageVariants{Thumb: url, Card: url, Full: url}
+	}
+	return models.ImageVariants{
+		Thumb: imaging.VariantFilename(url, "thumb"),
+		Card:  imaging.VariantFilename(url, "card"),
+		Full:  imaging.VariantFilename(url, "full"),
+	}
+}
+
+// withImageVariants fills in the variant URLs of a listing's images.
+func withImageVariants(listing *models.Listing) {
+	for i := range listing.Images {
+		listing.Images[i].Variants = imageVariants(listing.Images[i].URL)
+	}
+}
+
+// uploadContentSecurityPolicy sandboxes uploaded files so that a file
+// which is not the image it claims to be cannot run script on our origin.
+const uploadContentSecurityPolicy = "default-src 'none'; img-src 'self'; sandbox"
+
+// publicUploadCacheControl bounds how long browsers and shared caches keep
+// a public upload. Keys are never rewritten, but a moderator can remove an
+// image at any time, and caches must stop serving it soon after.
+const publicUploadCacheControl = "public, max-age=3600, must-revalidate"
+
+// File serves a public upload from storage, unless a moderator removed it.
+// When the backend has its own public URL for an object (e.g. a CDN in
+// front of a bucket) the client is redirected there instead; the redirect
+// is not cached, so it stops once the image is removed.
+func (h *UploadHandler) File(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if storage.ValidateKey(key) != nil || strings.HasPrefix(key, services.PrivateKeyPrefix) {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+	// Checked before redirecting, too, so that a removed image's URL does
+	// not lead to its copy on a CDN.
+	if err := h.uploadService.CheckServable(r.Context(), key); err != nil {
+		writeServiceError(w, r, err, "failed to load file")
+		return
+	}
+
+	if target := h.store.URL(key); target != uploadURLPrefix+key {
+		w.Header().Set("Cache-Control", "no-cache")
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	h.serveObject(w, r, key, publicUploadCacheControl)
+}
+
+// PrivateFile serves a private upload after checking its signed URL. The
+// response must not be cached or leak the URL through the Referer header.
+func (h *UploadHandler) PrivateFile(w http.ResponseWriter, r *http.Request) {
+	key := services.PrivateKeyPrefix + r.PathValue("key")
+	if storage.ValidateKey(key) != nil {
+		writeError(w, r, apierror.ErrNotFound)
+		return
+	}
+
+	requesterID, _ := userIDFromContext(r)
+	if err := h.signer.Verify(key, r.URL.Query(), requesterID); err != nil {
+		slog.WarnContext(r.Context(), "upload_handler.private_file: signature rejected", "key", key, "user_id", requesterID, "err", err)
+		writeError(w, r, apierror.FromError(err, apierror.ErrSignedURLInvalid))
+		return
+	}
+
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	h.serveObject(w, r, key, "private, no-store")
+}
+
+// serveObject streams an object with headers that stop browsers from
+// treating it as anything but the image it is.
+func (h *UploadHandler) serveObject(w http.ResponseWriter, r *http.Request, key, cacheControl string) {
+	body, obj, err := h.store.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, apierror.ErrNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "upload_handler.serve_object: get failed", "key", key, "err", err)
+		writeError(w, r, apierror.ErrInternal)
+		return
+	}
+	defer body.Close()
+
+	header := w.Header()
+	header.Set("Content-Security-Policy", uploadContentSecurityPolicy)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cross-Origin-Resource-Policy", "cross-origin")
+	header.Set("Cache-Control", cacheControl)
+	contentType := obj.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	if obj.Size > 0 {
+		header.Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	if !obj.LastModified.IsZero() {
+		header.Set("Last-Modified", obj.LastModified.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		if _, err := io.Copy(w, body); err != nil {
+			slog.WarnContext(r.Context(), "upload_handler.serve_object: copy failed", "key", key, "err", err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"uniswap-campus-marketplace/db/migrations"
+	"uniswap-campus-marketplace/storage"
+)
+
+// Database pings the connection pool.
+func Database(db *sql.DB) Check {
+	return Check{Name: "database", Run: db.PingContext}
+}
+
+// Migrations fails while embedded migrations are waiting to be applied.
+func Migrations(db *sql.DB, driver string) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		pending, err := migrations.Pending(ctx, db, driver)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending, next is %s", len(pending), pending[0].Name)
+		}
+		return nil
+	}}
+}
+
+// WritableDir checks that files can be created in dir.
+func WritableDir(name, dir string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".healthcheck-*")
+		if err != nil {
+			return err
+		}
+		path := f.Name()
+		closeErr := f.Close()
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		return closeErr
+	}}
+}
+
+// Storage checks that the object store answers. It looks up a key that
+// does not exist, which needs valid credentials but writes nothing.
+func Storage(name string, store storage.Storage) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		_, err := store.Stat(ctx, ".healthcheck")
+		if err == nil || errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}}
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check is a single readiness dependency check.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one check in a readiness report.
+type CheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the readiness payload.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+const (
+	statusOK       = "ok"
+	statusFailed   = "failed"
+	statusReady    = "ready"
+	statusNotReady = "not_ready"
+	statusDraining = "draining"
+)
+
+// Checker answers liveness and readiness probes. Readiness runs every check
+// concurrently, each bounded by timeout, and fails once draining has begun so
+// load balancers stop routing new requests before the server shuts down.
+type Checker struct {
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// StartDraining flips readiness to not-ready for the rest of the process
+// lifetime.
+func (c *Checker) StartDraining() {
+	c.draining.Store(true)
+}
+
+// Live reports that the process is up and serving HTTP. It never checks
+// dependencies, so a database outage does not get the process restarted.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, true, map[string]string{"status": statusOK})
+}
+
+// Ready reports whether the service can take traffic, with per-check status
+// and latency.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	if report.Status != statusReady {
+		writeJSON(w, http.StatusServiceUnavailable, false, report)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, report)
+}
+
+// Run executes every check and builds the readiness report.
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: statusReady, Checks: results}
+	for _, result := range results {
+		if result.Status != statusOK {
+			report.Status = statusNotReady
+		}
+	}
+	if c.draining.Load() {
+		report.Status = statusDraining
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := CheckResult{
+		Name:      check.Name,
+		Status:    statusOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = statusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+type apiResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, success bool, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiResponse{Success: success, Data: data})
+}
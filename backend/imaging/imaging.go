@@ -0,0 +1,174 @@
+// Package imaging validates uploaded images and re-encodes them so that
+// only clean pixel data is ever stored: no metadata, no trailing payloads,
+// and nothing a browser could sniff as another content type.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrInvalidImage      = errors.New("invalid image")
+	ErrImageTooLarge     = errors.New("image dimensions too large")
+)
+
+// Limits bound the work done decoding an image. Dimensions are checked from
+// the header before any pixels are decoded, so a small file claiming a huge
+// canvas (a decompression bomb) is rejected cheaply.
+type Limits struct {
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int
+}
+
+// DefaultLimits comfortably fit photos from current phones.
+var DefaultLimits = Limits{
+	MaxWidth:  8192,
+	MaxHeight: 8192,
+	MaxPixels: 40_000_000,
+}
+
+// Format is an accepted input format, identified by its magic bytes.
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+)
+
+var decoders = map[Format]struct {
+	decode       func(io.Reader) (image.Image, error)
+	decodeConfig func(io.Reader) (image.Config, error)
+}{
+	FormatJPEG: {jpeg.Decode, jpeg.DecodeConfig},
+	FormatPNG:  {png.Decode, png.DecodeConfig},
+	FormatGIF:  {gif.Decode, gif.DecodeConfig},
+	FormatWebP: {webp.Decode, webp.DecodeConfig},
+}
+
+// Detect identifies the format from the content, ignoring any file name or
+// client-supplied content type.
+func Detect(data []byte) (Format, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return FormatJPEG, nil
+	case "image/png":
+		return FormatPNG, nil
+	case "image/gif":
+		return FormatGIF, nil
+	case "image/webp":
+		return FormatWebP, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Image is a re-encoded image ready to store.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	// PHash is the perceptual hash of the pixels; see DHash. Process sets it
+	// for the original only, not for variants.
+	PHash uint64
+}
+
+// Process detects, bounds-checks and decodes data, then re-encodes it. It
+// returns the decoded pixels too, for generating variants.
+// Photos (JPEG, WebP) become JPEG with their EXIF orientation applied;
+// PNG and GIF become PNG, keeping transparency. Animated GIFs keep only
+// their first frame.
+func Process(data []byte, limits Limits) (image.Image, *Image, error) {
+	img, err := Decode(data, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format, _ := Detect(data)
+	encode := EncodePNG
+	if format == FormatJPEG || format == FormatWebP {
+		encode = EncodeJPEG
+	}
+
+	encoded, err := encode(img)
+	if err != nil {
+		return nil, nil, err
+	}
+	encoded.PHash = DHash(img)
+	return img, encoded, nil
+}
+
+// Decode detects the format, checks the dimensions against limits and
+// decodes the pixels, applying the EXIF orientation of JPEGs.
+func Decode(data []byte, limits Limits) (image.Image, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	codec := decoders[format]
+
+	cfg, err := codec.decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width > limits.MaxWidth || cfg.Height > limits.MaxHeight || cfg.Width*cfg.Height > limits.MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, err := codec.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	if format == FormatJPEG {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+const jpegQuality = 85
+
+func EncodeJPEG(img image.Image) (*Image, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("encode jpeg: %w", err)
+	}
+	return newImage(buf.Bytes(), "image/jpeg", ".jpg", img), nil
+}
+
+func EncodePNG(img image.Image) (*Image, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+	return newImage(buf.Bytes(), "image/png", ".png", img), nil
+}
+
+func newImage(data []byte, contentType, ext string, img image.Image) *Image {
+	bounds := img.Bounds()
+	return &Image{
+		Data:        data,
+		ContentType: contentType,
+		Extension:   ext,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}
+}
@@ -0,0 +1,35 @@
+package imaging
+
+import (
+	"image"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// DHash returns the 64-bit difference hash of img: the image is shrunk to
+// 9x8 grey pixels and each bit records whether a pixel is brighter than its
+// right-hand neighbour. Re-encoding, resizing and small colour or
+// compression changes leave most bits alone, so near-identical images have
+// hashes a small Hamming distance apart.
+func DHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance returns the number of bits that differ between two
+// hashes: 0 for the same picture, up to 64.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
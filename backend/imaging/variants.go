@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Variant is a resized copy of an upload, stored next to the original as
+// "<name>_<variant><ext>". MaxSize bounds the longer side; images already
+// smaller are not upscaled.
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+var Variants = []Variant{
+	{Name: "thumb", MaxSize: 200},
+	{Name: "card", MaxSize: 600},
+	{Name: "full", MaxSize: 1600},
+}
+
+// VariantFilename returns the file name of a variant of the original file.
+func VariantFilename(original, variant string) string {
+	ext := path.Ext(original)
+	return strings.TrimSuffix(original, ext) + "_" + variant + ext
+}
+
+// OriginalFilename returns the name of the original upload a variant was
+// generated from, or name itself when it is not a variant.
+func OriginalFilename(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for _, v := range Variants {
+		if original, ok := strings.CutSuffix(base, "_"+v.Name); ok {
+			return original + ext
+		}
+	}
+	return name
+}
+
+// IsVariantFilename reports whether name is a generated variant rather than
+// an original upload.
+func IsVariantFilename(name string) bool {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	for _, v := range Variants {
+		if strings.HasSuffix(base, "_"+v.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateVariants resizes img to every variant, encoding each as ext
+// (".jpg" or ".png") like the original.
+func GenerateVariants(img image.Image, ext string) (map[string]*Image, error) {
+	variants := make(map[string]*Image, len(Variants))
+	for _, v := range Variants {
+		encoded, err := Encode(Resize(img, v.MaxSize), ext)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", v.Name, err)
+		}
+		variants[v.Name] = encoded
+	}
+	return variants, nil
+}
+
+// Encode encodes img in the format matching ext.
+func Encode(img image.Image, ext string) (*Image, error) {
+	switch ext {
+	case ".jpg":
+		return EncodeJPEG(img)
+	case ".png":
+		return EncodePNG(img)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, ext)
+}
+
+// Resize scales img so its longer side is at most maxSize, keeping the
+// aspect ratio.
+func Resize(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	if w >= h {
+		h = max(1, h*maxSize/w)
+		w = maxSize
+	} else {
+		w = max(1, w*maxSize/h)
+		h = maxSize
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
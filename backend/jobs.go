@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"uniswap-campus-marketplace/jobs"
+	"uniswap-campus-marketplace/services"
+)
+
+// Maintenance jobs. They take no payload: what they do depends only on the
+// configuration.
+var (
+	sweepUploadsJob     = jobs.NewKind[struct{}]("uploads.sweep")
+	purgeDeletedJob     = jobs.NewKind[struct{}]("retention.purge")
+	purgeIdempotencyJob = jobs.NewKind[struct{}]("idempotency.purge")
+	purgeJobsJob        = jobs.NewKind[struct{}]("jobs.purge")
+)
+
+// registerJobs is the single place that declares every background job
+// handler and when recurring jobs run.
+func (a *app) registerJobs(
+	runner *jobs.Runner,
+	uploadService *services.UploadService,
+	retentionService *services.RetentionService,
+	idempotencyService *services.IdempotencyService,
+) {
+	jobs.Handle(runner, sweepUploadsJob, func(ctx context.Context, _ struct{}) error {
+		_, err := uploadService.Sweep(ctx, a.cfg.UploadOrphanMaxAge)
+		return err
+	})
+	jobs.Recur(runner, sweepUploadsJob, jobs.Every(a.cfg.UploadSweepInterval), struct{}{})
+
+	jobs.Handle(runner, purgeDeletedJob, func(ctx context.Context, _ struct{}) error {
+		_, _, err := retentionService.Purge(ctx, a.cfg.DeletedRetention)
+		return err
+	})
+	jobs.Recur(runner, purgeDeletedJob, jobs.Every(a.cfg.RetentionSweepInterval), struct{}{})
+
+	jobs.Handle(runner, purgeIdempotencyJob, func(ctx context.Context, _ struct{}) error {
+		_, err := idempotencyService.Purge(ctx)
+		return err
+	})
+	jobs.Recur(runner, purgeIdempotencyJob, jobs.Every(a.cfg.RetentionSweepInterval), struct{}{})
+
+	jobs.Handle(runner, purgeJobsJob, func(ctx context.Context, _ struct{}) error {
+		_, err := runner.Purge(ctx, a.cfg.JobRetention)
+		return err
+	})
+	jobs.Recur(runner, purgeJobsJob, jobs.MustCron("30 3 * * *"), struct{}{})
+}
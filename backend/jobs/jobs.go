@@ -0,0 +1,118 @@
+// Package jobs runs background work from a durable queue in the database.
+// Each kind of job has a typed payload and a handler; failed jobs are
+// retried with exponential backoff until they run out of attempts and are
+// left dead for inspection. Recurring jobs are enqueued on a Schedule.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultTimeout     = 5 * time.Minute
+)
+
+// Kind names a kind of job whose payload is a T. Declare each kind once,
+// next to the code that enqueues it, and register its handler with Handle.
+type Kind[T any] struct {
+	name        string
+	maxAttempts int
+	timeout     time.Duration
+}
+
+// NewKind declares a kind of job tried up to 5 times, each attempt limited
+// to 5 minutes. name is stored with every job, so it must not change while
+// jobs of the kind may be queued.
+func NewKind[T any](name string) Kind[T] {
+	return Kind[T]{name: name, maxAttempts: defaultMaxAttempts, timeout: defaultTimeout}
+}
+
+// Name returns the kind's name.
+func (k Kind[T]) Name() string {
+	return k.name
+}
+
+// WithMaxAttempts returns a copy of k whose jobs are tried up to n times.
+func (k Kind[T]) WithMaxAttempts(n int) Kind[T] {
+	k.maxAttempts = n
+	return k
+}
+
+// WithTimeout returns a copy of k whose attempts are cancelled after d.
+func (k Kind[T]) WithTimeout(d time.Duration) Kind[T] {
+	k.timeout = d
+	return k
+}
+
+// EnqueueOptions adjust a single job. The zero value runs the job as soon
+// as possible.
+type EnqueueOptions struct {
+	// RunAt delays the job until then.
+	RunAt time.Time
+	// DedupeKey, when set, makes Enqueue fail with
+	// repository.ErrDuplicateJob while another stored job has the same key:
+	// not only while that job waits or runs, but also after it finished,
+	// until Purge deletes it. A key thus means "at most once per retention
+	// period", which is why Recur puts the run time in its keys.
+	DedupeKey string
+}
+
+// Enqueuer stores jobs; repository.JobRepository is one.
+type Enqueuer interface {
+	Enqueue(ctx context.Context, job *models.Job) (*models.Job, error)
+}
+
+// Enqueue queues a job of kind k with payload. Called inside a transaction,
+// the job is only queued if the transaction commits, which makes it safe to
+// enqueue follow-up work together with the change that needs it.
+func Enqueue[T any](ctx context.Context, q Enqueuer, k Kind[T], payload T, opts EnqueueOptions) (*models.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", k.name, err)
+	}
+
+	job := &models.Job{
+		Kind:        k.name,
+		Payload:     string(encoded),
+		MaxAttempts: k.maxAttempts,
+		RunAt:       opts.RunAt,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.DedupeKey != "" {
+		job.DedupeKey = &opts.DedupeKey
+	}
+	return q.Enqueue(ctx, job)
+}
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps a handler's error to make the job dead at once instead of
+// retrying it, e.g. when the payload refers to something that is gone.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var perr *permanentError
+	return errors.As(err, &perr)
+}
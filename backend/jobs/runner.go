@@ -0,0 +1,323 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"uniswap-campus-marketplace/metrics"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+)
+
+var tracer = otel.Tracer("uniswap-campus-marketplace/jobs")
+
+const (
+	// leaseMargin is how long past the longest job timeout a worker holds
+	// a job, leaving it time to record the outcome before another worker
+	// may take the job over.
+	leaseMargin = time.Minute
+	// Failed attempts are retried after 30s, 1m, 2m and so on, up to an
+	// hour, with jitter so that jobs failing together spread out.
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+// Job outcomes, as counted in metrics.JobsProcessed.
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeRetried   = "retried"
+	outcomeDead      = "dead"
+	outcomeReleased  = "released"
+	outcomeLost      = "lost"
+)
+
+// Config tunes a Runner.
+type Config struct {
+	// Workers is how many jobs run at once.
+	Workers int
+	// PollInterval is how long an idle worker waits before looking for due
+	// jobs again.
+	PollInterval time.Duration
+	// DrainTimeout is how long Run waits for running jobs once its context
+	// is done. Jobs still running then are cancelled and queued again.
+	DrainTimeout time.Duration
+}
+
+type handler struct {
+	timeout time.Duration
+	run     func(ctx context.Context, payload string) error
+}
+
+type recurringJob struct {
+	kind     string
+	schedule Schedule
+	enqueue  func(ctx context.Context, runAt time.Time) error
+}
+
+// Runner works the job queue: it claims due jobs of the kinds it has
+// handlers for, runs them and records the outcome, and enqueues recurring
+// jobs when they are due. Several servers can each run one against the same
+// database.
+type Runner struct {
+	repo      repository.JobRepository
+	cfg       Config
+	handlers  map[string]handler
+	recurring []recurringJob
+	now       func() time.Time
+}
+
+func NewRunner(repo repository.JobRepository, cfg Config) *Runner {
+	return &Runner{
+		repo:     repo,
+		cfg:      cfg,
+		handlers: make(map[string]handler),
+		now:      time.Now,
+	}
+}
+
+// Handle makes fn the handler of jobs of kind k. Register every handler
+// before calling Run. A handler returning an error is retried unless the
+// error is Permanent; it should respect ctx, which is cancelled when the
+// attempt times out.
+func Handle[T any](r *Runner, k Kind[T], fn func(ctx context.Context, payload T) error) {
+	r.handlers[k.name] = handler{
+		timeout: k.timeout,
+		run: func(ctx context.Context, payload string) error {
+			var decoded T
+			if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+				return Permanent(fmt.Errorf("decode payload: %w", err))
+			}
+			return fn(ctx, decoded)
+		},
+	}
+}
+
+// Recur enqueues a job of kind k with payload at every run time of
+// schedule, from the first one after Run starts. Each run is enqueued once
+// however many servers share the schedule, even by a server that comes to
+// it after the run finished, as a finished job keeps its dedupe key until
+// purged. Runs missed while no server was
+// up are not made up for.
+func Recur[T any](r *Runner, k Kind[T], schedule Schedule, payload T) {
+	r.recurring = append(r.recurring, recurringJob{
+		kind:     k.name,
+		schedule: schedule,
+		enqueue: func(ctx context.Context, runAt time.Time) error {
+			_, err := Enqueue(ctx, r.repo, k, payload, EnqueueOptions{
+				RunAt:     runAt,
+				DedupeKey: fmt.Sprintf("%s@%d", k.name, runAt.Unix()),
+			})
+			if errors.Is(err, repository.ErrDuplicateJob) {
+				return nil
+			}
+			return err
+		},
+	})
+}
+
+// Run works the queue until ctx is done, then waits up to DrainTimeout for
+// running jobs to finish before returning.
+func (r *Runner) Run(ctx context.Context) {
+	kinds := slices.Sorted(maps.Keys(r.handlers))
+	lease := leaseMargin
+	for _, h := range r.handlers {
+		lease = max(lease, h.timeout+leaseMargin)
+	}
+
+	// Jobs run on a context of their own so that shutdown lets them
+	// finish rather than cutting them off.
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	for range r.cfg.Workers {
+		wg.Go(func() { r.work(ctx, jobCtx, kinds, lease) })
+	}
+	wg.Go(func() { r.schedule(ctx) })
+	slog.InfoContext(ctx, "jobs: runner started", "workers", r.cfg.Workers, "kinds", kinds)
+
+	<-ctx.Done()
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	slog.Info("jobs: draining", "timeout", r.cfg.DrainTimeout.String())
+	select {
+	case <-drained:
+	case <-time.After(r.cfg.DrainTimeout):
+		slog.Warn("jobs: drain timed out, cancelling running jobs")
+		cancelJobs()
+		<-drained
+	}
+	slog.Info("jobs: runner stopped")
+}
+
+// work claims and runs one job after another until ctx is done.
+func (r *Runner) work(ctx, jobCtx context.Context, kinds []string, lease time.Duration) {
+	for ctx.Err() == nil {
+		now := r.now()
+		job, err := r.repo.Claim(ctx, kinds, now, now.Add(lease))
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "jobs: claim failed", "err", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.cfg.PollInterval):
+			}
+			continue
+		}
+		r.execute(jobCtx, job)
+	}
+}
+
+// execute runs a claimed job and records the outcome.
+func (r *Runner) execute(ctx context.Context, job *models.Job) {
+	ctx, span := tracer.Start(ctx, "job "+job.Kind, trace.WithAttributes(
+		attribute.String("job.kind", job.Kind),
+		attribute.Int64("job.id", job.ID),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	defer span.End()
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// The job was taken over from a worker that stopped during the
+		// last attempt.
+		err = Permanent(errors.New("worker stopped during the last attempt"))
+	} else {
+		err = r.run(ctx, r.handlers[job.Kind], job)
+	}
+
+	// The outcome is recorded even when running jobs are being cancelled.
+	recordCtx := context.WithoutCancel(ctx)
+	now := r.now()
+	var outcome string
+	var recordErr error
+	switch {
+	case err == nil:
+		outcome = outcomeSucceeded
+		recordErr = r.repo.Complete(recordCtx, job.ID, job.Attempts, now)
+	case ctx.Err() != nil:
+		outcome = outcomeReleased
+		slog.WarnContext(ctx, "jobs: job interrupted by shutdown", "kind", job.Kind, "job_id", job.ID)
+		recordErr = r.repo.Release(recordCtx, job.ID, job.Attempts, now)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		outcome = outcomeDead
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "job failed for good")
+		slog.ErrorContext(ctx, "jobs: job failed for good", "kind", job.Kind, "job_id", job.ID, "attempt", job.Attempts, "err", err)
+		recordErr = r.repo.Bury(recordCtx, job.ID, job.Attempts, now, err.Error())
+	default:
+		outcome = outcomeRetried
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "job failed")
+		runAt := now.Add(retryDelay(job.Attempts))
+		slog.WarnContext(ctx, "jobs: job failed, will retry", "kind", job.Kind, "job_id", job.ID, "attempt", job.Attempts, "retry_at", runAt, "err", err)
+		recordErr = r.repo.Retry(recordCtx, job.ID, job.Attempts, runAt, err.Error())
+	}
+	switch {
+	case errors.Is(recordErr, repository.ErrJobLeaseLost):
+		// The attempt overran its lease and another worker took the job
+		// over; its outcome is the one that counts.
+		outcome = outcomeLost
+		slog.WarnContext(ctx, "jobs: lease lost, outcome discarded", "kind", job.Kind, "job_id", job.ID, "attempt", job.Attempts)
+	case recordErr != nil:
+		// The job stays running until its lease runs out and another
+		// worker takes it over.
+		slog.ErrorContext(ctx, "jobs: recording outcome failed", "kind", job.Kind, "job_id", job.ID, "outcome", outcome, "err", recordErr)
+	}
+	metrics.JobsProcessed.WithLabelValues(job.Kind, outcome).Inc()
+}
+
+// run calls the job's handler, turning a panic into an error so that one
+// bad job cannot take the server down.
+func (r *Runner) run(ctx context.Context, h handler, job *models.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			slog.ErrorContext(ctx, "jobs: handler panicked", "kind", job.Kind, "job_id", job.ID, "panic", p, "stack", string(debug.Stack()))
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return h.run(ctx, job.Payload)
+}
+
+// schedule enqueues recurring jobs as they come due until ctx is done.
+func (r *Runner) schedule(ctx context.Context) {
+	next := make([]time.Time, len(r.recurring))
+	for i, job := range r.recurring {
+		next[i] = job.schedule.Next(r.now())
+	}
+
+	for {
+		var earliest time.Time
+		for _, t := range next {
+			if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+				earliest = t
+			}
+		}
+		if earliest.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(earliest.Sub(r.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := r.now()
+		for i, job := range r.recurring {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			if err := job.enqueue(ctx, next[i]); err != nil {
+				slog.ErrorContext(ctx, "jobs: enqueuing recurring job failed", "kind", job.kind, "run_at", next[i], "err", err)
+			}
+			next[i] = job.schedule.Next(now)
+		}
+	}
+}
+
+// retryDelay is how long to wait before retrying a job that failed the
+// given attempt.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, retryMaxDelay)
+	return delay/2 + rand.N(delay)
+}
+
+// Purge deletes jobs that succeeded or died more than retention ago.
+func (r *Runner) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	deleted, err := r.repo.DeleteFinished(ctx, r.now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "jobs: purged finished jobs", "jobs", deleted)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,280 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/repository"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+var greetJob = NewKind[greeting]("test.greet")
+
+func newTestRunner(repo repository.JobRepository) *Runner {
+	return NewRunner(repo, Config{Workers: 2, PollInterval: 5 * time.Millisecond, DrainTimeout: time.Second})
+}
+
+// start runs r until the returned func is called, which waits for Run to
+// return.
+func start(t *testing.T, r *Runner) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// finished counts the jobs that succeeded or died.
+func finished(t *testing.T, repo repository.JobRepository) int64 {
+	t.Helper()
+	n, err := repo.DeleteFinished(context.Background(), time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteFinished: %v", err)
+	}
+	return n
+}
+
+func TestRunnerRunsJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryJobRepository()
+	r := newTestRunner(repo)
+	greeted := make(chan string, 2)
+	Handle(r, greetJob, func(ctx context.Context, payload greeting) error {
+		greeted <- payload.Name
+		return nil
+	})
+
+	stop := start(t, r)
+	if _, err := Enqueue(ctx, repo, greetJob, greeting{Name: "Ada"}, EnqueueOptions{}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := Enqueue(ctx, repo, greetJob, greeting{Name: "Bob"}, EnqueueOptions{RunAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case name := <-greeted:
+		if name != "Ada" {
+			t.Errorf("greeted %q, want Ada", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run")
+	}
+	stop()
+
+	select {
+	case name := <-greeted:
+		t.Errorf("greeted %q before its run time", name)
+	default:
+	}
+	if n := finished(t, repo); n != 1 {
+		t.Errorf("finished jobs = %d, want 1", n)
+	}
+}
+
+func TestRunnerRetriesThenBuries(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryJobRepository()
+	r := newTestRunner(repo)
+	var clock atomic.Int64
+	r.now = func() time.Time { return time.Now().Add(time.Duration(clock.Load())) }
+	var calls atomic.Int32
+	Handle(r, greetJob.WithMaxAttempts(2), func(ctx context.Context, payload greeting) error {
+		calls.Add(1)
+		return errors.New("mail server down")
+	})
+
+	start(t, r)
+	if _, err := Enqueue(ctx, repo, greetJob.WithMaxAttempts(2), greeting{Name: "Ada"}, EnqueueOptions{}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	waitFor(t, "the first attempt", func() bool { return calls.Load() == 1 })
+
+	// The retry waits for its backoff.
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("attempts = %d before the backoff passed, want 1", n)
+	}
+	clock.Store(int64(2 * retryMaxDelay))
+	waitFor(t, "the retry", func() bool { return calls.Load() == 2 })
+	waitFor(t, "the job to die", func() bool { return finished(t, repo) == 1 })
+
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("attempts = %d, want no more than max attempts", n)
+	}
+}
+
+func TestRunnerPermanentErrorsAndPanics(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryJobRepository()
+	r := newTestRunner(repo)
+	var calls atomic.Int32
+	Handle(r, greetJob, func(ctx context.Context, payload greeting) error {
+		calls.Add(1)
+		if payload.Name == "panic" {
+			panic("boom")
+		}
+		return Permanent(errors.New("no such user"))
+	})
+
+	start(t, r)
+	for _, name := range []string{"gone", "panic"} {
+		if _, err := Enqueue(ctx, repo, greetJob, greeting{Name: name}, EnqueueOptions{}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	waitFor(t, "both jobs to run", func() bool { return calls.Load() == 2 })
+
+	// The permanent failure dies at once; the panic is retried later.
+	waitFor(t, "the permanent failure to die", func() bool { return finished(t, repo) == 1 })
+	job, err := repo.Claim(ctx, []string{greetJob.Name()}, time.Now().Add(2*retryMaxDelay), time.Now().Add(3*retryMaxDelay))
+	if err != nil || job == nil || job.Attempts != 2 || job.LastError == nil {
+		t.Fatalf("Claim = %+v, %v; want the panicked job queued for retry", job, err)
+	}
+}
+
+func TestRunnerDrains(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryJobRepository()
+	r := newTestRunner(repo)
+	r.cfg.DrainTimeout = 100 * time.Millisecond
+	started := make(chan string, 2)
+	Handle(r, greetJob, func(ctx context.Context, payload greeting) error {
+		started <- payload.Name
+		if payload.Name == "slow" {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	stop := start(t, r)
+	for _, name := range []string{"slow", "stuck"} {
+		if _, err := Enqueue(ctx, repo, greetJob, greeting{Name: name}, EnqueueOptions{}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	<-started
+	<-started
+	stop()
+
+	// The slow job finished within the drain timeout; the stuck one was
+	// cancelled and queued again without using up an attempt.
+	if n := finished(t, repo); n != 1 {
+		t.Errorf("finished jobs = %d, want 1", n)
+	}
+	job, err := repo.Claim(ctx, []string{greetJob.Name()}, time.Now(), time.Now().Add(time.Minute))
+	if err != nil || job == nil || job.Attempts != 1 {
+		t.Errorf("Claim = %+v, %v; want the stuck job back on its first attempt", job, err)
+	}
+}
+
+func TestRunnerDiscardsOutcomeAfterLostLease(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryJobRepository()
+	r := newTestRunner(repo)
+	Handle(r, greetJob, func(ctx context.Context, payload greeting) error {
+		return errors.New("too slow")
+	})
+	if _, err := Enqueue(ctx, repo, greetJob, greeting{Name: "Ada"}, EnqueueOptions{}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// The first worker overruns its lease and a second one takes the job
+	// over before the first reports back.
+	kinds := []string{greetJob.Name()}
+	now := time.Now()
+	stale, err := repo.Claim(ctx, kinds, now, now.Add(time.Second))
+	if err != nil || stale == nil {
+		t.Fatalf("Claim = %+v, %v", stale, err)
+	}
+	current, err := repo.Claim(ctx, kinds, now.Add(2*time.Second), now.Add(time.Hour))
+	if err != nil || current == nil || current.Attempts != 2 {
+		t.Fatalf("Claim after the lease ran out = %+v, %v; want attempt 2", current, err)
+	}
+
+	r.execute(ctx, stale)
+	if job, err := repo.Claim(ctx, kinds, now.Add(time.Minute), now.Add(time.Hour)); err != nil || job != nil {
+		t.Fatalf("Claim = %+v, %v; want the job still held by the second worker", job, err)
+	}
+	if err := repo.Complete(ctx, current.ID, current.Attempts, time.Now()); err != nil {
+		t.Errorf("Complete by the second worker: %v", err)
+	}
+}
+
+func TestRunnerRecurringJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryJobRepository()
+	var calls atomic.Int32
+
+	// Two servers share the schedule; each run is enqueued once.
+	runners := []*Runner{newTestRunner(repo), newTestRunner(repo)}
+	for _, r := range runners {
+		Handle(r, greetJob, func(ctx context.Context, payload greeting) error {
+			calls.Add(1)
+			return nil
+		})
+		Recur(r, greetJob, Every(time.Hour), greeting{Name: "nightly"})
+	}
+	runAt := Every(time.Hour).Next(time.Now())
+	for _, r := range runners {
+		if err := r.recurring[0].enqueue(ctx, runAt); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	job, err := repo.Claim(ctx, []string{greetJob.Name()}, runAt, runAt.Add(time.Minute))
+	if err != nil || job == nil || job.Payload != `{"name":"nightly"}` {
+		t.Fatalf("Claim = %+v, %v; want the recurring job", job, err)
+	}
+	if job, _ := repo.Claim(ctx, []string{greetJob.Name()}, runAt, runAt.Add(time.Minute)); job != nil {
+		t.Errorf("second Claim = %+v, want the run enqueued once", job)
+	}
+
+	// The scheduler enqueues runs as they come due.
+	r := newTestRunner(repository.NewMemoryJobRepository())
+	Handle(r, greetJob, func(ctx context.Context, payload greeting) error {
+		calls.Add(1)
+		return nil
+	})
+	Recur(r, greetJob, Every(20*time.Millisecond), greeting{Name: "often"})
+	start(t, r)
+	waitFor(t, "a recurring run", func() bool { return calls.Load() > 0 })
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, base := range map[int]time.Duration{1: retryBaseDelay, 2: 2 * retryBaseDelay, 4: 8 * retryBaseDelay, 50: retryMaxDelay} {
+		if d := retryDelay(attempt); d < base/2 || d >= base*3/2 {
+			t.Errorf("retryDelay(%d) = %v, want within [%v, %v)", attempt, d, base/2, base*3/2)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	at := time.Date(2026, 3, 14, 10, 17, 5, 0, time.UTC)
+	if got, want := Every(time.Hour).Next(at), time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Every(1h).Next = %v, want %v", got, want)
+	}
+	if got, want := Every(15*time.Minute).Next(at), time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Every(15m).Next = %v, want %v", got, want)
+	}
+}
+
+func TestCron(t *testing.T) {
+	// A Saturday.
+	at := time.Date(2026, 3, 14, 10, 17, 5, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 14, 10, 18, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2026, 3, 15, 3, 30, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2026, 3, 14, 10, 20, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 3, 14, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1", time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,20 1,12 *", time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// With both day fields set, either matching will do.
+		{"0 12 20 * 0", time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Cron(tt.expr)
+			if err != nil {
+				t.Fatalf("Cron: %v", err)
+			}
+			if got := schedule.Next(at); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("Cron(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey string
+
+const requestIDContextKey contextKey = "request_id"
+
+// New returns a JSON logger writing to w at the given level ("debug",
+// "info", "warn" or "error"). Every record gets the request ID found in its
+// context, and sensitive attributes are redacted before they are written.
+func New(w io.Writer, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("parse log level %q: %w", level, err)
+	}
+
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redact,
+	})
+	return slog.New(contextHandler{handler}), nil
+}
+
+// WithRequestID stores the request ID used to correlate log lines.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey).(string)
+	return requestID, ok && requestID != ""
+}
+
+// contextHandler adds the request ID and trace IDs from the record's
+// context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanCtx.TraceID().String()),
+			slog.String("span_id", spanCtx.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+var (
+	secretKeys = map[string]bool{
+		"password":      true,
+		"token":         true,
+		"authorization": true,
+		"secret":        true,
+		"jwt_secret":    true,
+	}
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+)
+
+// redact removes secrets and pseudonymises email addresses. Emails are
+// replaced by a short hash so lines about the same account still correlate.
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	if secretKeys[key] {
+		return slog.String(attr.Key, "[REDACTED]")
+	}
+
+	if attr.Value.Kind() != slog.KindString && attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+
+	var value string
+	switch v := attr.Value.Any().(type) {
+	case string:
+		value = v
+	case error:
+		value = v.Error()
+	default:
+		return attr
+	}
+
+	redacted := jwtPattern.ReplaceAllString(value, "[REDACTED]")
+	redacted = emailPattern.ReplaceAllStringFunc(redacted, HashEmail)
+	if redacted == value {
+		return attr
+	}
+	return slog.String(attr.Key, redacted)
+}
+
+// HashEmail returns a stable pseudonym for an email address.
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "email:" + hex.EncodeToString(sum[:6])
+}
@@ -3,27 +3,44 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"uniswap-campus-marketplace/config"
+	"uniswap-campus-marketplace/db/migrations"
 	"uniswap-campus-marketplace/handlers"
+	"uniswap-campus-marketplace/health"
+	"uniswap-campus-marketplace/jobs"
+	"uniswap-campus-marketplace/logging"
+	"uniswap-campus-marketplace/metrics"
 	"uniswap-campus-marketplace/middleware"
+	"uniswap-campus-marketplace/ratelimit"
 	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/router"
 	"uniswap-campus-marketplace/services"
+	"uniswap-campus-marketplace/signedurl"
+	"uniswap-campus-marketplace/storage"
+	"uniswap-campus-marketplace/tracing"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 type app struct {
-	cfg *config.Config
-	db  *sql.DB
+	cfg          *config.Config
+	db           *sql.DB
+	health       *health.Checker
+	rateLimits   ratelimit.Store
+	optionalAuth router.Middleware
+	// requireModerator guards the moderation routes; it runs after auth.
+	requireModerator router.Middleware
+	// idempotent replays responses to retried creates; it runs after auth.
+	idempotent router.Middleware
 }
 
 func main() {
@@ -32,102 +49,158 @@ func main() {
 
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("load config: %v", err)
+		fatal("load config", err)
 	}
 
-	db, err := config.OpenDB(ctx, cfg.DatabaseDSN())
+	logger, err := logging.New(os.Stdout, cfg.LogLevel)
 	if err != nil {
-		log.Fatalf("connect database: %v", err)
+		fatal("configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(cfg.TracingExporter, cfg.TracingFile)
+	if err != nil {
+		fatal("configure tracing", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("tracing shutdown error", "err", err)
+		}
+	}()
+
+	dialect, err := repository.DialectFor(cfg.DBDriver)
+	if err != nil {
+		fatal("configure database", err)
+	}
+	db, err := config.OpenDB(ctx, cfg.DBDriver, cfg.DatabaseDSN())
+	if err != nil {
+		fatal("connect database", err)
 	}
 	defer db.Close()
+	metrics.RegisterDB(db, cfg.DBName)
+
+	if cfg.AutoMigrate {
+		if err := migrations.Apply(ctx, db, cfg.DBDriver); err != nil {
+			fatal("apply migrations", err)
+		}
+	}
 
-	a := &app{cfg: cfg, db: db}
-	userRepo := repository.NewPostgresUserRepository(db)
-	listingRepo := repository.NewPostgresListingRepository(db)
-	reportRepo := repository.NewPostgresReportRepository(db)
+	store, err := storage.New(cfg.Storage())
+	if err != nil {
+		fatal("configure storage", err)
+	}
+	storageCheck := health.Storage("storage", store)
+	if cfg.StorageBackend == storage.BackendLocal {
+		storageCheck = health.WritableDir("storage", cfg.UploadDir)
+	}
+
+	rateLimits := ratelimit.NewMemoryStore()
+	go rateLimits.RunCleanup(ctx, time.Minute, 2*time.Hour)
+
+	a := &app{
+		cfg: cfg,
+		db:  db,
+		health: health.NewChecker(2*time.Second,
+			health.Database(db),
+			health.Migrations(db, cfg.DBDriver),
+			storageCheck,
+		),
+		rateLimits: rateLimits,
+	}
+	userRepo := repository.NewSQLUserRepository(db, dialect)
+	listingRepo := repository.NewSQLListingRepository(db, dialect)
+	reportRepo := repository.NewSQLReportRepository(db, dialect)
+	uploadRepo := repository.NewSQLUploadRepository(db, dialect)
+	moderationRepo := repository.NewSQLModerationRepository(db, dialect)
+	idempotencyRepo := repository.NewSQLIdempotencyRepository(db, dialect)
+	jobRepo := repository.NewSQLJobRepository(db, dialect)
+	txManager := repository.NewSQLTxManager(db, dialect)
 
 	authService := services.NewAuthService(userRepo, cfg.JWTSecret)
-	listingService := services.NewListingService(listingRepo)
+	moderationService := services.NewModerationService(moderationRepo, uploadRepo, listingRepo, reportRepo, userRepo, txManager, cfg.ImageMatchMaxDistance)
+	listingService := services.NewListingService(listingRepo, uploadRepo, txManager, moderationService)
 	reportService := services.NewReportService(reportRepo, listingRepo)
+	uploadService := services.NewUploadService(uploadRepo, moderationRepo, store, cfg.UploadQuotaBytes, cfg.BlockRemovedImages)
+	retentionService := services.NewRetentionService(userRepo, listingRepo, uploadRepo, txManager, uploadService)
+	accountService := services.NewAccountService(userRepo, listingRepo, reportRepo, uploadRepo, txManager, uploadService, store)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyKeyTTL)
+
+	runner := jobs.NewRunner(jobRepo, jobs.Config{
+		Workers:      cfg.JobWorkers,
+		PollInterval: cfg.JobPollInterval,
+		DrainTimeout: cfg.JobDrainTimeout,
+	})
+	a.registerJobs(runner, uploadService, retentionService, idempotencyService)
+	// The runner drains alongside the HTTP server once ctx is done.
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		runner.Run(ctx)
+	}()
 
-	authHandler := handlers.NewAuthHandler(authService)
+	sessions := handlers.SessionOptions{
+		Enabled: cfg.SessionCookies,
+		Secure:  cfg.SecureCookies,
+	}
+	authHandler := handlers.NewAuthHandler(authService, sessions)
 	listingHandler := handlers.NewListingHandler(listingService, reportService)
-	uploadHandler := handlers.NewUploadHandler()
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", a.healthCheck)
-	mux.HandleFunc("/api/auth/register", authHandler.Register)
-	mux.HandleFunc("/api/auth/login", authHandler.Login)
-	mux.Handle("/api/auth/me", middleware.Auth(authService)(http.HandlerFunc(authHandler.Me)))
-	mux.Handle("/api/listings", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			middleware.Auth(authService)(http.HandlerFunc(listingHandler.Listings)).ServeHTTP(w, r)
-			return
-		}
-		listingHandler.Listings(w, r)
-	}))
-	mux.Handle("/api/listings/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(strings.TrimSpace(r.URL.Path), "/report") && r.Method == http.MethodPost {
-			middleware.Auth(authService)(http.HandlerFunc(listingHandler.ListingByIDRoutes)).ServeHTTP(w, r)
-			return
-		}
-		listingHandler.ListingByIDRoutes(w, r)
-	}))
-	mux.Handle("/api/uploads/image", middleware.Auth(authService)(http.HandlerFunc(uploadHandler.UploadImage)))
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads"))))
+	uploadHandler := handlers.NewUploadHandler(uploadService, store, signedurl.NewSigner(cfg.UploadSigningSecret))
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	accountHandler := handlers.NewAccountHandler(accountService, sessions)
+
+	a.optionalAuth = middleware.OptionalAuth(authService)
+	a.requireModerator = middleware.RequireModerator(authService)
+	a.idempotent = middleware.Idempotency(idempotencyService)
+	rt := router.New(middleware.Auth(authService))
+	a.registerRoutes(rt, authHandler, listingHandler, uploadHandler, moderationHandler, accountHandler)
+
+	var handler http.Handler = middleware.Tracing(middleware.RequestID(middleware.AccessLog(middleware.Metrics(
+		middleware.SecurityHeaders(cfg.HSTS)(middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins: cfg.CORSAllowedOrigins,
+			MaxAgeSeconds:  600,
+		})(rt)),
+	))))
+	if cfg.TrustProxyHeaders {
+		handler = middleware.RealIP(cfg.TrustedProxyHops, cfg.TrustRealIP)(handler)
+	}
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-ctx.Done()
+
+		// Report not-ready first so load balancers stop sending new
+		// requests, then drain the ones in flight.
+		a.health.StartDraining()
+		slog.Info("server draining", "delay", cfg.ShutdownDrainDelay.String())
+		time.Sleep(cfg.ShutdownDrainDelay)
+
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(shutdownCtx); err != nil {
-			log.Printf("server shutdown error: %v", err)
+			slog.Error("server shutdown error", "err", err)
 		}
 	}()
 
-	log.Printf("server running on :%s", cfg.Port)
+	slog.Info("server running", "port", cfg.Port)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server error: %v", err)
+		fatal("server error", err)
 	}
+	<-shutdownDone
+	<-jobsDone
 }
 
-func (a *app) healthCheck(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, apiError("method not allowed"))
-		return
-	}
-
-	writeJSON(w, http.StatusOK, apiSuccess(map[string]string{
-		"status": "ok",
-	}))
-}
-
-type apiResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-}
-
-func apiSuccess(data interface{}) apiResponse {
-	return apiResponse{Success: true, Data: data}
-}
-
-func apiError(message string) apiResponse {
-	return apiResponse{Success: false, Error: message}
-}
-
-func writeJSON(w http.ResponseWriter, status int, payload apiResponse) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-	}
+func fatal(message string, err error) {
+	slog.Error(message, "err", err)
+	os.Exit(1)
 }
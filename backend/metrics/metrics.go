@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "uniswap"
+
+// Registry holds every metric the API exposes on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency, by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+)
+
+// Business event counters.
+var (
+	Registrations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Accounts registered.",
+	})
+
+	LoginsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_failed_total",
+		Help:      "Failed login attempts, by reason.",
+	}, []string{"reason"})
+
+	ListingsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "listings_created_total",
+		Help:      "Listings created.",
+	})
+
+	ReportsFiled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reports_filed_total",
+		Help:      "Listing reports filed.",
+	})
+
+	ImageMatchesFlagged = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_matches_flagged_total",
+		Help:      "Listing images flagged as near-duplicates of another seller's.",
+	})
+
+	UploadsBlocked = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_blocked_total",
+		Help:      "Uploads rejected as re-uploads of images removed by moderators.",
+	})
+
+	RecordsPurged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "records_purged_total",
+		Help:      "Soft-deleted records deleted for good after the retention period, by table.",
+	}, []string{"table"})
+
+	JobsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_processed_total",
+		Help:      "Background job attempts, by kind and outcome: succeeded, retried, dead, released, or lost to another worker after the lease ran out.",
+	}, []string{"kind", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		Registrations,
+		LoginsFailed,
+		ListingsCreated,
+		ReportsFiled,
+		ImageMatchesFlagged,
+		UploadsBlocked,
+		RecordsPurged,
+		JobsProcessed,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a served request. route is the matched route
+// pattern without its method, never a raw path, so label cardinality stays
+// bounded.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// TrackInFlight marks a request as started and returns the func that marks
+// it finished.
+func TrackInFlight() func() {
+	httpInFlight.Inc()
+	return httpInFlight.Dec
+}
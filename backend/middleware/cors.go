@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig lists the browser origins allowed to call the API. A single
+// "*" allows any origin but then credentials (cookies) are not allowed, as
+// browsers require.
+type CORSConfig struct {
+	AllowedOrigins []string
+	MaxAgeSeconds  int
+}
+
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	corsAllowedHeaders = []string{"Authorization", "Content-Type", "X-CSRF-Token", RequestIDHeader, IdempotencyKeyHeader, "If-Match", "If-None-Match"}
+	corsExposedHeaders = []string{RequestIDHeader, "ETag", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", IdempotentReplayedHeader}
+)
+
+// CORS answers preflight requests and adds CORS headers for allowed origins.
+// It must wrap the router, which would otherwise answer OPTIONS with 405.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	allowAny := len(cfg.AllowedOrigins) == 1 && cfg.AllowedOrigins[0] == "*"
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			if origin == "" || (!allowAny && !allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowAny {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+				h.Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+				if cfg.MaxAgeSeconds > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAgeSeconds))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
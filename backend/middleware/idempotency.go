@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/validation"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with an idempotent
+// response and sent again when it is replayed.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+type idempotencyStore interface {
+	Begin(ctx context.Context, userID int64, key, fingerprint string) (*models.StoredResponse, error)
+	Finish(ctx context.Context, userID int64, key string, response *models.StoredResponse) error
+	Abandon(ctx context.Context, userID int64, key string) error
+}
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to
+// retry: the first response per user and key is stored and replayed to
+// retries, and reusing the key with a different request is rejected with
+// 409. Server errors and rate limiting are not stored, so those requests can
+// be retried for real. Requests without the header pass straight through.
+// It must run after Auth.
+func Idempotency(store idempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				apierror.Write(w, r, apierror.ErrUnauthorized)
+				return
+			}
+			if !validIdempotencyKey(key) {
+				apierror.Write(w, r, apierror.ErrValidation.WithDetails([]validation.FieldError{{
+					Field:   IdempotencyKeyHeader,
+					Code:    validation.CodeInvalidFormat,
+					Message: "Idempotency-Key must be 1 to 255 visible ASCII characters",
+				}}))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					apierror.Write(w, r, apierror.ErrInvalidRequestBody.WithMessage("request body is too large"))
+					return
+				}
+				apierror.Write(w, r, apierror.ErrInvalidRequestBody)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			stored, err := store.Begin(r.Context(), userID, key, requestFingerprint(r, body))
+			if err != nil {
+				apiErr := apierror.FromError(err, apierror.ErrInternal)
+				if apiErr.Code == apierror.CodeIdempotencyInProgress {
+					w.Header().Set("Retry-After", "1")
+				}
+				if apiErr == apierror.ErrInternal {
+					slog.ErrorContext(r.Context(), "idempotency: begin failed", "user_id", userID, "err", err)
+				}
+				apierror.Write(w, r, apiErr)
+				return
+			}
+			if stored != nil {
+				for name, value := range stored.Header {
+					w.Header().Set(name, value)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// The outcome is recorded even if the client has gone away, so
+			// that its retry finds it.
+			ctx := context.WithoutCancel(r.Context())
+			rec := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+			finished := false
+			defer func() {
+				if finished {
+					return
+				}
+				if err := store.Abandon(ctx, userID, key); err != nil {
+					slog.ErrorContext(ctx, "idempotency: abandon failed", "user_id", userID, "err", err)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests {
+				return
+			}
+			response := &models.StoredResponse{
+				Status: rec.status,
+				Header: make(map[string]string),
+				Body:   rec.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := w.Header().Get(name); value != "" {
+					response.Header[name] = value
+				}
+			}
+			if err := store.Finish(ctx, userID, key, response); err != nil {
+				slog.ErrorContext(ctx, "idempotency: storing response failed", "user_id", userID, "err", err)
+				return
+			}
+			finished = true
+		})
+	}
+}
+
+// validIdempotencyKey reports whether key is 1 to 255 visible ASCII
+// characters, which covers UUIDs and other random tokens clients generate.
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < '!' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method)
+	h.Write([]byte{0})
+	io.WriteString(h, r.URL.Path)
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCapture passes the response through while keeping a copy of its
+// status and body.
+type responseCapture struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseCapture) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseCapture) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseCapture) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
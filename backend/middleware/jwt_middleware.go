@@ -2,59 +2,98 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+
+	"uniswap-campus-marketplace/apierror"
 )
 
-type tokenParser interface {
-	ParseToken(tokenString string) (int64, error)
+type authenticator interface {
+	Authenticate(ctx context.Context, tokenString string) (int64, error)
 }
 
 type contextKey string
 
 const userIDContextKey contextKey = "user_id"
 
-func Auth(parser tokenParser) func(http.Handler) http.Handler {
+func Auth(auth authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("auth_middleware: request started method=%s path=%s", r.Method, r.URL.Path)
+			slog.DebugContext(r.Context(), "auth_middleware: request started", "method", r.Method, "path", r.URL.Path)
 
-			authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
-			if authHeader == "" {
-				log.Printf("auth_middleware: missing authorization header method=%s path=%s", r.Method, r.URL.Path)
-				writeUnauthorized(w, "authorization header is required")
+			token, fromCookie, apiErr := requestToken(r)
+			if apiErr != nil {
+				slog.WarnContext(r.Context(), "auth_middleware: credentials rejected", "method", r.Method, "path", r.URL.Path, "code", apiErr.Code)
+				apierror.Write(w, r, apiErr)
 				return
 			}
 
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || strings.TrimSpace(parts[1]) == "" {
-				log.Printf("auth_middleware: invalid authorization format method=%s path=%s", r.Method, r.URL.Path)
-				writeUnauthorized(w, "invalid authorization header format")
+			if fromCookie && !validCSRF(r) {
+				slog.WarnContext(r.Context(), "auth_middleware: csrf token mismatch", "method", r.Method, "path", r.URL.Path)
+				apierror.Write(w, r, apierror.ErrCSRFTokenInvalid)
 				return
 			}
 
-			userID, err := parser.ParseToken(strings.TrimSpace(parts[1]))
+			userID, err := auth.Authenticate(r.Context(), token)
 			if err != nil {
-				log.Printf("auth_middleware: token parse failed method=%s path=%s err=%v", r.Method, r.URL.Path, err)
-				writeUnauthorized(w, "invalid or expired token")
+				slog.WarnContext(r.Context(), "auth_middleware: token rejected", "method", r.Method, "path", r.URL.Path, "err", err)
+				apierror.Write(w, r, apierror.FromError(err, apierror.ErrTokenInvalid))
 				return
 			}
 
-			log.Printf("auth_middleware: token validated user_id=%d method=%s path=%s", userID, r.Method, r.URL.Path)
+			slog.DebugContext(r.Context(), "auth_middleware: token validated", "user_id", userID, "method", r.Method, "path", r.URL.Path)
 			ctx := context.WithValue(r.Context(), userIDContextKey, userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// requestToken returns the bearer token, or failing that the session cookie.
+// A request with an Authorization header is never cookie-authenticated, so
+// bearer clients need no CSRF token.
+func requestToken(r *http.Request) (token string, fromCookie bool, apiErr *apierror.Error) {
+	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
+	if authHeader == "" {
+		if cookie, err := r.Cookie(SessionCookieName); err == nil && cookie.Value != "" {
+			return cookie.Value, true, nil
+		}
+		return "", false, apierror.ErrAuthHeaderMissing
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || strings.TrimSpace(parts[1]) == "" {
+		return "", false, apierror.ErrAuthHeaderInvalid
+	}
+	return strings.TrimSpace(parts[1]), false, nil
+}
+
 func UserIDFromContext(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(userIDContextKey).(int64)
 	return userID, ok
 }
 
-func writeUnauthorized(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	_, _ = w.Write([]byte(`{"success":false,"error":"` + message + `"}`))
+// OptionalAuth identifies the user when the request carries valid
+// credentials and otherwise lets it through anonymously, for routes whose
+// response depends on who is asking but which do not require a login.
+func OptionalAuth(auth authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, fromCookie, apiErr := requestToken(r)
+			if apiErr != nil || (fromCookie && !validCSRF(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, err := auth.Authenticate(r.Context(), token)
+			if err != nil {
+				slog.DebugContext(r.Context(), "optional_auth: ignoring invalid token", "method", r.Method, "path", r.URL.Path, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"uniswap-campus-marketplace/metrics"
+	"uniswap-campus-marketplace/router"
+)
+
+// unmatchedRoute labels requests no route matched, keeping label
+// cardinality bounded no matter which paths clients probe.
+const unmatchedRoute = "unmatched"
+
+// Metrics records request count, latency and in-flight requests, labelled
+// by the route pattern the router matched.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		done := metrics.TrackInFlight()
+		defer done()
+
+		r, pattern := router.TrackPattern(r)
+		rec := newStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		metrics.ObserveRequest(r.Method, routeLabel(pattern()), rec.status, time.Since(start))
+	})
+}
+
+// routeLabel strips the method from a ServeMux pattern such as
+// "GET /api/listings/{id}".
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
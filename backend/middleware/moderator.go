@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"uniswap-campus-marketplace/apierror"
+)
+
+type moderatorChecker interface {
+	IsModerator(ctx context.Context, userID int64) (bool, error)
+}
+
+// RequireModerator lets only moderators through. It must run after Auth,
+// which puts the user ID in the context.
+func RequireModerator(checker moderatorChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				apierror.Write(w, r, apierror.ErrUnauthorized)
+				return
+			}
+
+			isModerator, err := checker.IsModerator(r.Context(), userID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "require_moderator: lookup failed", "user_id", userID, "err", err)
+				apierror.Write(w, r, apierror.FromError(err, apierror.ErrInternal))
+				return
+			}
+			if !isModerator {
+				slog.WarnContext(r.Context(), "require_moderator: access denied", "user_id", userID, "method", r.Method, "path", r.URL.Path)
+				apierror.Write(w, r, apierror.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/ratelimit"
+)
+
+// RateLimitPolicy is a named limit and the way requests are grouped into
+// buckets, e.g. per client IP or per authenticated user.
+type RateLimitPolicy struct {
+	Name  string
+	Limit ratelimit.Limit
+	Key   func(r *http.Request) string
+}
+
+// PerIP buckets requests by client address.
+func PerIP(name string, limit ratelimit.Limit) RateLimitPolicy {
+	return RateLimitPolicy{Name: name, Limit: limit, Key: func(r *http.Request) string {
+		return "ip:" + ClientIP(r)
+	}}
+}
+
+// PerUser buckets requests by authenticated user, falling back to the client
+// address for anonymous requests. It must run after Auth.
+func PerUser(name string, limit ratelimit.Limit) RateLimitPolicy {
+	return RateLimitPolicy{Name: name, Limit: limit, Key: func(r *http.Request) string {
+		if userID, ok := UserIDFromContext(r.Context()); ok {
+			return "user:" + strconv.FormatInt(userID, 10)
+		}
+		return "ip:" + ClientIP(r)
+	}}
+}
+
+// RateLimit rejects requests over the policy's limit with 429. Every
+// response carries RateLimit-* headers and rejections carry Retry-After. If
+// the store fails the request is let through rather than taking the API
+// down with it.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := policy.Name + ":" + policy.Key(r)
+			result, err := store.Take(r.Context(), key, policy.Limit)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "rate_limit: store failed", "policy", policy.Name, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit.Requests, int(policy.Limit.Per.Seconds())))
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				slog.WarnContext(r.Context(), "rate_limit: rejected", "policy", policy.Name, "key", key)
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				apierror.Write(w, r, apierror.ErrRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RealIP replaces RemoteAddr with the client address reported by the
+// proxies in front of the server: the address trustedHops entries from the
+// right of X-Forwarded-For or, when trustRealIP is set, X-Real-IP. Each
+// proxy appends the address it received the request from, so the entries
+// further left were written by the client and cannot be trusted. Only set
+// trustRealIP behind a proxy that replaces any X-Real-IP the client sent,
+// otherwise clients can pick their own rate limit bucket.
+func RealIP(trustedHops int, trustRealIP bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := clientIP(r.Header, trustedHops, trustRealIP); ip != nil {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the client address from the proxy headers, or nil when
+// they carry none, e.g. because X-Forwarded-For has fewer entries than
+// there are trusted proxies.
+func clientIP(h http.Header, trustedHops int, trustRealIP bool) net.IP {
+	if trustRealIP {
+		if ip := net.ParseIP(strings.TrimSpace(h.Get("X-Real-IP"))); ip != nil {
+			return ip
+		}
+	}
+	var forwarded []string
+	for _, value := range h.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+	if trustedHops < 1 || len(forwarded) < trustedHops {
+		return nil
+	}
+	return net.ParseIP(strings.TrimSpace(forwarded[len(forwarded)-trustedHops]))
+}
+
+// ClientIP returns the host part of RemoteAddr.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
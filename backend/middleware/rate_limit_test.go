@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/ratelimit"
+)
+
+var noContent = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(ratelimit.NewMemoryStore(), PerIP("login", ratelimit.Limit{Requests: 2, Per: time.Minute}))(noContent)
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		r.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		rec := request("192.0.2.1:1234")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+		}
+		h := rec.Header()
+		if h.Get("RateLimit-Policy") != "2;w=60" || h.Get("RateLimit-Limit") != "2" || h.Get("RateLimit-Remaining") != remaining {
+			t.Errorf("headers = %v, want policy 2;w=60 with %s remaining", h, remaining)
+		}
+		if h.Get("Retry-After") != "" {
+			t.Errorf("Retry-After = %q on an allowed request", h.Get("Retry-After"))
+		}
+	}
+
+	rec := request("192.0.2.1:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over the limit = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if got := rec.Header().Get("RateLimit-Reset"); got != "60" {
+		t.Errorf("RateLimit-Reset = %q, want 60", got)
+	}
+	var body struct {
+		Code apierror.Code `json:"code"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != apierror.CodeRateLimited {
+		t.Errorf("body code = %q, %v; want %s", body.Code, err, apierror.CodeRateLimited)
+	}
+
+	if rec := request("192.0.2.2:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("another client's status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store down")
+}
+
+func TestRateLimitLetsRequestsThroughWhenStoreFails(t *testing.T) {
+	handler := RateLimit(failingStore{}, PerIP("login", ratelimit.Limit{Requests: 1, Per: time.Minute}))(noContent)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	tests := []struct {
+		name        string
+		hops        int
+		trustRealIP bool
+		realIP      string
+		forwarded   []string
+		want        string
+	}{
+		{"no headers", 1, false, "", nil, "203.0.113.9"},
+		{"X-Real-IP", 1, true, "198.51.100.7", nil, "198.51.100.7"},
+		{"X-Real-IP wins", 1, true, "198.51.100.7", []string{"192.0.2.1"}, "198.51.100.7"},
+		{"untrusted X-Real-IP", 1, false, "198.51.100.7", nil, "203.0.113.9"},
+		{"untrusted X-Real-IP with X-Forwarded-For", 1, false, "198.51.100.7", []string{"192.0.2.1"}, "192.0.2.1"},
+		{"rightmost entry", 1, false, "", []string{"192.0.2.66, 192.0.2.1"}, "192.0.2.1"},
+		{"entry two hops from the right", 2, false, "", []string{"192.0.2.66, 192.0.2.1, 10.0.0.2"}, "192.0.2.1"},
+		{"repeated headers", 2, false, "", []string{"192.0.2.66, 192.0.2.1", "10.0.0.2"}, "192.0.2.1"},
+		{"fewer entries than hops", 2, false, "", []string{"192.0.2.1"}, "203.0.113.9"},
+		{"not an address", 1, false, "", []string{"192.0.2.1, unknown"}, "203.0.113.9"},
+		{"IPv6", 1, false, "", []string{"2001:db8::1"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "203.0.113.9:4321"
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			var got string
+			RealIP(tt.hops, tt.trustRealIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitIgnoresSpoofedRealIP(t *testing.T) {
+	limited := RateLimit(ratelimit.NewMemoryStore(), PerIP("login", ratelimit.Limit{Requests: 1, Per: time.Minute}))(noContent)
+	handler := RealIP(1, false)(limited)
+
+	for i, want := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		r.RemoteAddr = "10.0.0.2:4321"
+		r.Header.Set("X-Forwarded-For", "192.0.2.1")
+		r.Header.Set("X-Real-IP", fmt.Sprintf("198.51.100.%d", i+1))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != want {
+			t.Errorf("request %d with a fresh X-Real-IP: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+}
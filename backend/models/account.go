@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type DeleteAccountRequest struct {
+	// Password re-confirms that the account owner is at the keyboard.
+	Password string `json:"password"`
+}
+
+// AccountExport is everything the marketplace stores about a user, as
+// handed to them on request. Uploads holds the records of their images;
+// the archive export carries the files too. Messages and reviews belong
+// here as well once the marketplace has them.
+type AccountExport struct {
+	ExportedAt time.Time `json:"exported_at"`
+	Profile    User      `json:"profile"`
+	Listings   []Listing `json:"listings"`
+	Uploads    []Upload  `json:"uploads"`
+	Reports    []Report  `json:"reports_filed"`
+}
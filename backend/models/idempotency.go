@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is a request a user sent with an Idempotency-Key.
+// Fingerprint identifies the request, so the key cannot be reused for a
+// different one; Response is nil while the request is being processed.
+type IdempotencyRecord struct {
+	UserID      int64
+	Key         string
+	Fingerprint string
+	Response    *StoredResponse
+	CreatedAt   time.Time
+}
+
+// StoredResponse is a response kept to be replayed. Header holds only the
+// headers worth replaying, such as Content-Type and ETag.
+type StoredResponse struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ImageVariants are the URLs of the resized copies of an uploaded image.
+type ImageVariants struct {
+	Thumb string `json:"thumb"`
+	Card  string `json:"card"`
+	Full  string `json:"full"`
+}
+
+// UploadedImage describes a stored upload. Private uploads have a Key, used
+// to request new signed URLs, and a URL that expires at ExpiresAt.
+type UploadedImage struct {
+	URL       string        `json:"url"`
+	Key       string        `json:"key,omitempty"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+	Width     int           `json:"width"`
+	Height    int           `json:"height"`
+	Variants  ImageVariants `json:"variants"`
+}
+
+// SignURLRequest asks for a signed URL to a private upload. UserID binds the
+// URL to another user to share the file with; it defaults to the caller.
+type SignURLRequest struct {
+	Key    string `json:"key"`
+	UserID *int64 `json:"user_id,omitempty"`
+}
+
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ListingImage struct {
+	URL       string        `json:"url"`
+	IsPrimary bool          `json:"is_primary"`
+	Variants  ImageVariants `json:"variants"`
+}
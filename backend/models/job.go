@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Job statuses. A pending job waits for its run time, a running one is held
+// by a worker, and a job that failed every attempt is dead.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// Job is a unit of background work. Payload is the JSON-encoded argument of
+// the handler registered for Kind. A job is tried up to MaxAttempts times;
+// LastError is the error of the latest failed attempt.
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     string
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LockedUntil *time.Time
+	LastError   *string
+	DedupeKey   *string
+	CreatedAt   time.Time
+	FinishedAt  *time.Time
+}
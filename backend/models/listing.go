@@ -1,20 +1,55 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// ListingCategories are the categories a listing can be filed under.
+var ListingCategories = []string{"Books", "Electronics", "Furniture", "Other"}
+
+// CanonicalCategory returns the category spelled as in ListingCategories, or
+// the input unchanged when it is not a known category.
+func CanonicalCategory(category string) string {
+	for _, known := range ListingCategories {
+		if strings.EqualFold(category, known) {
+			return known
+		}
+	}
+	return category
+}
 
 type CreateListingRequest struct {
 	Title       string  `json:"title"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	Category    string  `json:"category"`
+	// ImageURLs are URLs returned by the upload endpoint; the first is the
+	// primary image.
+	ImageURLs []string `json:"image_urls"`
+}
+
+// UpdateListingRequest replaces every field of a listing, images included.
+// Its fields match CreateListingRequest's, so either converts to the other.
+type UpdateListingRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Price       float64  `json:"price"`
+	Category    string   `json:"category"`
+	ImageURLs   []string `json:"image_urls"`
 }
 
 type Listing struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Category    string    `json:"category"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int64          `json:"id"`
+	UserID      int64          `json:"user_id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Price       float64        `json:"price"`
+	Category    string         `json:"category"`
+	Images      []ListingImage `json:"images"`
+	CreatedAt   time.Time      `json:"created_at"`
+	// Version counts changes to the listing; it is the listing's ETag.
+	Version int64 `json:"version"`
+	// DeletedAt is set while the listing is soft-deleted.
+	DeletedAt *time.Time `json:"-"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Image match statuses. An open match waits in the moderation queue until a
+// moderator dismisses it or removes the image.
+const (
+	MatchOpen      = "open"
+	MatchDismissed = "dismissed"
+	MatchRemoved   = "removed"
+)
+
+// ImageMatch is a listing image that looks like an image on another
+// seller's listing. Distance is the Hamming distance between the two
+// perceptual hashes; 0 means the same picture.
+type ImageMatch struct {
+	ID               int64     `json:"id"`
+	ListingID        int64     `json:"listing_id"`
+	UploadID         int64     `json:"upload_id"`
+	ImageURL         string    `json:"image_url"`
+	MatchedListingID int64     `json:"matched_listing_id"`
+	MatchedUploadID  int64     `json:"matched_upload_id"`
+	MatchedImageURL  string    `json:"matched_image_url"`
+	MatchedSellerID  int64     `json:"matched_seller_id"`
+	Distance         int       `json:"distance"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// BlockedImage is an image removed by a moderator. Uploads with the same
+// content hash are refused.
+type BlockedImage struct {
+	SHA256    string `json:"sha256"`
+	PHash     *int64 `json:"phash,omitempty"`
+	Reason    string `json:"reason"`
+	BlockedBy int64  `json:"blocked_by"`
+}
+
+// ModerationQueueItem gathers everything awaiting review for one listing.
+type ModerationQueueItem struct {
+	Listing      Listing      `json:"listing"`
+	Reports      []Report     `json:"reports"`
+	ImageMatches []ImageMatch `json:"image_matches"`
+}
+
+type RemoveImageRequest struct {
+	ImageURL string `json:"image_url"`
+	Reason   string `json:"reason"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Upload visibilities.
+const (
+	UploadPublic  = "public"
+	UploadPrivate = "private"
+)
+
+// Upload records a stored file. SizeBytes includes generated variants, as
+// that is what counts against the owner's quota. PHash is the perceptual
+// hash of the image bits stored as a signed integer; it is nil for uploads
+// made before hashing was introduced. HeldAt is set once a moderator removed
+// the image, which keeps the upload as evidence instead of sweeping it.
+type Upload struct {
+	ID          int64      `json:"id"`
+	OwnerID     int64      `json:"owner_id"`
+	Key         string     `json:"key"`
+	SizeBytes   int64      `json:"size_bytes"`
+	SHA256      string     `json:"sha256"`
+	PHash       *int64     `json:"phash,omitempty"`
+	ContentType string     `json:"content_type"`
+	Visibility  string     `json:"visibility"`
+	ListingID   *int64     `json:"listing_id,omitempty"`
+	AttachedAt  *time.Time `json:"attached_at,omitempty"`
+	HeldAt      *time.Time `json:"held_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -8,6 +8,14 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
 	University   string    `json:"university,omitempty"`
+	IsModerator  bool      `json:"is_moderator"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	// DeletedAt is set while the account is soft-deleted. AnonymizedAt is
+	// set once its owner closed it and its personal data was erased.
+	DeletedAt    *time.Time `json:"-"`
+	AnonymizedAt *time.Time `json:"-"`
 }
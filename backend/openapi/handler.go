@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"uniswap-campus-marketplace/router"
+)
+
+// SpecHandler serves the specification generated from the router's route
+// table. The document is built on first request, once every route is
+// registered.
+func SpecHandler(info Info, rt *router.Router) http.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			body, err = json.MarshalIndent(Generate(info, rt.Routes()), "", "  ")
+		})
+		if err != nil {
+			http.Error(w, "failed to build specification", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}
+}
+
+// Swagger UI is loaded from unpkg at a pinned version, and the browser
+// refuses the files unless they match these Subresource Integrity hashes,
+// so a compromised CDN cannot run script on the docs page. Update the
+// version and hashes together.
+const (
+	swaggerUIBase      = "https://unpkg.com/swagger-ui-dist@5.18.2/"
+	swaggerUICSSSRI    = "sha384-rcbEi6xgdPk0iWkAQzT2F3FeBJXdG+ydrawGlfHAFIZG7wU6aKbQaRewysYpmrlW"
+	swaggerUIBundleSRI = "sha384-NXtFPpN61oWCuN4D42K6Zd5Rt2+uxeIT36R7kpXBuY9tLnZorzrJ4ykpqwJfgjpZ"
+)
+
+// docsContentSecurityPolicy relaxes the API's default policy just enough for
+// Swagger UI, which is loaded from the pinned unpkg path and started by an
+// inline script.
+const docsContentSecurityPolicy = "default-src 'none'; script-src 'unsafe-inline' " + swaggerUIBase + "swagger-ui-bundle.js; " +
+	"style-src 'unsafe-inline' " + swaggerUIBase + "swagger-ui.css; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'"
+
+// DocsHandler serves a Swagger UI page pointed at specURL.
+func DocsHandler(title, specURL string) http.HandlerFunc {
+	page := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>` + title + `</title>
+  <link rel="stylesheet" href="` + swaggerUIBase + `swagger-ui.css" integrity="` + swaggerUICSSSRI + `" crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="` + swaggerUIBase + `swagger-ui-bundle.js" integrity="` + swaggerUIBundleSRI + `" crossorigin="anonymous"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "` + specURL + `", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", docsContentSecurityPolicy)
+		_, _ = w.Write([]byte(page))
+	}
+}
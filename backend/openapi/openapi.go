@@ -0,0 +1,378 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"uniswap-campus-marketplace/apierror"
+	"uniswap-campus-marketplace/router"
+	"uniswap-campus-marketplace/validation"
+)
+
+const envelopeSchema = "apiResponse"
+const errorSchema = "errorResponse"
+const problemSchema = "problemDetails"
+
+// Info is the metadata block of the generated document.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is the subset of an OpenAPI 3.1 document the API uses.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+}
+
+// Generate builds the specification from the documented routes of the route
+// table. Routes without a Doc are left out, which the route tests catch.
+func Generate(info Info, routes []router.Route) *Document {
+	g := &generator{schemas: map[string]*Schema{
+		envelopeSchema: {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+				"data":    {},
+				"error":   {Type: "string"},
+			},
+			Required: []string{"success"},
+		},
+	}}
+	codes := &Schema{Type: "string"}
+	for _, apiErr := range apierror.Catalog() {
+		codes.Enum = append(codes.Enum, string(apiErr.Code))
+	}
+	details := g.schemaFor(reflect.TypeOf([]validation.FieldError{}))
+	meta := &Schema{Type: "object", Description: "Error-specific context, e.g. remaining_bytes for UPLOAD_QUOTA_EXCEEDED"}
+	g.schemas[errorSchema] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"error":   {Type: "string"},
+			"code":    codes,
+			"details": details,
+			"meta":    meta,
+		},
+		Required: []string{"success", "error", "code"},
+	}
+	g.schemas[problemSchema] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":     {Type: "string"},
+			"title":    {Type: "string"},
+			"status":   {Type: "integer", Format: "int32"},
+			"detail":   {Type: "string"},
+			"instance": {Type: "string"},
+			"code":     codes,
+			"errors":   details,
+			"meta":     meta,
+		},
+		Required: []string{"type", "title", "status", "code"},
+	}
+
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]map[string]Operation),
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		if route.Doc == nil {
+			continue
+		}
+		path, params := pathTemplate(route.Pattern)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = g.operation(route, params)
+	}
+
+	return doc
+}
+
+// PathTemplate converts a ServeMux pattern into its OpenAPI path form.
+func PathTemplate(pattern string) string {
+	path, _ := pathTemplate(pattern)
+	return path
+}
+
+func pathTemplate(pattern string) (string, []string) {
+	segments := strings.Split(pattern, "/")
+	var params []string
+	for i, segment := range segments {
+		if segment == "{$}" {
+			segments[i] = ""
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := strings.TrimSuffix(strings.Trim(segment, "{}"), "...")
+			segments[i] = "{" + name + "}"
+			params = append(params, name)
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// pathParamSchema types a path parameter by its name: {id} and {*_id} are
+// database IDs, anything else, such as an upload {key...}, is a string.
+func pathParamSchema(name string) *Schema {
+	if name == "id" || strings.HasSuffix(name, "_id") {
+		return &Schema{Type: "integer", Format: "int64"}
+	}
+	return &Schema{Type: "string"}
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func (g *generator) operation(route router.Route, pathParams []string) Operation {
+	d := route.Doc
+	op := Operation{
+		Summary:   d.Summary,
+		Tags:      d.Tags,
+		Responses: make(map[string]Response),
+	}
+
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   pathParamSchema(name),
+		})
+	}
+	for _, param := range d.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+	for _, param := range d.Headers {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        param.Name,
+			In:          "header",
+			Description: param.Description,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	switch {
+	case d.Request != nil:
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: g.schemaFor(reflect.TypeOf(d.Request))},
+			},
+		}
+	case d.Upload != "":
+		properties := map[string]*Schema{
+			d.Upload: {Type: "string", Format: "binary"},
+		}
+		for _, field := range d.FormFields {
+			properties[field.Name] = &Schema{Type: "string", Description: field.Description}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"multipart/form-data": {Schema: &Schema{
+					Type:       "object",
+					Properties: properties,
+					Required:   []string{d.Upload},
+				}},
+			},
+		}
+	}
+
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = g.successResponse(status, d)
+
+	errors := append([]int(nil), d.Errors...)
+	if route.RequireAuth {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		errors = append(errors, http.StatusUnauthorized)
+	}
+	sort.Ints(errors)
+	for _, code := range errors {
+		op.Responses[strconv.Itoa(code)] = Response{
+			Description: http.StatusText(code),
+			Content: map[string]MediaType{
+				"application/json":         {Schema: &Schema{Ref: schemaRef(errorSchema)}},
+				"application/problem+json": {Schema: &Schema{Ref: schemaRef(problemSchema)}},
+			},
+		}
+	}
+
+	return op
+}
+
+func (g *generator) successResponse(status int, d *router.Doc) Response {
+	resp := Response{Description: http.StatusText(status)}
+	if d.Raw != "" {
+		resp.Content = map[string]MediaType{d.Raw: {}}
+		return resp
+	}
+
+	schema := &Schema{Ref: schemaRef(envelopeSchema)}
+	if d.Response != nil {
+		schema = &Schema{AllOf: []*Schema{
+			schema,
+			{Type: "object", Properties: map[string]*Schema{"data": g.dataSchema(d.Response)}},
+		}}
+	}
+	resp.Content = map[string]MediaType{"application/json": {Schema: schema}}
+	return resp
+}
+
+// dataSchema documents map payloads by their keys; everything else is
+// reflected from its type.
+func (g *generator) dataSchema(value interface{}) *Schema {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String || v.Len() == 0 {
+		return g.schemaFor(v.Type())
+	}
+
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	iter := v.MapRange()
+	for iter.Next() {
+		schema.Properties[iter.Key().String()] = g.schemaFor(v.Type().Elem())
+	}
+	return schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name first so self-referencing types terminate.
+			g.schemas[name] = nil
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: schemaRef(name)}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schemaFor(field.Type)
+	}
+	return schema
+}
+
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
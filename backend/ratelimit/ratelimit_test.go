@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestStore() (*MemoryStore, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func take(t *testing.T, s *MemoryStore, key string, limit Limit) Result {
+	t.Helper()
+	result, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	return result
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	s, now := newTestStore()
+	limit := Limit{Requests: 3, Per: time.Minute}
+
+	// A new bucket is full: the burst is allowed, the next request is not.
+	for want := 2; want >= 0; want-- {
+		result := take(t, s, "ip:1", limit)
+		if !result.Allowed || result.Remaining != want || result.Limit != 3 {
+			t.Fatalf("Take = %+v, want allowed with %d remaining", result, want)
+		}
+	}
+	result := take(t, s, "ip:1", limit)
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Take over the limit = %+v, want rejected", result)
+	}
+	if result.RetryAfter != 20*time.Second || result.Reset != time.Minute {
+		t.Errorf("RetryAfter = %v, Reset = %v; want 20s and 1m", result.RetryAfter, result.Reset)
+	}
+
+	// Other keys have buckets of their own.
+	if result := take(t, s, "ip:2", limit); !result.Allowed {
+		t.Errorf("Take on another key = %+v, want allowed", result)
+	}
+
+	// Tokens refill continuously, one every 20s, up to the limit.
+	*now = now.Add(20 * time.Second)
+	if result := take(t, s, "ip:1", limit); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Take after one refill = %+v, want allowed with none remaining", result)
+	}
+	*now = now.Add(time.Hour)
+	if result := take(t, s, "ip:1", limit); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Take after a long idle = %+v, want a full bucket", result)
+	}
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	s, now := newTestStore()
+	limit := Limit{Requests: 1, Per: time.Minute}
+	take(t, s, "idle", limit)
+	*now = now.Add(time.Hour)
+	take(t, s, "busy", limit)
+
+	s.Cleanup(time.Minute)
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("idle bucket was kept")
+	}
+	if _, ok := s.buckets["busy"]; !ok {
+		t.Error("busy bucket was dropped")
+	}
+}
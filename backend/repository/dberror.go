@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// PostgreSQL SQLSTATE codes the repositories act on.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	codeForeignKeyViolation  pq.ErrorCode = "23503"
+	codeUniqueViolation      pq.ErrorCode = "23505"
+	codeCheckViolation       pq.ErrorCode = "23514"
+	codeSerializationFailure pq.ErrorCode = "40001"
+	codeDeadlockDetected     pq.ErrorCode = "40P01"
+)
+
+// Generic constraint violations, for constraints without a more specific
+// domain error. Every error returned by constraintViolation matches one of
+// these with errors.Is.
+var (
+	ErrUniqueViolation     = errors.New("unique constraint violated")
+	ErrForeignKeyViolation = errors.New("foreign key constraint violated")
+	ErrCheckViolation      = errors.New("check constraint violated")
+)
+
+var (
+	ErrSellerNotFound = errors.New("seller not found")
+	ErrInvalidPrice   = errors.New("price must not be negative")
+)
+
+// constraintErrors maps constraint names, as PostgreSQL generates them for
+// the tables in db/migrations, onto domain errors. The SQLite migrations
+// name their CHECK constraints the same way; see sqliteConstraintViolation
+// and nameForeignKey for the rest.
+var constraintErrors = map[string]error{
+	"users_email_key":                      ErrEmailAlreadyExists,
+	"listings_seller_id_fkey":              ErrSellerNotFound,
+	"listings_price_check":                 ErrInvalidPrice,
+	"listing_images_listing_id_fkey":       ErrListingNotFound,
+	"reports_listing_id_fkey":              ErrListingNotFound,
+	"reports_reporter_id_fkey":             ErrUserNotFound,
+	"uploads_owner_id_fkey":                ErrUserNotFound,
+	"uploads_listing_id_fkey":              ErrListingNotFound,
+	"image_matches_listing_id_fkey":        ErrListingNotFound,
+	"image_matches_upload_id_fkey":         ErrUploadNotFound,
+	"image_matches_matched_upload_id_fkey": ErrUploadNotFound,
+}
+
+// ConstraintError is an integrity constraint violation translated from a
+// PostgreSQL or SQLite error. It matches both its domain error, when the constraint
+// has one, and the generic violation with errors.Is.
+type ConstraintError struct {
+	Constraint string
+	Table      string
+	domain     error
+	kind       error
+	cause      error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.domain != nil {
+		return e.domain.Error()
+	}
+	return e.kind.Error() + ": " + e.Constraint
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	if e.domain != nil {
+		return []error{e.domain, e.kind, e.cause}
+	}
+	return []error{e.kind, e.cause}
+}
+
+// constraintViolation translates err into a *ConstraintError when it is a
+// unique, foreign key or check violation, and returns nil otherwise.
+// Callers return the translated error as is: a violated constraint is a
+// client error, not a failed query.
+func constraintViolation(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteConstraintViolation(sqliteErr)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+
+	var kind error
+	switch pqErr.Code {
+	case codeUniqueViolation:
+		kind = ErrUniqueViolation
+	case codeForeignKeyViolation:
+		kind = ErrForeignKeyViolation
+	case codeCheckViolation:
+		kind = ErrCheckViolation
+	default:
+		return nil
+	}
+
+	return &ConstraintError{
+		Constraint: pqErr.Constraint,
+		Table:      pqErr.Table,
+		domain:     constraintErrors[pqErr.Constraint],
+		kind:       kind,
+		cause:      pqErr,
+	}
+}
+
+// sqliteConstraintViolation is constraintViolation for SQLite, which names
+// the constraint only in its message: "UNIQUE constraint failed: users.email"
+// gives the columns, from which the name PostgreSQL would have generated is
+// rebuilt, and "CHECK constraint failed: listings_price_check" the name.
+// Foreign key violations name nothing; see nameForeignKey.
+func sqliteConstraintViolation(err *sqlite.Error) error {
+	_, detail, _ := strings.Cut(err.Error(), " constraint failed: ")
+	detail, _, _ = strings.Cut(detail, " (")
+
+	cerr := &ConstraintError{cause: err}
+	switch err.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		cerr.kind = ErrUniqueViolation
+		columns := strings.Split(detail, ", ")
+		for i, column := range columns {
+			cerr.Table, columns[i], _ = strings.Cut(column, ".")
+		}
+		cerr.Constraint = cerr.Table + "_" + strings.Join(columns, "_") + "_key"
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		cerr.kind = ErrUniqueViolation
+		cerr.Table, _, _ = strings.Cut(detail, ".")
+		cerr.Constraint = cerr.Table + "_pkey"
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		cerr.kind = ErrForeignKeyViolation
+	case sqlite3.SQLITE_CONSTRAINT_CHECK:
+		cerr.kind = ErrCheckViolation
+		cerr.Constraint = detail
+	default:
+		return nil
+	}
+
+	cerr.domain = constraintErrors[cerr.Constraint]
+	return cerr
+}
+
+// foreignKey is a parent row that a write references: the constraint, as
+// PostgreSQL names it, the parent table and the referenced ID.
+type foreignKey struct {
+	constraint string
+	table      string
+	id         int64
+}
+
+// nameForeignKey names the foreign key of a violation SQLite reported
+// without one, by looking up the parents the failed write referenced, in
+// the same transaction: the first one missing is the violated constraint.
+// Any other error, including PostgreSQL's, which name their constraint, is
+// returned as is.
+func nameForeignKey(ctx context.Context, db DBTX, err error, table string, keys ...foreignKey) error {
+	cerr, ok := errors.AsType[*ConstraintError](err)
+	if !ok || cerr.kind != ErrForeignKeyViolation || cerr.Constraint != "" {
+		return err
+	}
+	for _, key := range keys {
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM ` + key.table + ` WHERE id = $1)`
+		if err := db.QueryRowContext(ctx, query, key.id).Scan(&exists); err != nil {
+			break
+		}
+		if !exists {
+			cerr.Constraint, cerr.Table = key.constraint, table
+			cerr.domain = constraintErrors[key.constraint]
+			break
+		}
+	}
+	return cerr
+}
+
+// isSerializationFailure reports whether err is a serialization failure or
+// deadlock, after which the whole transaction can safely be run again. For
+// SQLite that is a database left locked by another process past the busy
+// timeout.
+func isSerializationFailure(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == codeSerializationFailure || pqErr.Code == codeDeadlockDetected
+}
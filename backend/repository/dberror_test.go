@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestConstraintViolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		domain error
+		kind   error
+	}{
+		{
+			name:   "duplicate email",
+			err:    &pq.Error{Code: codeUniqueViolation, Constraint: "users_email_key", Table: "users"},
+			domain: ErrEmailAlreadyExists,
+			kind:   ErrUniqueViolation,
+		},
+		{
+			name:   "missing seller",
+			err:    &pq.Error{Code: codeForeignKeyViolation, Constraint: "listings_seller_id_fkey", Table: "listings"},
+			domain: ErrSellerNotFound,
+			kind:   ErrForeignKeyViolation,
+		},
+		{
+			name:   "negative price",
+			err:    &pq.Error{Code: codeCheckViolation, Constraint: "listings_price_check", Table: "listings"},
+			domain: ErrInvalidPrice,
+			kind:   ErrCheckViolation,
+		},
+		{
+			name:   "wrapped",
+			err:    fmt.Errorf("create report: %w", &pq.Error{Code: codeForeignKeyViolation, Constraint: "reports_listing_id_fkey"}),
+			domain: ErrListingNotFound,
+			kind:   ErrForeignKeyViolation,
+		},
+		{
+			name: "unmapped constraint",
+			err:  &pq.Error{Code: codeUniqueViolation, Constraint: "uploads_storage_key_key"},
+			kind: ErrUniqueViolation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := constraintViolation(tt.err)
+			if err == nil {
+				t.Fatal("constraintViolation = nil")
+			}
+			if tt.domain != nil && !errors.Is(err, tt.domain) {
+				t.Errorf("err = %v, want %v", err, tt.domain)
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("err = %v, want %v", err, tt.kind)
+			}
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) {
+				t.Error("the PostgreSQL error is not kept")
+			}
+		})
+	}
+
+	for _, err := range []error{
+		errors.New("connection refused"),
+		&pq.Error{Code: codeSerializationFailure},
+	} {
+		if got := constraintViolation(err); got != nil {
+			t.Errorf("constraintViolation(%v) = %v, want nil", err, got)
+		}
+	}
+}
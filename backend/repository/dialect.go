@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Dialect is the SQL flavour of a database. PostgreSQL and SQLite share the
+// SQL repositories, which ask the dialect only for what the two spell
+// differently.
+type Dialect struct {
+	// driver is the database/sql driver name.
+	driver string
+	// system is the OpenTelemetry db.system of the database.
+	system string
+}
+
+var (
+	Postgres = Dialect{driver: "postgres", system: "postgresql"}
+	SQLite   = Dialect{driver: "sqlite", system: "sqlite"}
+)
+
+// DialectFor returns the dialect of a database/sql driver name.
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case Postgres.driver:
+		return Postgres, nil
+	case SQLite.driver:
+		return SQLite, nil
+	default:
+		return Dialect{}, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// Driver returns the database/sql driver name of d.
+func (d Dialect) Driver() string {
+	return d.driver
+}
+
+// ilike is the case-insensitive LIKE operator. SQLite's LIKE already ignores
+// case, for ASCII letters at least.
+func (d Dialect) ilike() string {
+	if d == SQLite {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
+
+// inList renders a condition that column equals one of values, numbering
+// its placeholders from pos, and returns it with the arguments to bind.
+// PostgreSQL takes the values as a single array; SQLite, which has no
+// arrays, gets one placeholder per value, so the list must come last in the
+// query.
+func inList[T any](d Dialect, column string, pos int, values []T) (string, []any) {
+	if d != SQLite {
+		return column + " = ANY($" + strconv.Itoa(pos) + ")", []any{pq.Array(values)}
+	}
+
+	placeholders := make([]string, len(values))
+	args := make([]any, len(values))
+	for i, value := range values {
+		placeholders[i] = "$" + strconv.Itoa(pos+i)
+		args[i] = value
+	}
+	return column + " IN (" + strings.Join(placeholders, ", ") + ")", args
+}
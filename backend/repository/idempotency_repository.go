@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+type IdempotencyRepository interface {
+	// Claim records that the user's request with the key is being processed
+	// and returns nil, or returns the record already held under the key.
+	// Records created before expiredBefore, and unfinished ones created
+	// before abandonedBefore, no longer hold the key.
+	Claim(ctx context.Context, record *models.IdempotencyRecord, expiredBefore, abandonedBefore time.Time) (*models.IdempotencyRecord, error)
+	// Complete stores the response to a claimed request.
+	Complete(ctx context.Context, userID int64, key string, response *models.StoredResponse) error
+	// Release frees a claimed key, so that the request can be retried.
+	Release(ctx context.Context, userID int64, key string) error
+	// DeleteExpired deletes records created before the cutoff and returns
+	// how many it deleted.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SQLIdempotencyRepository stores idempotency records in PostgreSQL or
+// SQLite.
+type SQLIdempotencyRepository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewSQLIdempotencyRepository(db *sql.DB, dialect Dialect) *SQLIdempotencyRepository {
+	return &SQLIdempotencyRepository{db: db, dialect: dialect}
+}
+
+func (r *SQLIdempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyRecord, expiredBefore, abandonedBefore time.Time) (*models.IdempotencyRecord, error) {
+	ctx, span := startSpan(ctx, r.dialect, "IdempotencyRepository.Claim", "idempotency_keys", "INSERT")
+	defer span.End()
+
+	const deleteQuery = `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+			AND (created_at < $3 OR (status_code IS NULL AND created_at < $4))
+	`
+	// A concurrent claim of the same key makes the insert wait for it and
+	// then do nothing.
+	const insertQuery = `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`
+	const selectQuery = `
+		SELECT fingerprint, status_code, response_headers, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+
+	var existing *models.IdempotencyRecord
+	err := atomic(ctx, r.db, func(ctx context.Context) error {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, deleteQuery, record.UserID, record.Key, expiredBefore.UTC(), abandonedBefore.UTC()); err != nil {
+			return fmt.Errorf("delete stale idempotency key: %w", err)
+		}
+
+		result, err := conn(ctx, r.db).ExecContext(ctx, insertQuery, record.UserID, record.Key, record.Fingerprint, record.CreatedAt.UTC())
+		if err != nil {
+			if cerr := constraintViolation(err); cerr != nil {
+				return cerr
+			}
+			return fmt.Errorf("claim idempotency key: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("claim idempotency key: %w", err)
+		}
+		if n == 1 {
+			return nil
+		}
+
+		var (
+			status  sql.NullInt64
+			headers sql.NullString
+			body    []byte
+		)
+		existing = &models.IdempotencyRecord{UserID: record.UserID, Key: record.Key}
+		err = conn(ctx, r.db).QueryRowContext(ctx, selectQuery, record.UserID, record.Key).Scan(
+			&existing.Fingerprint,
+			&status,
+			&headers,
+			&body,
+			&existing.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("get idempotency key: %w", err)
+		}
+		if status.Valid {
+			existing.Response = &models.StoredResponse{Status: int(status.Int64), Body: body}
+			if headers.Valid {
+				if err := json.Unmarshal([]byte(headers.String), &existing.Response.Header); err != nil {
+					return fmt.Errorf("decode stored response headers: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := errors.AsType[*ConstraintError](err); !ok {
+			queryFailed(ctx, span, "claim_idempotency_key", err)
+		}
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (r *SQLIdempotencyRepository) Complete(ctx context.Context, userID int64, key string, response *models.StoredResponse) error {
+	ctx, span := startSpan(ctx, r.dialect, "IdempotencyRepository.Complete", "idempotency_keys", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE idempotency_keys
+		SET status_code = $3, response_headers = $4, response_body = $5
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+
+	headers, err := json.Marshal(response.Header)
+	if err != nil {
+		return fmt.Errorf("encode stored response headers: %w", err)
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, key, response.Status, string(headers), response.Body); err != nil {
+		queryFailed(ctx, span, "complete_idempotency_key", err)
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLIdempotencyRepository) Release(ctx context.Context, userID int64, key string) error {
+	ctx, span := startSpan(ctx, r.dialect, "IdempotencyRepository.Release", "idempotency_keys", "DELETE")
+	defer span.End()
+
+	const query = `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, key); err != nil {
+		queryFailed(ctx, span, "release_idempotency_key", err)
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, r.dialect, "IdempotencyRepository.DeleteExpired", "idempotency_keys", "DELETE")
+	defer span.End()
+
+	const query = `DELETE FROM idempotency_keys WHERE created_at < $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "delete_expired_idempotency_keys", err)
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "delete_expired_idempotency_keys", err)
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+
+	return n, nil
+}
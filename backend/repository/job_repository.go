@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+var (
+	// ErrDuplicateJob is returned when enqueuing a job whose dedupe key a
+	// stored job already has, whether it is still waiting or finished but
+	// not yet deleted by DeleteFinished.
+	ErrDuplicateJob = errors.New("a job with this dedupe key already exists")
+	// ErrJobLeaseLost is returned when recording the outcome of an attempt
+	// whose lock ran out, so that another worker took the job over; the
+	// outcome of the newer attempt is left alone.
+	ErrJobLeaseLost = errors.New("job was taken over by another worker")
+)
+
+type JobRepository interface {
+	// Enqueue adds a pending job. Inside a transaction the job is only
+	// enqueued if the transaction commits.
+	Enqueue(ctx context.Context, job *models.Job) (*models.Job, error)
+	// Claim marks the oldest job of one of kinds that is due at now, or
+	// whose worker's lock ran out, as running until lockedUntil, counts the
+	// attempt and returns the job. It returns nil when no job is waiting.
+	Claim(ctx context.Context, kinds []string, now, lockedUntil time.Time) (*models.Job, error)
+	// Complete, Retry, Release and Bury record the outcome of attempt, the
+	// job's Attempts when it was claimed. They return ErrJobLeaseLost when
+	// the job is no longer running that attempt.
+	//
+	// Complete marks a running job as succeeded.
+	Complete(ctx context.Context, id int64, attempt int, at time.Time) error
+	// Retry puts a running job that failed back in the queue to run again
+	// at runAt.
+	Retry(ctx context.Context, id int64, attempt int, runAt time.Time, lastErr string) error
+	// Release puts a running job back in the queue without counting the
+	// attempt, e.g. when the worker was interrupted by shutdown.
+	Release(ctx context.Context, id int64, attempt int, runAt time.Time) error
+	// Bury marks a running job as dead: it failed for good.
+	Bury(ctx context.Context, id int64, attempt int, at time.Time, lastErr string) error
+	// DeleteFinished deletes succeeded and dead jobs finished before the
+	// cutoff and returns how many it deleted.
+	DeleteFinished(ctx context.Context, before time.Time) (int64, error)
+}
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, locked_until, last_error, dedupe_key, created_at, finished_at`
+
+func scanJob(row rowScanner) (*models.Job, error) {
+	job := &models.Job{}
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&job.Payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedUntil,
+		&job.LastError,
+		&job.DedupeKey,
+		&job.CreatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// SQLJobRepository keeps the job queue in PostgreSQL or SQLite.
+type SQLJobRepository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewSQLJobRepository(db *sql.DB, dialect Dialect) *SQLJobRepository {
+	return &SQLJobRepository{db: db, dialect: dialect}
+}
+
+func (r *SQLJobRepository) Enqueue(ctx context.Context, job *models.Job) (*models.Job, error) {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.Enqueue", "jobs", "INSERT")
+	defer span.End()
+
+	const query = `
+		INSERT INTO jobs (kind, payload, max_attempts, run_at, dedupe_key)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (dedupe_key) DO NOTHING
+		RETURNING ` + jobColumns
+
+	created, err := scanJob(conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		job.Kind,
+		job.Payload,
+		job.MaxAttempts,
+		job.RunAt.UTC(),
+		job.DedupeKey,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDuplicateJob
+		}
+		if cerr := constraintViolation(err); cerr != nil {
+			return nil, cerr
+		}
+		queryFailed(ctx, span, "enqueue_job", err)
+		return nil, fmt.Errorf("enqueue job: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *SQLJobRepository) Claim(ctx context.Context, kinds []string, now, lockedUntil time.Time) (*models.Job, error) {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.Claim", "jobs", "UPDATE")
+	defer span.End()
+
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	// SKIP LOCKED lets concurrent workers pass over the rows other workers
+	// are claiming instead of queueing behind them. SQLite has no row locks;
+	// it runs one write at a time, which makes the statement atomic anyway.
+	lock := ""
+	if r.dialect == Postgres {
+		lock = "FOR UPDATE SKIP LOCKED"
+	}
+	kindIn, kindArgs := inList(r.dialect, "kind", 3, kinds)
+	query := `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_until = $2
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE ((status = 'pending' AND run_at <= $1) OR (status = 'running' AND locked_until < $1))
+				AND ` + kindIn + `
+			ORDER BY run_at, id
+			LIMIT 1
+			` + lock + `
+		)
+		RETURNING ` + jobColumns
+
+	args := append([]any{now.UTC(), lockedUntil.UTC()}, kindArgs...)
+	job, err := scanJob(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		queryFailed(ctx, span, "claim_job", err)
+		return nil, fmt.Errorf("claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *SQLJobRepository) Complete(ctx context.Context, id int64, attempt int, at time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.Complete", "jobs", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE jobs
+		SET status = 'succeeded', locked_until = NULL, finished_at = $3
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, attempt, at.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "complete_job", err)
+		return fmt.Errorf("complete job: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "complete_job", err)
+		return fmt.Errorf("complete job: %w", err)
+	}
+	if updated == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+func (r *SQLJobRepository) Retry(ctx context.Context, id int64, attempt int, runAt time.Time, lastErr string) error {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.Retry", "jobs", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE jobs
+		SET status = 'pending', locked_until = NULL, run_at = $3, last_error = $4
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, attempt, runAt.UTC(), lastErr)
+	if err != nil {
+		queryFailed(ctx, span, "retry_job", err)
+		return fmt.Errorf("retry job: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "retry_job", err)
+		return fmt.Errorf("retry job: %w", err)
+	}
+	if updated == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+func (r *SQLJobRepository) Release(ctx context.Context, id int64, attempt int, runAt time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.Release", "jobs", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE jobs
+		SET status = 'pending', locked_until = NULL, run_at = $3, attempts = attempts - 1
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, attempt, runAt.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "release_job", err)
+		return fmt.Errorf("release job: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "release_job", err)
+		return fmt.Errorf("release job: %w", err)
+	}
+	if updated == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+func (r *SQLJobRepository) Bury(ctx context.Context, id int64, attempt int, at time.Time, lastErr string) error {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.Bury", "jobs", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE jobs
+		SET status = 'dead', locked_until = NULL, finished_at = $3, last_error = $4
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, attempt, at.UTC(), lastErr)
+	if err != nil {
+		queryFailed(ctx, span, "bury_job", err)
+		return fmt.Errorf("bury job: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "bury_job", err)
+		return fmt.Errorf("bury job: %w", err)
+	}
+	if updated == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+func (r *SQLJobRepository) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, r.dialect, "JobRepository.DeleteFinished", "jobs", "DELETE")
+	defer span.End()
+
+	const query = `DELETE FROM jobs WHERE status IN ('succeeded', 'dead') AND finished_at < $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "delete_finished_jobs", err)
+		return 0, fmt.Errorf("delete finished jobs: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "delete_finished_jobs", err)
+		return 0, fmt.Errorf("delete finished jobs: %w", err)
+	}
+
+	return n, nil
+}
@@ -6,63 +6,126 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"uniswap-campus-marketplace/models"
 )
 
 var ErrListingNotFound = errors.New("listing not found")
 
+// ErrListingVersionMismatch is returned when updating a listing that was
+// changed since the caller read it.
+var ErrListingVersionMismatch = errors.New("listing was changed by someone else")
+
+// ListingRepository skips soft-deleted listings unless a method says
+// otherwise.
 type ListingRepository interface {
 	Create(ctx context.Context, listing *models.Listing) (*models.Listing, error)
 	GetAll(ctx context.Context, search string) ([]models.Listing, error)
 	GetByID(ctx context.Context, id int64) (*models.Listing, error)
+	// Update replaces the fields and images of the listing if it is still
+	// at version, and bumps the version. It returns ErrListingVersionMismatch
+	// if the listing has changed since.
+	Update(ctx context.Context, listing *models.Listing, version int64) (*models.Listing, error)
+	// ListBySeller returns the seller's listings, newest first.
+	ListBySeller(ctx context.Context, sellerID int64) ([]models.Listing, error)
+	// GetByIDs returns the listings that exist among ids, in no particular
+	// order.
+	GetByIDs(ctx context.Context, ids []int64) ([]models.Listing, error)
+	// RemoveImage takes the image at url off every listing showing it,
+	// promoting the next image where it was the primary one and bumping the
+	// version, and returns how many listings were affected.
+	RemoveImage(ctx context.Context, url string) (int64, error)
+	// GetDeleted returns a soft-deleted listing, or ErrListingNotFound if
+	// the listing does not exist or is not deleted.
+	GetDeleted(ctx context.Context, id int64) (*models.Listing, error)
+	// SoftDelete hides the listing as of at.
+	SoftDelete(ctx context.Context, id int64, at time.Time) error
+	// SoftDeleteBySeller hides every listing of the seller as of at.
+	SoftDeleteBySeller(ctx context.Context, sellerID int64, at time.Time) error
+	Restore(ctx context.Context, id int64) error
+	// RestoreBySeller restores the seller's listings deleted at or after
+	// deletedSince, i.e. those deleted along with the seller's account.
+	RestoreBySeller(ctx context.Context, sellerID int64, deletedSince time.Time) error
+	// ListDeleted returns the IDs of listings soft-deleted before the
+	// cutoff, oldest first.
+	ListDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error)
+	// Purge deletes soft-deleted listings for good, along with everything
+	// the schema cascades to.
+	Purge(ctx context.Context, ids []int64) error
 }
 
-type PostgresListingRepository struct {
-	db *sql.DB
+// SQLListingRepository stores listings in PostgreSQL or SQLite.
+type SQLListingRepository struct {
+	db      *sql.DB
+	dialect Dialect
 }
 
-func NewPostgresListingRepository(db *sql.DB) *PostgresListingRepository {
-	return &PostgresListingRepository{db: db}
+func NewSQLListingRepository(db *sql.DB, dialect Dialect) *SQLListingRepository {
+	return &SQLListingRepository{db: db, dialect: dialect}
 }
 
-func (r *PostgresListingRepository) Create(ctx context.Context, listing *models.Listing) (*models.Listing, error) {
-	const query = `
+const listingColumns = `id, seller_id, title, description, price, category, created_at, version, deleted_at`
+
+func (r *SQLListingRepository) Create(ctx context.Context, listing *models.Listing) (*models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.Create", "listings", "INSERT")
+	defer span.End()
+
+	query := `
 		INSERT INTO listings (seller_id, title, description, category, price)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, seller_id, title, description, price, category, created_at
+		RETURNING ` + listingColumns
+	const imageQuery = `
+		INSERT INTO listing_images (listing_id, image_url, is_primary)
+		VALUES ($1, $2, $3)
 	`
 
-	created := &models.Listing{}
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		listing.UserID,
-		listing.Title,
-		listing.Description,
-		listing.Category,
-		listing.Price,
-	).Scan(
-		&created.ID,
-		&created.UserID,
-		&created.Title,
-		&created.Description,
-		&created.Price,
-		&created.Category,
-		&created.CreatedAt,
-	)
+	var created *models.Listing
+	err := atomic(ctx, r.db, func(ctx context.Context) error {
+		var err error
+		created, err = scanListing(conn(ctx, r.db).QueryRowContext(
+			ctx,
+			query,
+			listing.UserID,
+			listing.Title,
+			listing.Description,
+			listing.Category,
+			listing.Price,
+		))
+		if err != nil {
+			if cerr := constraintViolation(err); cerr != nil {
+				return nameForeignKey(ctx, conn(ctx, r.db), cerr, "listings",
+					foreignKey{"listings_seller_id_fkey", "users", listing.UserID})
+			}
+			return fmt.Errorf("create listing: %w", err)
+		}
+
+		for _, image := range listing.Images {
+			if _, err := conn(ctx, r.db).ExecContext(ctx, imageQuery, created.ID, image.URL, image.IsPrimary); err != nil {
+				if cerr := constraintViolation(err); cerr != nil {
+					return cerr
+				}
+				return fmt.Errorf("create listing image: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("create listing: %w", err)
+		if _, ok := errors.AsType[*ConstraintError](err); !ok {
+			queryFailed(ctx, span, "create_listing", err)
+		}
+		return nil, err
 	}
 
+	created.Images = append(make([]models.ListingImage, 0, len(listing.Images)), listing.Images...)
 	return created, nil
 }
 
-func (r *PostgresListingRepository) GetAll(ctx context.Context, search string) ([]models.Listing, error) {
-	base := `
-		SELECT id, seller_id, title, description, price, category, created_at
-		FROM listings
-	`
+func (r *SQLListingRepository) GetAll(ctx context.Context, search string) ([]models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.GetAll", "listings", "SELECT")
+	defer span.End()
+
+	base := `SELECT ` + listingColumns + ` FROM listings`
 
 	var (
 		args  []interface{}
@@ -70,51 +133,436 @@ func (r *PostgresListingRepository) GetAll(ctx context.Context, search string) (
 	)
 
 	if strings.TrimSpace(search) != "" {
-		query = base + ` WHERE title ILIKE $1 ORDER BY created_at DESC`
+		query = base + ` WHERE deleted_at IS NULL AND title ` + r.dialect.ilike() + ` $1 ORDER BY created_at DESC, id DESC`
 		args = append(args, "%"+strings.TrimSpace(search)+"%")
 	} else {
-		query = base + ` ORDER BY created_at DESC`
+		query = base + ` WHERE deleted_at IS NULL ORDER BY created_at DESC, id DESC`
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
+		queryFailed(ctx, span, "get_listings", err)
 		return nil, fmt.Errorf("get listings: %w", err)
 	}
 	defer rows.Close()
 
 	listings := make([]models.Listing, 0)
 	for rows.Next() {
-		var listing models.Listing
-		if err := rows.Scan(
-			&listing.ID,
-			&listing.UserID,
-			&listing.Title,
-			&listing.Description,
-			&listing.Price,
-			&listing.Category,
-			&listing.CreatedAt,
-		); err != nil {
+		listing, err := scanListing(rows)
+		if err != nil {
+			queryFailed(ctx, span, "scan_listing", err)
 			return nil, fmt.Errorf("scan listing: %w", err)
 		}
-		listings = append(listings, listing)
+		listings = append(listings, *listing)
 	}
 
 	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_listings", err)
 		return nil, fmt.Errorf("iterate listings: %w", err)
 	}
 
+	if err := r.loadImages(ctx, listings); err != nil {
+		queryFailed(ctx, span, "get_listing_images", err)
+		return nil, err
+	}
+
 	return listings, nil
 }
 
-func (r *PostgresListingRepository) GetByID(ctx context.Context, id int64) (*models.Listing, error) {
+func (r *SQLListingRepository) GetByID(ctx context.Context, id int64) (*models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.GetByID", "listings", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + listingColumns + ` FROM listings WHERE id = $1 AND deleted_at IS NULL`
+
+	listing, err := scanListing(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrListingNotFound
+		}
+		queryFailed(ctx, span, "get_listing_by_id", err)
+		return nil, fmt.Errorf("get listing by id: %w", err)
+	}
+
+	listings := []models.Listing{*listing}
+	if err := r.loadImages(ctx, listings); err != nil {
+		queryFailed(ctx, span, "get_listing_images", err)
+		return nil, err
+	}
+
+	return &listings[0], nil
+}
+
+func (r *SQLListingRepository) Update(ctx context.Context, listing *models.Listing, version int64) (*models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.Update", "listings", "UPDATE")
+	defer span.End()
+
+	query := `
+		UPDATE listings
+		SET title = $3, description = $4, category = $5, price = $6, version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING ` + listingColumns
+	const existsQuery = `SELECT 1 FROM listings WHERE id = $1 AND deleted_at IS NULL`
+	const deleteImagesQuery = `DELETE FROM listing_images WHERE listing_id = $1`
+	const imageQuery = `
+		INSERT INTO listing_images (listing_id, image_url, is_primary)
+		VALUES ($1, $2, $3)
+	`
+
+	var updated *models.Listing
+	err := atomic(ctx, r.db, func(ctx context.Context) error {
+		var err error
+		updated, err = scanListing(conn(ctx, r.db).QueryRowContext(
+			ctx,
+			query,
+			listing.ID,
+			version,
+			listing.Title,
+			listing.Description,
+			listing.Category,
+			listing.Price,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			var one int
+			if err := conn(ctx, r.db).QueryRowContext(ctx, existsQuery, listing.ID).Scan(&one); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrListingNotFound
+				}
+				return fmt.Errorf("check listing: %w", err)
+			}
+			return ErrListingVersionMismatch
+		}
+		if err != nil {
+			if cerr := constraintViolation(err); cerr != nil {
+				return cerr
+			}
+			return fmt.Errorf("update listing: %w", err)
+		}
+
+		if _, err := conn(ctx, r.db).ExecContext(ctx, deleteImagesQuery, listing.ID); err != nil {
+			return fmt.Errorf("delete listing images: %w", err)
+		}
+		for _, image := range listing.Images {
+			if _, err := conn(ctx, r.db).ExecContext(ctx, imageQuery, listing.ID, image.URL, image.IsPrimary); err != nil {
+				if cerr := constraintViolation(err); cerr != nil {
+					return cerr
+				}
+				return fmt.Errorf("create listing image: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := errors.AsType[*ConstraintError](err); !ok && !errors.Is(err, ErrListingNotFound) && !errors.Is(err, ErrListingVersionMismatch) {
+			queryFailed(ctx, span, "update_listing", err)
+		}
+		return nil, err
+	}
+
+	updated.Images = append(make([]models.ListingImage, 0, len(listing.Images)), listing.Images...)
+	return updated, nil
+}
+
+func (r *SQLListingRepository) ListBySeller(ctx context.Context, sellerID int64) ([]models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.ListBySeller", "listings", "SELECT")
+	defer span.End()
+
+	query := `
+		SELECT ` + listingColumns + `
+		FROM listings
+		WHERE seller_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sellerID)
+	if err != nil {
+		queryFailed(ctx, span, "list_seller_listings", err)
+		return nil, fmt.Errorf("list seller listings: %w", err)
+	}
+	defer rows.Close()
+
+	listings := make([]models.Listing, 0)
+	for rows.Next() {
+		listing, err := scanListing(rows)
+		if err != nil {
+			queryFailed(ctx, span, "scan_listing", err)
+			return nil, fmt.Errorf("scan listing: %w", err)
+		}
+		listings = append(listings, *listing)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_listings", err)
+		return nil, fmt.Errorf("iterate listings: %w", err)
+	}
+
+	if err := r.loadImages(ctx, listings); err != nil {
+		queryFailed(ctx, span, "get_listing_images", err)
+		return nil, err
+	}
+
+	return listings, nil
+}
+
+func (r *SQLListingRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.GetByIDs", "listings", "SELECT")
+	defer span.End()
+
+	where, args := inList(r.dialect, "id", 1, ids)
+	query := `
+		SELECT ` + listingColumns + `
+		FROM listings
+		WHERE deleted_at IS NULL AND ` + where
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		queryFailed(ctx, span, "get_listings_by_ids", err)
+		return nil, fmt.Errorf("get listings by ids: %w", err)
+	}
+	defer rows.Close()
+
+	listings := make([]models.Listing, 0, len(ids))
+	for rows.Next() {
+		listing, err := scanListing(rows)
+		if err != nil {
+			queryFailed(ctx, span, "scan_listing", err)
+			return nil, fmt.Errorf("scan listing: %w", err)
+		}
+		listings = append(listings, *listing)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_listings", err)
+		return nil, fmt.Errorf("iterate listings: %w", err)
+	}
+
+	if err := r.loadImages(ctx, listings); err != nil {
+		queryFailed(ctx, span, "get_listing_images", err)
+		return nil, err
+	}
+
+	return listings, nil
+}
+
+func (r *SQLListingRepository) RemoveImage(ctx context.Context, url string) (int64, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.RemoveImage", "listing_images", "DELETE")
+	defer span.End()
+
+	const deleteQuery = `
+		DELETE FROM listing_images
+		WHERE image_url = $1
+		RETURNING listing_id
+	`
+
+	var listingIDs []int64
+	err := atomic(ctx, r.db, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.db).QueryContext(ctx, deleteQuery, url)
+		if err != nil {
+			return fmt.Errorf("remove listing image: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan removed listing image: %w", err)
+			}
+			listingIDs = append(listingIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate removed listing images: %w", err)
+		}
+
+		if len(listingIDs) > 0 {
+			where, args := inList(r.dialect, "listing_id", 1, listingIDs)
+			promoteQuery := `
+				UPDATE listing_images
+				SET is_primary = TRUE
+				WHERE id IN (
+					SELECT MIN(id) FROM listing_images
+					WHERE ` + where + `
+					GROUP BY listing_id
+					HAVING MAX(CASE WHEN is_primary THEN 1 ELSE 0 END) = 0
+				)
+			`
+			if _, err := conn(ctx, r.db).ExecContext(ctx, promoteQuery, args...); err != nil {
+				return fmt.Errorf("promote listing image: %w", err)
+			}
+			where, args = inList(r.dialect, "id", 1, listingIDs)
+			versionQuery := `UPDATE listings SET version = version + 1 WHERE ` + where
+			if _, err := conn(ctx, r.db).ExecContext(ctx, versionQuery, args...); err != nil {
+				return fmt.Errorf("bump listing version: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		queryFailed(ctx, span, "remove_listing_image", err)
+		return 0, err
+	}
+
+	return int64(len(listingIDs)), nil
+}
+
+func (r *SQLListingRepository) GetDeleted(ctx context.Context, id int64) (*models.Listing, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.GetDeleted", "listings", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + listingColumns + ` FROM listings WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	listing, err := scanListing(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrListingNotFound
+		}
+		queryFailed(ctx, span, "get_deleted_listing", err)
+		return nil, fmt.Errorf("get deleted listing: %w", err)
+	}
+
+	listings := []models.Listing{*listing}
+	if err := r.loadImages(ctx, listings); err != nil {
+		queryFailed(ctx, span, "get_listing_images", err)
+		return nil, err
+	}
+
+	return &listings[0], nil
+}
+
+func (r *SQLListingRepository) SoftDelete(ctx context.Context, id int64, at time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.SoftDelete", "listings", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE listings SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, at.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "soft_delete_listing", err)
+		return fmt.Errorf("soft delete listing: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "soft_delete_listing", err)
+		return fmt.Errorf("soft delete listing: %w", err)
+	}
+	if n == 0 {
+		return ErrListingNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLListingRepository) SoftDeleteBySeller(ctx context.Context, sellerID int64, at time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.SoftDeleteBySeller", "listings", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE listings SET deleted_at = $2 WHERE seller_id = $1 AND deleted_at IS NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, sellerID, at.UTC()); err != nil {
+		queryFailed(ctx, span, "soft_delete_seller_listings", err)
+		return fmt.Errorf("soft delete seller listings: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLListingRepository) Restore(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.Restore", "listings", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE listings SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		queryFailed(ctx, span, "restore_listing", err)
+		return fmt.Errorf("restore listing: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "restore_listing", err)
+		return fmt.Errorf("restore listing: %w", err)
+	}
+	if n == 0 {
+		return ErrListingNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLListingRepository) RestoreBySeller(ctx context.Context, sellerID int64, deletedSince time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.RestoreBySeller", "listings", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE listings SET deleted_at = NULL WHERE seller_id = $1 AND deleted_at >= $2`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, sellerID, deletedSince.UTC()); err != nil {
+		queryFailed(ctx, span, "restore_seller_listings", err)
+		return fmt.Errorf("restore seller listings: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLListingRepository) ListDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.ListDeleted", "listings", "SELECT")
+	defer span.End()
+
 	const query = `
-		SELECT id, seller_id, title, description, price, category, created_at
+		SELECT id
 		FROM listings
-		WHERE id = $1
+		WHERE deleted_at < $1
+		ORDER BY deleted_at, id
+		LIMIT $2
 	`
 
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, deletedBefore.UTC(), limit)
+	if err != nil {
+		queryFailed(ctx, span, "list_deleted_listings", err)
+		return nil, fmt.Errorf("list deleted listings: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			queryFailed(ctx, span, "scan_deleted_listing", err)
+			return nil, fmt.Errorf("scan deleted listing: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_deleted_listings", err)
+		return nil, fmt.Errorf("iterate deleted listings: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *SQLListingRepository) Purge(ctx context.Context, ids []int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "ListingRepository.Purge", "listings", "DELETE")
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	where, args := inList(r.dialect, "id", 1, ids)
+	query := `DELETE FROM listings WHERE deleted_at IS NOT NULL AND ` + where
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		queryFailed(ctx, span, "purge_listings", err)
+		return fmt.Errorf("purge listings: %w", err)
+	}
+
+	return nil
+}
+
+// loadImages fills in the images of listings with a single query, primary
+// image first.
+func scanListing(row rowScanner) (*models.Listing, error) {
 	listing := &models.Listing{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&listing.ID,
 		&listing.UserID,
 		&listing.Title,
@@ -122,13 +570,55 @@ func (r *PostgresListingRepository) GetByID(ctx context.Context, id int64) (*mod
 		&listing.Price,
 		&listing.Category,
 		&listing.CreatedAt,
+		&listing.Version,
+		&listing.DeletedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrListingNotFound
+		return nil, err
+	}
+	return listing, nil
+}
+
+func (r *SQLListingRepository) loadImages(ctx context.Context, listings []models.Listing) error {
+	index := make(map[int64]int, len(listings))
+	ids := make([]int64, len(listings))
+	for i := range listings {
+		listings[i].Images = make([]models.ListingImage, 0)
+		index[listings[i].ID] = i
+		ids[i] = listings[i].ID
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	where, args := inList(r.dialect, "listing_id", 1, ids)
+	query := `
+		SELECT listing_id, image_url, is_primary
+		FROM listing_images
+		WHERE ` + where + `
+		ORDER BY is_primary DESC, id
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("get listing images: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			listingID int64
+			image     models.ListingImage
+		)
+		if err := rows.Scan(&listingID, &image.URL, &image.IsPrimary); err != nil {
+			return fmt.Errorf("scan listing image: %w", err)
 		}
-		return nil, fmt.Errorf("get listing by id: %w", err)
+		i := index[listingID]
+		listings[i].Images = append(listings[i].Images, image)
 	}
 
-	return listing, nil
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate listing images: %w", err)
+	}
+	return nil
 }
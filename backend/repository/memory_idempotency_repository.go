@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+type idempotencyKey struct {
+	userID int64
+	key    string
+}
+
+// MemoryIdempotencyRepository is a thread-safe IdempotencyRepository kept in
+// memory.
+type MemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]*models.IdempotencyRecord
+}
+
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{records: make(map[idempotencyKey]*models.IdempotencyRecord)}
+}
+
+func (r *MemoryIdempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyRecord, expiredBefore, abandonedBefore time.Time) (*models.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := idempotencyKey{record.UserID, record.Key}
+	if existing, ok := r.records[k]; ok {
+		stale := existing.CreatedAt.Before(expiredBefore) ||
+			(existing.Response == nil && existing.CreatedAt.Before(abandonedBefore))
+		if !stale {
+			return cloneIdempotencyRecord(existing), nil
+		}
+	}
+
+	claimed := *record
+	claimed.Response = nil
+	r.records[k] = &claimed
+	return nil, nil
+}
+
+func (r *MemoryIdempotencyRepository) Complete(ctx context.Context, userID int64, key string, response *models.StoredResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[idempotencyKey{userID, key}]; ok {
+		stored := *response
+		stored.Header = maps.Clone(response.Header)
+		stored.Body = slices.Clone(response.Body)
+		record.Response = &stored
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) Release(ctx context.Context, userID int64, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := idempotencyKey{userID, key}
+	if record, ok := r.records[k]; ok && record.Response == nil {
+		delete(r.records, k)
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for k, record := range r.records {
+		if record.CreatedAt.Before(before) {
+			delete(r.records, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func cloneIdempotencyRecord(record *models.IdempotencyRecord) *models.IdempotencyRecord {
+	clone := *record
+	if record.Response != nil {
+		response := *record.Response
+		response.Header = maps.Clone(record.Response.Header)
+		response.Body = slices.Clone(record.Response.Body)
+		clone.Response = &response
+	}
+	return &clone
+}
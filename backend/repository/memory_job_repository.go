@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// MemoryJobRepository is a thread-safe JobRepository kept in memory.
+type MemoryJobRepository struct {
+	mu     sync.Mutex
+	jobs   []*models.Job
+	nextID int64
+}
+
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{nextID: 1}
+}
+
+func (r *MemoryJobRepository) Enqueue(ctx context.Context, job *models.Job) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job.DedupeKey != nil {
+		for _, existing := range r.jobs {
+			if existing.DedupeKey != nil && *existing.DedupeKey == *job.DedupeKey {
+				return nil, ErrDuplicateJob
+			}
+		}
+	}
+
+	created := &models.Job{
+		ID:          r.nextID,
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		Status:      models.JobPending,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		DedupeKey:   job.DedupeKey,
+		CreatedAt:   time.Now(),
+	}
+	r.nextID++
+	r.jobs = append(r.jobs, created)
+
+	clone := *created
+	return &clone, nil
+}
+
+func (r *MemoryJobRepository) Claim(ctx context.Context, kinds []string, now, lockedUntil time.Time) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed *models.Job
+	for _, job := range r.jobs {
+		if !slices.Contains(kinds, job.Kind) {
+			continue
+		}
+		due := job.Status == models.JobPending && !job.RunAt.After(now)
+		abandoned := job.Status == models.JobRunning && job.LockedUntil.Before(now)
+		if !due && !abandoned {
+			continue
+		}
+		if claimed == nil || job.RunAt.Before(claimed.RunAt) {
+			claimed = job
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+
+	claimed.Status = models.JobRunning
+	claimed.Attempts++
+	claimed.LockedUntil = &lockedUntil
+
+	clone := *claimed
+	return &clone, nil
+}
+
+func (r *MemoryJobRepository) Complete(ctx context.Context, id int64, attempt int, at time.Time) error {
+	return r.update(id, attempt, func(job *models.Job) {
+		job.Status = models.JobSucceeded
+		job.LockedUntil = nil
+		job.FinishedAt = &at
+	})
+}
+
+func (r *MemoryJobRepository) Retry(ctx context.Context, id int64, attempt int, runAt time.Time, lastErr string) error {
+	return r.update(id, attempt, func(job *models.Job) {
+		job.Status = models.JobPending
+		job.LockedUntil = nil
+		job.RunAt = runAt
+		job.LastError = &lastErr
+	})
+}
+
+func (r *MemoryJobRepository) Release(ctx context.Context, id int64, attempt int, runAt time.Time) error {
+	return r.update(id, attempt, func(job *models.Job) {
+		job.Status = models.JobPending
+		job.LockedUntil = nil
+		job.RunAt = runAt
+		job.Attempts--
+	})
+}
+
+func (r *MemoryJobRepository) Bury(ctx context.Context, id int64, attempt int, at time.Time, lastErr string) error {
+	return r.update(id, attempt, func(job *models.Job) {
+		job.Status = models.JobDead
+		job.LockedUntil = nil
+		job.FinishedAt = &at
+		job.LastError = &lastErr
+	})
+}
+
+func (r *MemoryJobRepository) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.jobs[:0]
+	var deleted int64
+	for _, job := range r.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, job)
+	}
+	r.jobs = kept
+	return deleted, nil
+}
+
+// update applies fn to the job with the given ID if it is still running
+// attempt.
+func (r *MemoryJobRepository) update(id int64, attempt int, fn func(job *models.Job)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, job := range r.jobs {
+		if job.ID == id && job.Status == models.JobRunning && job.Attempts == attempt {
+			fn(job)
+			return nil
+		}
+	}
+	return ErrJobLeaseLost
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// MemoryListingRepository is a thread-safe ListingRepository kept in memory.
+// It reads users to check that a new listing's seller exists.
+type MemoryListingRepository struct {
+	mu       sync.Mutex
+	users    *MemoryUserRepository
+	nextID   int64
+	listings []models.Listing
+}
+
+func NewMemoryListingRepository(users *MemoryUserRepository) *MemoryListingRepository {
+	return &MemoryListingRepository{users: users}
+}
+
+func (r *MemoryListingRepository) Create(ctx context.Context, listing *models.Listing) (*models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Mirrors the listings_seller_id_fkey and listings_price_check
+	// constraints.
+	if _, ok := r.users.get(listing.UserID); !ok {
+		return nil, ErrSellerNotFound
+	}
+	if listing.Price < 0 {
+		return nil, ErrInvalidPrice
+	}
+
+	r.nextID++
+	created := *listing
+	created.ID = r.nextID
+	created.CreatedAt = time.Now()
+	created.Version = 1
+	created.Images = append(make([]models.ListingImage, 0, len(listing.Images)), listing.Images...)
+	r.listings = append(r.listings, created)
+
+	return cloneListing(created), nil
+}
+
+func (r *MemoryListingRepository) GetAll(ctx context.Context, search string) ([]models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search = strings.ToLower(strings.TrimSpace(search))
+	listings := make([]models.Listing, 0)
+	// Listings are stored in creation order; walk back for newest first.
+	for i := len(r.listings) - 1; i >= 0; i-- {
+		if r.listings[i].DeletedAt != nil {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(r.listings[i].Title), search) {
+			continue
+		}
+		listings = append(listings, *cloneListing(r.listings[i]))
+	}
+	return listings, nil
+}
+
+func (r *MemoryListingRepository) GetByID(ctx context.Context, id int64) (*models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, listing := range r.listings {
+		if listing.ID == id && listing.DeletedAt == nil {
+			return cloneListing(listing), nil
+		}
+	}
+	return nil, ErrListingNotFound
+}
+
+func (r *MemoryListingRepository) Update(ctx context.Context, listing *models.Listing, version int64) (*models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if listing.Price < 0 {
+		return nil, ErrInvalidPrice
+	}
+
+	for i := range r.listings {
+		current := &r.listings[i]
+		if current.ID != listing.ID || current.DeletedAt != nil {
+			continue
+		}
+		if current.Version != version {
+			return nil, ErrListingVersionMismatch
+		}
+		current.Title = listing.Title
+		current.Description = listing.Description
+		current.Category = listing.Category
+		current.Price = listing.Price
+		current.Images = append(make([]models.ListingImage, 0, len(listing.Images)), listing.Images...)
+		current.Version++
+		return cloneListing(*current), nil
+	}
+	return nil, ErrListingNotFound
+}
+
+func (r *MemoryListingRepository) ListBySeller(ctx context.Context, sellerID int64) ([]models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	listings := make([]models.Listing, 0)
+	for i := len(r.listings) - 1; i >= 0; i-- {
+		if r.listings[i].UserID == sellerID && r.listings[i].DeletedAt == nil {
+			listings = append(listings, *cloneListing(r.listings[i]))
+		}
+	}
+	return listings, nil
+}
+
+func (r *MemoryListingRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	listings := make([]models.Listing, 0, len(ids))
+	for _, listing := range r.listings {
+		if slices.Contains(ids, listing.ID) && listing.DeletedAt == nil {
+			listings = append(listings, *cloneListing(listing))
+		}
+	}
+	return listings, nil
+}
+
+func (r *MemoryListingRepository) RemoveImage(ctx context.Context, url string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var affected int64
+	for i := range r.listings {
+		images := r.listings[i].Images
+		kept := slices.DeleteFunc(slices.Clone(images), func(image models.ListingImage) bool { return image.URL == url })
+		if len(kept) == len(images) {
+			continue
+		}
+		if len(kept) > 0 && !slices.ContainsFunc(kept, func(image models.ListingImage) bool { return image.IsPrimary }) {
+			kept[0].IsPrimary = true
+		}
+		r.listings[i].Images = kept
+		r.listings[i].Version++
+		affected++
+	}
+	return affected, nil
+}
+
+func (r *MemoryListingRepository) GetDeleted(ctx context.Context, id int64) (*models.Listing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, listing := range r.listings {
+		if listing.ID == id && listing.DeletedAt != nil {
+			return cloneListing(listing), nil
+		}
+	}
+	return nil, ErrListingNotFound
+}
+
+func (r *MemoryListingRepository) SoftDelete(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.listings {
+		if r.listings[i].ID == id && r.listings[i].DeletedAt == nil {
+			r.listings[i].DeletedAt = &at
+			return nil
+		}
+	}
+	return ErrListingNotFound
+}
+
+func (r *MemoryListingRepository) SoftDeleteBySeller(ctx context.Context, sellerID int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.listings {
+		if r.listings[i].UserID == sellerID && r.listings[i].DeletedAt == nil {
+			r.listings[i].DeletedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *MemoryListingRepository) Restore(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.listings {
+		if r.listings[i].ID == id && r.listings[i].DeletedAt != nil {
+			r.listings[i].DeletedAt = nil
+			return nil
+		}
+	}
+	return ErrListingNotFound
+}
+
+func (r *MemoryListingRepository) RestoreBySeller(ctx context.Context, sellerID int64, deletedSince time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.listings {
+		deletedAt := r.listings[i].DeletedAt
+		if r.listings[i].UserID == sellerID && deletedAt != nil && !deletedAt.Before(deletedSince) {
+			r.listings[i].DeletedAt = nil
+		}
+	}
+	return nil
+}
+
+func (r *MemoryListingRepository) ListDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted []models.Listing
+	for _, listing := range r.listings {
+		if listing.DeletedAt != nil && listing.DeletedAt.Before(deletedBefore) {
+			deleted = append(deleted, listing)
+		}
+	}
+	slices.SortFunc(deleted, func(a, b models.Listing) int {
+		return cmp.Or(a.DeletedAt.Compare(*b.DeletedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	ids := make([]int64, 0, min(limit, len(deleted)))
+	for _, listing := range deleted[:min(limit, len(deleted))] {
+		ids = append(ids, listing.ID)
+	}
+	return ids, nil
+}
+
+func (r *MemoryListingRepository) Purge(ctx context.Context, ids []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listings = slices.DeleteFunc(r.listings, func(listing models.Listing) bool {
+		return listing.DeletedAt != nil && slices.Contains(ids, listing.ID)
+	})
+	return nil
+}
+
+func cloneListing(listing models.Listing) *models.Listing {
+	clone := listing
+	clone.Images = append(make([]models.ListingImage, 0, len(listing.Images)), listing.Images...)
+	return &clone
+}
+
+func (r *MemoryListingRepository) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nextID := r.nextID
+	listings := make([]models.Listing, len(r.listings))
+	for i, listing := range r.listings {
+		listings[i] = *cloneListing(listing)
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.nextID, r.listings = nextID, listings
+	}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// MemoryModerationRepository is a thread-safe ModerationRepository kept in
+// memory. It reads uploads to fill in the image side of matches.
+type MemoryModerationRepository struct {
+	mu      sync.Mutex
+	uploads *MemoryUploadRepository
+	nextID  int64
+	matches []models.ImageMatch
+	blocked map[string]models.BlockedImage
+}
+
+func NewMemoryModerationRepository(uploads *MemoryUploadRepository) *MemoryModerationRepository {
+	return &MemoryModerationRepository{
+		uploads: uploads,
+		blocked: make(map[string]models.BlockedImage),
+	}
+}
+
+func (r *MemoryModerationRepository) CreateImageMatches(ctx context.Context, matches []models.ImageMatch) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := 0
+	for _, match := range matches {
+		if r.hasPair(match.UploadID, match.MatchedUploadID) {
+			continue
+		}
+		r.nextID++
+		match.ID = r.nextID
+		match.Status = models.MatchOpen
+		match.CreatedAt = time.Now()
+		r.matches = append(r.matches, match)
+		created++
+	}
+	return created, nil
+}
+
+func (r *MemoryModerationRepository) hasPair(uploadID, matchedUploadID int64) bool {
+	for _, match := range r.matches {
+		if match.UploadID == uploadID && match.MatchedUploadID == matchedUploadID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryModerationRepository) ListOpenImageMatches(ctx context.Context, limit int) ([]models.ImageMatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := make([]models.ImageMatch, 0)
+	for _, match := range r.matches {
+		if len(matches) == limit {
+			break
+		}
+		if match.Status != models.MatchOpen {
+			continue
+		}
+		upload, ok := r.uploads.get(match.UploadID)
+		matched, matchedOK := r.uploads.get(match.MatchedUploadID)
+		if !ok || !matchedOK || matched.ListingID == nil {
+			continue
+		}
+		match.ImageURL = "/uploads/" + upload.Key
+		match.MatchedListingID = *matched.ListingID
+		match.MatchedImageURL = "/uploads/" + matched.Key
+		match.MatchedSellerID = matched.OwnerID
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+func (r *MemoryModerationRepository) ResolveImageMatch(ctx context.Context, id int64, status string, moderatorID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.matches {
+		if r.matches[i].ID == id && r.matches[i].Status == models.MatchOpen {
+			r.matches[i].Status = status
+			return nil
+		}
+	}
+	return ErrImageMatchNotFound
+}
+
+func (r *MemoryModerationRepository) ResolveImageMatchesForUpload(ctx context.Context, uploadID int64, status string, moderatorID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.matches {
+		match := &r.matches[i]
+		if (match.UploadID == uploadID || match.MatchedUploadID == uploadID) && match.Status == models.MatchOpen {
+			match.Status = status
+		}
+	}
+	return nil
+}
+
+func (r *MemoryModerationRepository) BlockImage(ctx context.Context, image *models.BlockedImage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.blocked[image.SHA256]; !ok {
+		r.blocked[image.SHA256] = *image
+	}
+	return nil
+}
+
+func (r *MemoryModerationRepository) IsImageBlocked(ctx context.Context, sha256 string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.blocked[sha256]
+	return ok, nil
+}
+
+func (r *MemoryModerationRepository) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nextID := r.nextID
+	matches := slices.Clone(r.matches)
+	blocked := maps.Clone(r.blocked)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.nextID, r.matches, r.blocked = nextID, matches, blocked
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// MemoryReportRepository is a thread-safe ReportRepository kept in memory.
+type MemoryReportRepository struct {
+	mu      sync.Mutex
+	nextID  int64
+	reports []models.Report
+}
+
+func NewMemoryReportRepository() *MemoryReportRepository {
+	return &MemoryReportRepository{}
+}
+
+func (r *MemoryReportRepository) Create(ctx context.Context, report *models.Report) (*models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	created := *report
+	created.ID = r.nextID
+	created.CreatedAt = time.Now()
+	r.reports = append(r.reports, created)
+
+	return &created, nil
+}
+
+func (r *MemoryReportRepository) ListRecent(ctx context.Context, limit int) ([]models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reports := make([]models.Report, 0, min(limit, len(r.reports)))
+	for i := len(r.reports) - 1; i >= 0 && len(reports) < limit; i-- {
+		reports = append(reports, r.reports[i])
+	}
+	return reports, nil
+}
+
+func (r *MemoryReportRepository) ListByReporter(ctx context.Context, reporterID int64) ([]models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reports := make([]models.Report, 0)
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		if r.reports[i].ReporterUserID == reporterID {
+			reports = append(reports, r.reports[i])
+		}
+	}
+	return reports, nil
+}
+
+func (r *MemoryReportRepository) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nextID := r.nextID
+	reports := slices.Clone(r.reports)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.nextID, r.reports = nextID, reports
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// snapshotter is implemented by the in-memory repositories. snapshot copies
+// the repository's state and returns a function restoring it.
+type snapshotter interface {
+	snapshot() (restore func())
+}
+
+type memoryTxKey struct{}
+
+// MemoryTxManager gives the in-memory repositories transactions: units of
+// work run one at a time, and a failed one puts every repository back the
+// way it found them. Writes made outside WithTx while a unit of work is
+// running are lost if it rolls back, which tests do not do.
+type MemoryTxManager struct {
+	mu    sync.Mutex
+	repos []snapshotter
+}
+
+func NewMemoryTxManager(repos ...snapshotter) *MemoryTxManager {
+	return &MemoryTxManager{repos: repos}
+}
+
+func (m *MemoryTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restores := make([]func(), len(m.repos))
+	for i, repo := range m.repos {
+		restores[i] = repo.snapshot()
+	}
+	rollback := func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, true)); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
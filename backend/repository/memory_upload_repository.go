@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"math/bits"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// MemoryUploadRepository is a thread-safe UploadRepository kept in memory.
+// It reads users to find the uploads of anonymized accounts.
+type MemoryUploadRepository struct {
+	mu      sync.Mutex
+	users   *MemoryUserRepository
+	nextID  int64
+	uploads []*models.Upload
+}
+
+func NewMemoryUploadRepository(users *MemoryUserRepository) *MemoryUploadRepository {
+	return &MemoryUploadRepository{users: users}
+}
+
+func (r *MemoryUploadRepository) Create(ctx context.Context, upload *models.Upload, quotaBytes int64) (*models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.usage(upload.OwnerID)+upload.SizeBytes > quotaBytes {
+		return nil, ErrUploadQuotaExceeded
+	}
+
+	r.nextID++
+	created := *upload
+	created.ID = r.nextID
+	created.ListingID = nil
+	created.AttachedAt = nil
+	created.HeldAt = nil
+	created.CreatedAt = time.Now()
+	r.uploads = append(r.uploads, &created)
+
+	clone := created
+	return &clone, nil
+}
+
+func (r *MemoryUploadRepository) GetByKey(ctx context.Context, key string) (*models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, upload := range r.uploads {
+		if upload.Key == key {
+			clone := *upload
+			return &clone, nil
+		}
+	}
+	return nil, ErrUploadNotFound
+}
+
+func (r *MemoryUploadRepository) GetByKeys(ctx context.Context, keys []string) ([]models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uploads := make([]models.Upload, 0, len(keys))
+	for _, upload := range r.uploads {
+		if slices.Contains(keys, upload.Key) {
+			uploads = append(uploads, *upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (r *MemoryUploadRepository) ListByOwner(ctx context.Context, ownerID int64) ([]models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uploads := make([]models.Upload, 0)
+	for _, upload := range r.uploads {
+		if upload.OwnerID == ownerID {
+			uploads = append(uploads, *upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (r *MemoryUploadRepository) ListOwnedByAnonymized(ctx context.Context, anonymizedBefore time.Time, limit int) ([]models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uploads := make([]models.Upload, 0)
+	for _, upload := range r.uploads {
+		if len(uploads) == limit {
+			break
+		}
+		owner, ok := r.users.get(upload.OwnerID)
+		if ok && owner.AnonymizedAt != nil && owner.AnonymizedAt.Before(anonymizedBefore) {
+			uploads = append(uploads, *upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (r *MemoryUploadRepository) FindSimilar(ctx context.Context, phash int64, excludeOwnerID int64, maxDistance, limit int) ([]models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	distance := func(upload *models.Upload) int {
+		return bits.OnesCount64(uint64(*upload.PHash ^ phash))
+	}
+
+	var similar []*models.Upload
+	for _, upload := range r.uploads {
+		if upload.ListingID == nil || upload.PHash == nil || upload.OwnerID == excludeOwnerID {
+			continue
+		}
+		if distance(upload) <= maxDistance {
+			similar = append(similar, upload)
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return distance(similar[i]) < distance(similar[j]) })
+
+	uploads := make([]models.Upload, 0, min(limit, len(similar)))
+	for _, upload := range similar[:min(limit, len(similar))] {
+		uploads = append(uploads, *upload)
+	}
+	return uploads, nil
+}
+
+func (r *MemoryUploadRepository) UsageByOwner(ctx context.Context, ownerID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.usage(ownerID), nil
+}
+
+func (r *MemoryUploadRepository) usage(ownerID int64) int64 {
+	var used int64
+	for _, upload := range r.uploads {
+		if upload.OwnerID == ownerID {
+			used += upload.SizeBytes
+		}
+	}
+	return used
+}
+
+func (r *MemoryUploadRepository) Attach(ctx context.Context, keys []string, listingID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []*models.Upload
+	for _, upload := range r.uploads {
+		if slices.Contains(keys, upload.Key) && (upload.ListingID == nil || *upload.ListingID == listingID) && upload.HeldAt == nil {
+			matched = append(matched, upload)
+		}
+	}
+	if len(matched) != len(slices.Compact(slices.Sorted(slices.Values(keys)))) {
+		return ErrUploadInUse
+	}
+
+	now := time.Now()
+	for _, upload := range matched {
+		id := listingID
+		upload.ListingID = &id
+		if upload.AttachedAt == nil {
+			upload.AttachedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUploadRepository) Detach(ctx context.Context, listingIDs []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, upload := range r.uploads {
+		if upload.ListingID != nil && slices.Contains(listingIDs, *upload.ListingID) {
+			upload.ListingID = nil
+			upload.AttachedAt = nil
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUploadRepository) Hold(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, upload := range r.uploads {
+		if upload.ID == id {
+			upload.ListingID = nil
+			upload.AttachedAt = nil
+			if upload.HeldAt == nil {
+				now := time.Now()
+				upload.HeldAt = &now
+			}
+			return nil
+		}
+	}
+	return ErrUploadNotFound
+}
+
+func (r *MemoryUploadRepository) ListUnattached(ctx context.Context, createdBefore time.Time, limit int) ([]models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uploads := make([]models.Upload, 0)
+	for _, upload := range r.uploads {
+		if len(uploads) == limit {
+			break
+		}
+		if upload.AttachedAt == nil && upload.HeldAt == nil && upload.Visibility == models.UploadPublic && upload.CreatedAt.Before(createdBefore) {
+			uploads = append(uploads, *upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (r *MemoryUploadRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.uploads = slices.DeleteFunc(r.uploads, func(upload *models.Upload) bool { return upload.ID == id })
+	return nil
+}
+
+func (r *MemoryUploadRepository) DeleteUnattached(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, upload := range r.uploads {
+		if upload.ID == id && upload.AttachedAt == nil && upload.HeldAt == nil {
+			r.uploads = slices.Delete(r.uploads, i, i+1)
+			return nil
+		}
+	}
+	return ErrUploadNotFound
+}
+
+// get returns a copy of the upload with id, for repositories that join
+// against uploads.
+func (r *MemoryUploadRepository) get(id int64) (models.Upload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, upload := range r.uploads {
+		if upload.ID == id {
+			return *upload, true
+		}
+	}
+	return models.Upload{}, false
+}
+
+func (r *MemoryUploadRepository) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nextID := r.nextID
+	uploads := make([]*models.Upload, len(r.uploads))
+	for i, upload := range r.uploads {
+		copied := *upload
+		uploads[i] = &copied
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.nextID, r.uploads = nextID, uploads
+	}
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+// MemoryUserRepository is a thread-safe UserRepository kept in memory, for
+// tests and local runs without a database.
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	users  map[int64]*models.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[int64]*models.User)}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return nil, ErrEmailAlreadyExists
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	created := *user
+	created.ID = r.nextID
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	created.FailedLoginAttempts = 0
+	created.LockedUntil = nil
+	r.users[created.ID] = &created
+
+	clone := created
+	return &clone, nil
+}
+
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email && user.DeletedAt == nil {
+			clone := *user
+			return &clone, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (r *MemoryUserRepository) GetDeleted(ctx context.Context, id int64) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil || user.AnonymizedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (r *MemoryUserRepository) IncrementFailedLogins(ctx context.Context, id int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	user.FailedLoginAttempts++
+	return user.FailedLoginAttempts, nil
+}
+
+func (r *MemoryUserRepository) SetLockedUntil(ctx context.Context, id int64, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok {
+		user.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok {
+		user.FailedLoginAttempts = 0
+		user.LockedUntil = nil
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) SoftDelete(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	user.DeletedAt = &at
+	return nil
+}
+
+func (r *MemoryUserRepository) Restore(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil || user.AnonymizedAt != nil {
+		return ErrUserNotFound
+	}
+	user.DeletedAt = nil
+	return nil
+}
+
+func (r *MemoryUserRepository) Anonymize(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	user.FullName = "Deleted user"
+	user.Email = fmt.Sprintf("deleted-%d@deleted.invalid", id)
+	user.PasswordHash = ""
+	user.University = ""
+	user.IsModerator = false
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	user.UpdatedAt = at
+	user.DeletedAt = &at
+	user.AnonymizedAt = &at
+	return nil
+}
+
+func (r *MemoryUserRepository) ListDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted []*models.User
+	for _, user := range r.users {
+		if user.DeletedAt != nil && user.AnonymizedAt == nil && user.DeletedAt.Before(deletedBefore) {
+			deleted = append(deleted, user)
+		}
+	}
+	slices.SortFunc(deleted, func(a, b *models.User) int {
+		return cmp.Or(a.DeletedAt.Compare(*b.DeletedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	ids := make([]int64, 0, min(limit, len(deleted)))
+	for _, user := range deleted[:min(limit, len(deleted))] {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// Purge deletes only the user: unlike the database, the in-memory
+// repositories do not cascade.
+func (r *MemoryUserRepository) Purge(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok && user.DeletedAt != nil {
+		delete(r.users, id)
+	}
+	return nil
+}
+
+// SetModerator grants or revokes moderator rights. Accounts are made
+// moderators directly in the database; this is the in-memory equivalent.
+func (r *MemoryUserRepository) SetModerator(id int64, isModerator bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok {
+		user.IsModerator = isModerator
+	}
+}
+
+// get returns a copy of the user with id, for repositories that join
+// against users.
+func (r *MemoryUserRepository) get(id int64) (models.User, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, false
+	}
+	return *user, true
+}
+
+func (r *MemoryUserRepository) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nextID := r.nextID
+	users := make(map[int64]*models.User, len(r.users))
+	for id, user := range r.users {
+		copied := *user
+		users[id] = &copied
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.nextID, r.users = nextID, users
+	}
+}
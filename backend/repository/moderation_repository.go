@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"uniswap-campus-marketplace/models"
+)
+
+var ErrImageMatchNotFound = errors.New("image match not found")
+
+type ModerationRepository interface {
+	// CreateImageMatches records matches, skipping pairs already recorded,
+	// and returns how many were new.
+	CreateImageMatches(ctx context.Context, matches []models.ImageMatch) (int, error)
+	// ListOpenImageMatches returns open matches, oldest first.
+	ListOpenImageMatches(ctx context.Context, limit int) ([]models.ImageMatch, error)
+	// ResolveImageMatch closes an open match with status.
+	ResolveImageMatch(ctx context.Context, id int64, status string, moderatorID int64) error
+	// ResolveImageMatchesForUpload closes every open match either side of
+	// which is the upload.
+	ResolveImageMatchesForUpload(ctx context.Context, uploadID int64, status string, moderatorID int64) error
+	// BlockImage records a removed image; blocking one twice keeps the first
+	// record.
+	BlockImage(ctx context.Context, image *models.BlockedImage) error
+	IsImageBlocked(ctx context.Context, sha256 string) (bool, error)
+}
+
+// SQLModerationRepository stores image matches and blocked images in
+// PostgreSQL or SQLite.
+type SQLModerationRepository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewSQLModerationRepository(db *sql.DB, dialect Dialect) *SQLModerationRepository {
+	return &SQLModerationRepository{db: db, dialect: dialect}
+}
+
+func (r *SQLModerationRepository) CreateImageMatches(ctx context.Context, matches []models.ImageMatch) (int, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ModerationRepository.CreateImageMatches", "image_matches", "INSERT")
+	defer span.End()
+
+	const query = `
+		INSERT INTO image_matches (listing_id, upload_id, matched_upload_id, distance)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (upload_id, matched_upload_id) DO NOTHING
+	`
+
+	created := 0
+	for _, match := range matches {
+		result, err := conn(ctx, r.db).ExecContext(ctx, query, match.ListingID, match.UploadID, match.MatchedUploadID, match.Distance)
+		if err != nil {
+			if cerr := constraintViolation(err); cerr != nil {
+				return created, nameForeignKey(ctx, conn(ctx, r.db), cerr, "image_matches",
+					foreignKey{"image_matches_listing_id_fkey", "listings", match.ListingID},
+					foreignKey{"image_matches_upload_id_fkey", "uploads", match.UploadID},
+					foreignKey{"image_matches_matched_upload_id_fkey", "uploads", match.MatchedUploadID})
+			}
+			queryFailed(ctx, span, "create_image_match", err)
+			return created, fmt.Errorf("create image match: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			created += int(n)
+		}
+	}
+
+	return created, nil
+}
+
+func (r *SQLModerationRepository) ListOpenImageMatches(ctx context.Context, limit int) ([]models.ImageMatch, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ModerationRepository.ListOpenImageMatches", "image_matches", "SELECT")
+	defer span.End()
+
+	// Image URLs are rebuilt from the storage keys the same way the upload
+	// endpoint hands them out.
+	const query = `
+		SELECT m.id, m.listing_id, m.upload_id, '/uploads/' || u.storage_key,
+			mu.listing_id, m.matched_upload_id, '/uploads/' || mu.storage_key, mu.owner_id,
+			m.distance, m.status, m.created_at
+		FROM image_matches m
+		JOIN uploads u ON u.id = m.upload_id
+		JOIN uploads mu ON mu.id = m.matched_upload_id
+		WHERE m.status = 'open' AND mu.listing_id IS NOT NULL
+		ORDER BY m.created_at, m.id
+		LIMIT $1
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		queryFailed(ctx, span, "list_image_matches", err)
+		return nil, fmt.Errorf("list image matches: %w", err)
+	}
+	defer rows.Close()
+
+	matches := make([]models.ImageMatch, 0)
+	for rows.Next() {
+		var match models.ImageMatch
+		if err := rows.Scan(
+			&match.ID,
+			&match.ListingID,
+			&match.UploadID,
+			&match.ImageURL,
+			&match.MatchedListingID,
+			&match.MatchedUploadID,
+			&match.MatchedImageURL,
+			&match.MatchedSellerID,
+			&match.Distance,
+			&match.Status,
+			&match.CreatedAt,
+		); err != nil {
+			queryFailed(ctx, span, "scan_image_match", err)
+			return nil, fmt.Errorf("scan image match: %w", err)
+		}
+		matches = append(matches, match)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_image_matches", err)
+		return nil, fmt.Errorf("iterate image matches: %w", err)
+	}
+
+	return matches, nil
+}
+
+func (r *SQLModerationRepository) ResolveImageMatch(ctx context.Context, id int64, status string, moderatorID int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "ModerationRepository.ResolveImageMatch", "image_matches", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE image_matches
+		SET status = $2, resolved_by = $3, resolved_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'open'
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, status, moderatorID)
+	if err != nil {
+		queryFailed(ctx, span, "resolve_image_match", err)
+		return fmt.Errorf("resolve image match: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "resolve_image_match", err)
+		return fmt.Errorf("resolve image match: %w", err)
+	}
+	if n == 0 {
+		return ErrImageMatchNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLModerationRepository) ResolveImageMatchesForUpload(ctx context.Context, uploadID int64, status string, moderatorID int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "ModerationRepository.ResolveImageMatchesForUpload", "image_matches", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE image_matches
+		SET status = $2, resolved_by = $3, resolved_at = CURRENT_TIMESTAMP
+		WHERE (upload_id = $1 OR matched_upload_id = $1) AND status = 'open'
+	`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, uploadID, status, moderatorID); err != nil {
+		queryFailed(ctx, span, "resolve_image_matches", err)
+		return fmt.Errorf("resolve image matches: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLModerationRepository) BlockImage(ctx context.Context, image *models.BlockedImage) error {
+	ctx, span := startSpan(ctx, r.dialect, "ModerationRepository.BlockImage", "blocked_images", "INSERT")
+	defer span.End()
+
+	const query = `
+		INSERT INTO blocked_images (sha256, phash, reason, blocked_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (sha256) DO NOTHING
+	`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, image.SHA256, image.PHash, image.Reason, image.BlockedBy); err != nil {
+		if cerr := constraintViolation(err); cerr != nil {
+			return cerr
+		}
+		queryFailed(ctx, span, "block_image", err)
+		return fmt.Errorf("block image: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLModerationRepository) IsImageBlocked(ctx context.Context, sha256 string) (bool, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ModerationRepository.IsImageBlocked", "blocked_images", "SELECT")
+	defer span.End()
+
+	const query = `SELECT EXISTS (SELECT 1 FROM blocked_images WHERE sha256 = $1)`
+
+	var blocked bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, sha256).Scan(&blocked); err != nil {
+		queryFailed(ctx, span, "is_image_blocked", err)
+		return false, fmt.Errorf("is image blocked: %w", err)
+	}
+
+	return blocked, nil
+}
@@ -10,17 +10,26 @@ import (
 
 type ReportRepository interface {
 	Create(ctx context.Context, report *models.Report) (*models.Report, error)
+	// ListRecent returns the newest reports, newest first.
+	ListRecent(ctx context.Context, limit int) ([]models.Report, error)
+	// ListByReporter returns the reports a user filed, newest first.
+	ListByReporter(ctx context.Context, reporterID int64) ([]models.Report, error)
 }
 
-type PostgresReportRepository struct {
-	db *sql.DB
+// SQLReportRepository stores reports in PostgreSQL or SQLite.
+type SQLReportRepository struct {
+	db      *sql.DB
+	dialect Dialect
 }
 
-func NewPostgresReportRepository(db *sql.DB) *PostgresReportRepository {
-	return &PostgresReportRepository{db: db}
+func NewSQLReportRepository(db *sql.DB, dialect Dialect) *SQLReportRepository {
+	return &SQLReportRepository{db: db, dialect: dialect}
 }
 
-func (r *PostgresReportRepository) Create(ctx context.Context, report *models.Report) (*models.Report, error) {
+func (r *SQLReportRepository) Create(ctx context.Context, report *models.Report) (*models.Report, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ReportRepository.Create", "reports", "INSERT")
+	defer span.End()
+
 	const query = `
 		INSERT INTO reports (listing_id, reporter_id, reason)
 		VALUES ($1, $2, $3)
@@ -28,7 +37,7 @@ func (r *PostgresReportRepository) Create(ctx context.Context, report *models.Re
 	`
 
 	created := &models.Report{}
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		report.ListingID,
@@ -42,8 +51,98 @@ func (r *PostgresReportRepository) Create(ctx context.Context, report *models.Re
 		&created.CreatedAt,
 	)
 	if err != nil {
+		if cerr := constraintViolation(err); cerr != nil {
+			return nil, nameForeignKey(ctx, conn(ctx, r.db), cerr, "reports",
+				foreignKey{"reports_listing_id_fkey", "listings", report.ListingID},
+				foreignKey{"reports_reporter_id_fkey", "users", report.ReporterUserID})
+		}
+		queryFailed(ctx, span, "create_report", err)
 		return nil, fmt.Errorf("create report: %w", err)
 	}
 
 	return created, nil
 }
+
+func (r *SQLReportRepository) ListRecent(ctx context.Context, limit int) ([]models.Report, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ReportRepository.ListRecent", "reports", "SELECT")
+	defer span.End()
+
+	const query = `
+		SELECT id, listing_id, reporter_id, reason, created_at
+		FROM reports
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		queryFailed(ctx, span, "list_reports", err)
+		return nil, fmt.Errorf("list reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]models.Report, 0)
+	for rows.Next() {
+		var report models.Report
+		if err := rows.Scan(
+			&report.ID,
+			&report.ListingID,
+			&report.ReporterUserID,
+			&report.Reason,
+			&report.CreatedAt,
+		); err != nil {
+			queryFailed(ctx, span, "scan_report", err)
+			return nil, fmt.Errorf("scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_reports", err)
+		return nil, fmt.Errorf("iterate reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+func (r *SQLReportRepository) ListByReporter(ctx context.Context, reporterID int64) ([]models.Report, error) {
+	ctx, span := startSpan(ctx, r.dialect, "ReportRepository.ListByReporter", "reports", "SELECT")
+	defer span.End()
+
+	const query = `
+		SELECT id, listing_id, reporter_id, reason, created_at
+		FROM reports
+		WHERE reporter_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, reporterID)
+	if err != nil {
+		queryFailed(ctx, span, "list_reporter_reports", err)
+		return nil, fmt.Errorf("list reporter reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]models.Report, 0)
+	for rows.Next() {
+		var report models.Report
+		if err := rows.Scan(
+			&report.ID,
+			&report.ListingID,
+			&report.ReporterUserID,
+			&report.Reason,
+			&report.CreatedAt,
+		); err != nil {
+			queryFailed(ctx, span, "scan_report", err)
+			return nil, fmt.Errorf("scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_reports", err)
+		return nil, fmt.Errorf("iterate reports: %w", err)
+	}
+
+	return reports, nil
+}
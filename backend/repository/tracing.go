@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("uniswap-campus-marketplace/repository")
+
+// startSpan starts a client span for one repository call. name is the
+// repository method, e.g. "ListingRepository.GetByID".
+func startSpan(ctx context.Context, d Dialect, name, table, operation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", d.system),
+			attribute.String("db.collection.name", table),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+// queryFailed logs an unexpected database error and marks span as failed.
+func queryFailed(ctx context.Context, span trace.Span, op string, err error) {
+	slog.DebugContext(ctx, "repository: query failed", "op", op, "err", err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, op+" failed")
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// TxManager runs a unit of work atomically. Repository calls made with the
+// context passed to fn take part in the transaction; calls made with any
+// other context do not.
+type TxManager interface {
+	// WithTx runs fn in a transaction, committing when it returns nil and
+	// rolling back when it returns an error or panics. A WithTx nested in
+	// another joins the outer transaction.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DBTX is the subset of *sql.DB and *sql.Tx repositories run statements
+// through.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// conn returns the transaction carried by ctx, or db when there is none.
+func conn(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// atomic runs fn in the caller's transaction, or in one of its own when ctx
+// carries none, for repository methods that issue several statements.
+func atomic(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Serialization failures are retried up to txMaxAttempts times in all, with
+// a jittered backoff starting at txRetryBackoff.
+const (
+	txMaxAttempts  = 5
+	txRetryBackoff = 10 * time.Millisecond
+)
+
+// SQLTxManager runs units of work in database transactions. PostgreSQL
+// transactions are SERIALIZABLE, so that checks made inside fn still hold
+// when it commits, and are retried when PostgreSQL aborts one to keep that
+// guarantee; SQLite runs one writer at a time and is serializable anyway.
+type SQLTxManager struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewSQLTxManager(db *sql.DB, dialect Dialect) *SQLTxManager {
+	return &SQLTxManager{db: db, dialect: dialect}
+}
+
+func (m *SQLTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := startSpan(ctx, m.dialect, "TxManager.WithTx", "", "TRANSACTION")
+	defer span.End()
+
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || !isSerializationFailure(err) || attempt == txMaxAttempts {
+			if err != nil {
+				queryFailed(ctx, span, "transaction", err)
+			}
+			return err
+		}
+
+		slog.DebugContext(ctx, "repository: retrying transaction", "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff/2 + rand.N(backoff)):
+		}
+		backoff *= 2
+	}
+}
+
+// run makes a single attempt at fn. The deferred rollback also runs when fn
+// panics, so the connection goes back to the pool clean before the panic
+// carries on up the stack.
+func (m *SQLTxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	var opts *sql.TxOptions
+	if m.dialect == Postgres {
+		opts = &sql.TxOptions{Isolation: sql.LevelSerializable}
+	}
+	tx, err := m.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
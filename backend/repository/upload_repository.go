@@ -0,0 +1,460 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+var (
+	ErrUploadNotFound      = errors.New("upload not found")
+	ErrUploadQuotaExceeded = errors.New("upload quota exceeded")
+	ErrUploadInUse         = errors.New("upload is used by another listing")
+)
+
+type UploadRepository interface {
+	// Create records upload unless it would take the owner's total past
+	// quotaBytes, in which case it returns ErrUploadQuotaExceeded.
+	Create(ctx context.Context, upload *models.Upload, quotaBytes int64) (*models.Upload, error)
+	GetByKey(ctx context.Context, key string) (*models.Upload, error)
+	GetByKeys(ctx context.Context, keys []string) ([]models.Upload, error)
+	// ListByOwner returns every upload of a user, oldest first.
+	ListByOwner(ctx context.Context, ownerID int64) ([]models.Upload, error)
+	// ListOwnedByAnonymized returns the uploads still owned by users who
+	// deleted their account before the cutoff.
+	ListOwnedByAnonymized(ctx context.Context, anonymizedBefore time.Time, limit int) ([]models.Upload, error)
+	// FindSimilar returns uploads attached to listings of sellers other than
+	// excludeOwnerID whose perceptual hash is within maxDistance bits of
+	// phash, closest first.
+	FindSimilar(ctx context.Context, phash int64, excludeOwnerID int64, maxDistance, limit int) ([]models.Upload, error)
+	UsageByOwner(ctx context.Context, ownerID int64) (int64, error)
+	// Attach marks the uploads as used by a listing. It returns
+	// ErrUploadInUse, attaching none of them, when one is gone, held or
+	// already used by another listing.
+	Attach(ctx context.Context, keys []string, listingID int64) error
+	// Detach marks the uploads of listings as never attached, so that the
+	// sweeper deletes them once the listings are gone.
+	Detach(ctx context.Context, listingIDs []int64) error
+	// Hold takes an upload off its listing and keeps it from ever being
+	// attached or swept again, for images a moderator removed.
+	Hold(ctx context.Context, id int64) error
+	// ListUnattached returns public uploads never attached to a listing,
+	// not held and created before the cutoff, oldest first.
+	ListUnattached(ctx context.Context, createdBefore time.Time, limit int) ([]models.Upload, error)
+	Delete(ctx context.Context, id int64) error
+	// DeleteUnattached deletes an upload unless it was attached to a listing
+	// or held since it was listed, in which case, or when it is already gone,
+	// it returns ErrUploadNotFound.
+	DeleteUnattached(ctx context.Context, id int64) error
+}
+
+// SQLUploadRepository stores uploads in PostgreSQL or SQLite.
+type SQLUploadRepository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewSQLUploadRepository(db *sql.DB, dialect Dialect) *SQLUploadRepository {
+	return &SQLUploadRepository{db: db, dialect: dialect}
+}
+
+const uploadColumns = `id, owner_id, storage_key, size_bytes, sha256, phash, content_type, visibility, listing_id, attached_at, held_at, created_at`
+
+func (r *SQLUploadRepository) Create(ctx context.Context, upload *models.Upload, quotaBytes int64) (*models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.Create", "uploads", "INSERT")
+	defer span.End()
+
+	// Under READ COMMITTED two uploads of one owner could both check the
+	// quota against the same total, so on PostgreSQL they take turns by
+	// locking the owner's row first. SQLite runs one writer at a time.
+	const lock = `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`
+	const query = `
+		INSERT INTO uploads (owner_id, storage_key, size_bytes, sha256, phash, content_type, visibility)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE (SELECT COALESCE(SUM(size_bytes), 0) FROM uploads WHERE owner_id = $1) + $3 <= $8
+		RETURNING ` + uploadColumns
+
+	var created *models.Upload
+	err := atomic(ctx, r.db, func(ctx context.Context) error {
+		if r.dialect == Postgres {
+			if _, err := conn(ctx, r.db).ExecContext(ctx, lock, upload.OwnerID); err != nil {
+				return err
+			}
+		}
+		var err error
+		created, err = scanUpload(conn(ctx, r.db).QueryRowContext(
+			ctx,
+			query,
+			upload.OwnerID,
+			upload.Key,
+			upload.SizeBytes,
+			upload.SHA256,
+			upload.PHash,
+			upload.ContentType,
+			upload.Visibility,
+			quotaBytes,
+		))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadQuotaExceeded
+		}
+		if cerr := constraintViolation(err); cerr != nil {
+			return nil, nameForeignKey(ctx, conn(ctx, r.db), cerr, "uploads",
+				foreignKey{"uploads_owner_id_fkey", "users", upload.OwnerID})
+		}
+		queryFailed(ctx, span, "create_upload", err)
+		return nil, fmt.Errorf("create upload: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *SQLUploadRepository) GetByKey(ctx context.Context, key string) (*models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.GetByKey", "uploads", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE storage_key = $1`
+
+	upload, err := scanUpload(conn(ctx, r.db).QueryRowContext(ctx, query, key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		queryFailed(ctx, span, "get_upload_by_key", err)
+		return nil, fmt.Errorf("get upload by key: %w", err)
+	}
+
+	return upload, nil
+}
+
+func (r *SQLUploadRepository) GetByKeys(ctx context.Context, keys []string) ([]models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.GetByKeys", "uploads", "SELECT")
+	defer span.End()
+
+	where, args := inList(r.dialect, "storage_key", 1, keys)
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE ` + where
+
+	uploads, err := r.query(ctx, query, args...)
+	if err != nil {
+		queryFailed(ctx, span, "get_uploads_by_keys", err)
+		return nil, fmt.Errorf("get uploads by keys: %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (r *SQLUploadRepository) ListByOwner(ctx context.Context, ownerID int64) ([]models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.ListByOwner", "uploads", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE owner_id = $1 ORDER BY id`
+
+	uploads, err := r.query(ctx, query, ownerID)
+	if err != nil {
+		queryFailed(ctx, span, "list_uploads_by_owner", err)
+		return nil, fmt.Errorf("list uploads by owner: %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (r *SQLUploadRepository) ListOwnedByAnonymized(ctx context.Context, anonymizedBefore time.Time, limit int) ([]models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.ListOwnedByAnonymized", "uploads", "SELECT")
+	defer span.End()
+
+	query := `
+		SELECT ` + uploadColumns + `
+		FROM uploads
+		WHERE owner_id IN (SELECT id FROM users WHERE anonymized_at < $1)
+		ORDER BY id
+		LIMIT $2
+	`
+
+	uploads, err := r.query(ctx, query, anonymizedBefore.UTC(), limit)
+	if err != nil {
+		queryFailed(ctx, span, "list_uploads_owned_by_anonymized", err)
+		return nil, fmt.Errorf("list uploads owned by anonymized users: %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (r *SQLUploadRepository) FindSimilar(ctx context.Context, phash int64, excludeOwnerID int64, maxDistance, limit int) ([]models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.FindSimilar", "uploads", "SELECT")
+	defer span.End()
+
+	if r.dialect == SQLite {
+		uploads, err := r.findSimilarInGo(ctx, phash, excludeOwnerID, maxDistance, limit)
+		if err != nil {
+			queryFailed(ctx, span, "find_similar_uploads", err)
+			return nil, fmt.Errorf("find similar uploads: %w", err)
+		}
+		return uploads, nil
+	}
+
+	// bit_count needs PostgreSQL 14. The scan is linear, which is fine at
+	// campus scale: only attached public uploads with a hash are compared.
+	query := `
+		SELECT ` + uploadColumns + `
+		FROM uploads
+		WHERE listing_id IS NOT NULL
+			AND phash IS NOT NULL
+			AND owner_id <> $2
+			AND bit_count((phash # $1)::bit(64)) <= $3
+		ORDER BY bit_count((phash # $1)::bit(64)), id
+		LIMIT $4
+	`
+
+	uploads, err := r.query(ctx, query, phash, excludeOwnerID, maxDistance, limit)
+	if err != nil {
+		queryFailed(ctx, span, "find_similar_uploads", err)
+		return nil, fmt.Errorf("find similar uploads: %w", err)
+	}
+
+	return uploads, nil
+}
+
+// findSimilarInGo is FindSimilar for SQLite, which can neither XOR nor
+// count bits: it compares every candidate hash in Go.
+func (r *SQLUploadRepository) findSimilarInGo(ctx context.Context, phash int64, excludeOwnerID int64, maxDistance, limit int) ([]models.Upload, error) {
+	query := `
+		SELECT ` + uploadColumns + `
+		FROM uploads
+		WHERE listing_id IS NOT NULL AND phash IS NOT NULL AND owner_id <> $1
+	`
+
+	candidates, err := r.query(ctx, query, excludeOwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	distance := func(upload models.Upload) int {
+		return bits.OnesCount64(uint64(*upload.PHash ^ phash))
+	}
+	uploads := slices.DeleteFunc(candidates, func(upload models.Upload) bool {
+		return distance(upload) > maxDistance
+	})
+	slices.SortStableFunc(uploads, func(a, b models.Upload) int {
+		return cmp.Or(cmp.Compare(distance(a), distance(b)), cmp.Compare(a.ID, b.ID))
+	})
+	if len(uploads) > limit {
+		uploads = uploads[:limit]
+	}
+	return uploads, nil
+}
+
+func (r *SQLUploadRepository) UsageByOwner(ctx context.Context, ownerID int64) (int64, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.UsageByOwner", "uploads", "SELECT")
+	defer span.End()
+
+	const query = `SELECT COALESCE(SUM(size_bytes), 0) FROM uploads WHERE owner_id = $1`
+
+	var used int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, ownerID).Scan(&used); err != nil {
+		queryFailed(ctx, span, "upload_usage", err)
+		return 0, fmt.Errorf("upload usage: %w", err)
+	}
+
+	return used, nil
+}
+
+func (r *SQLUploadRepository) Attach(ctx context.Context, keys []string, listingID int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.Attach", "uploads", "UPDATE")
+	defer span.End()
+
+	keys = slices.Compact(slices.Sorted(slices.Values(keys)))
+	where, args := inList(r.dialect, "storage_key", 2, keys)
+	query := `
+		UPDATE uploads
+		SET listing_id = $1, attached_at = COALESCE(attached_at, CURRENT_TIMESTAMP)
+		WHERE (listing_id IS NULL OR listing_id = $1) AND held_at IS NULL AND ` + where
+
+	// Fewer rows than keys means an upload is gone, held or taken, and the
+	// rollback undoes the others.
+	err := atomic(ctx, r.db, func(ctx context.Context) error {
+		result, err := conn(ctx, r.db).ExecContext(ctx, query, append([]any{listingID}, args...)...)
+		if err != nil {
+			return err
+		}
+		attached, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if attached != int64(len(keys)) {
+			return ErrUploadInUse
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrUploadInUse) {
+			return err
+		}
+		if cerr := constraintViolation(err); cerr != nil {
+			return cerr
+		}
+		queryFailed(ctx, span, "attach_uploads", err)
+		return fmt.Errorf("attach uploads: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLUploadRepository) Detach(ctx context.Context, listingIDs []int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.Detach", "uploads", "UPDATE")
+	defer span.End()
+
+	if len(listingIDs) == 0 {
+		return nil
+	}
+
+	where, args := inList(r.dialect, "listing_id", 1, listingIDs)
+	query := `UPDATE uploads SET listing_id = NULL, attached_at = NULL WHERE ` + where
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		queryFailed(ctx, span, "detach_uploads", err)
+		return fmt.Errorf("detach uploads: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLUploadRepository) Hold(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.Hold", "uploads", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE uploads
+		SET listing_id = NULL, attached_at = NULL, held_at = COALESCE(held_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		queryFailed(ctx, span, "hold_upload", err)
+		return fmt.Errorf("hold upload: %w", err)
+	}
+	held, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "hold_upload", err)
+		return fmt.Errorf("hold upload: %w", err)
+	}
+	if held == 0 {
+		return ErrUploadNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLUploadRepository) ListUnattached(ctx context.Context, createdBefore time.Time, limit int) ([]models.Upload, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.ListUnattached", "uploads", "SELECT")
+	defer span.End()
+
+	query := `
+		SELECT ` + uploadColumns + `
+		FROM uploads
+		WHERE attached_at IS NULL AND held_at IS NULL AND visibility = 'public' AND created_at < $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	// SQLite compares timestamps as text, which only orders correctly when
+	// they share a time zone.
+	uploads, err := r.query(ctx, query, createdBefore.UTC(), limit)
+	if err != nil {
+		queryFailed(ctx, span, "list_unattached_uploads", err)
+		return nil, fmt.Errorf("list unattached uploads: %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (r *SQLUploadRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.Delete", "uploads", "DELETE")
+	defer span.End()
+
+	const query = `DELETE FROM uploads WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+		queryFailed(ctx, span, "delete_upload", err)
+		return fmt.Errorf("delete upload: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLUploadRepository) DeleteUnattached(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UploadRepository.DeleteUnattached", "uploads", "DELETE")
+	defer span.End()
+
+	const query = `DELETE FROM uploads WHERE id = $1 AND attached_at IS NULL AND held_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		queryFailed(ctx, span, "delete_unattached_upload", err)
+		return fmt.Errorf("delete unattached upload: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "delete_unattached_upload", err)
+		return fmt.Errorf("delete unattached upload: %w", err)
+	}
+	if deleted == 0 {
+		return ErrUploadNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLUploadRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.Upload, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := make([]models.Upload, 0)
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, *upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUpload(row rowScanner) (*models.Upload, error) {
+	upload := &models.Upload{}
+	err := row.Scan(
+		&upload.ID,
+		&upload.OwnerID,
+		&upload.Key,
+		&upload.SizeBytes,
+		&upload.SHA256,
+		&upload.PHash,
+		&upload.ContentType,
+		&upload.Visibility,
+		&upload.ListingID,
+		&upload.AttachedAt,
+		&upload.HeldAt,
+		&upload.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
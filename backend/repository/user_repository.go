@@ -5,7 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"uniswap-campus-marketplace/models"
 )
@@ -13,104 +13,337 @@ import (
 var ErrUserNotFound = errors.New("user not found")
 var ErrEmailAlreadyExists = errors.New("email already exists")
 
+// UserRepository skips soft-deleted users unless a method says otherwise.
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByID(ctx context.Context, id int64) (*models.User, error)
+	// GetDeleted returns a soft-deleted user, or ErrUserNotFound if the
+	// user does not exist, is not deleted or was anonymized.
+	GetDeleted(ctx context.Context, id int64) (*models.User, error)
+	// IncrementFailedLogins records a failed login and returns the number
+	// of consecutive failures.
+	IncrementFailedLogins(ctx context.Context, id int64) (int, error)
+	SetLockedUntil(ctx context.Context, id int64, until time.Time) error
+	// ResetFailedLogins clears the failure count and any lock.
+	ResetFailedLogins(ctx context.Context, id int64) error
+	// SoftDelete hides the user as of at.
+	SoftDelete(ctx context.Context, id int64, at time.Time) error
+	// Restore undoes SoftDelete; anonymized users cannot be restored.
+	Restore(ctx context.Context, id int64) error
+	// Anonymize erases the personal data of a user and soft-deletes the
+	// account as of at, keeping the row for the records that reference it.
+	Anonymize(ctx context.Context, id int64, at time.Time) error
+	// ListDeleted returns the IDs of users soft-deleted before the cutoff,
+	// oldest first. Anonymized users are kept and never listed.
+	ListDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error)
+	// Purge deletes a soft-deleted user for good, along with everything the
+	// schema cascades to.
+	Purge(ctx context.Context, id int64) error
 }
 
-type PostgresUserRepository struct {
-	db *sql.DB
+// SQLUserRepository stores users in PostgreSQL or SQLite.
+type SQLUserRepository struct {
+	db      *sql.DB
+	dialect Dialect
 }
 
-func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
-	return &PostgresUserRepository{db: db}
+func NewSQLUserRepository(db *sql.DB, dialect Dialect) *SQLUserRepository {
+	return &SQLUserRepository{db: db, dialect: dialect}
 }
 
-func (r *PostgresUserRepository) Create(ctx context.Context, user *models.User) (*models.User, error) {
-	const query = `
+const userColumns = `id, full_name, email, password_hash, university, is_moderator, created_at, updated_at, failed_login_attempts, locked_until, deleted_at, anonymized_at`
+
+func (r *SQLUserRepository) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.Create", "users", "INSERT")
+	defer span.End()
+
+	query := `
 		INSERT INTO users (full_name, email, password_hash, university)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, full_name, email, password_hash, university, created_at, updated_at
-	`
+		RETURNING ` + userColumns
 
-	created := &models.User{}
-	err := r.db.QueryRowContext(
+	created, err := scanUser(conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		user.FullName,
 		user.Email,
 		user.PasswordHash,
 		user.University,
-	).Scan(
-		&created.ID,
-		&created.FullName,
-		&created.Email,
-		&created.PasswordHash,
-		&created.University,
-		&created.CreatedAt,
-		&created.UpdatedAt,
-	)
+	))
 	if err != nil {
-		if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
-			return nil, ErrEmailAlreadyExists
+		if cerr := constraintViolation(err); cerr != nil {
+			return nil, cerr
 		}
+		queryFailed(ctx, span, "create_user", err)
 		return nil, fmt.Errorf("create user: %w", err)
 	}
 
 	return created, nil
 }
 
-func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.GetByEmail", "users", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1 AND deleted_at IS NULL`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		queryFailed(ctx, span, "get_user_by_email", err)
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *SQLUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.GetByID", "users", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		queryFailed(ctx, span, "get_user_by_id", err)
+		return nil, fmt.Errorf("get user by id: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *SQLUserRepository) IncrementFailedLogins(ctx context.Context, id int64) (int, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.IncrementFailedLogins", "users", "UPDATE")
+	defer span.End()
+
+	const query = `
+		UPDATE users
+		SET failed_login_attempts = failed_login_attempts + 1
+		WHERE id = $1
+		RETURNING failed_login_attempts
+	`
+
+	var attempts int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		queryFailed(ctx, span, "increment_failed_logins", err)
+		return 0, fmt.Errorf("increment failed logins: %w", err)
+	}
+
+	return attempts, nil
+}
+
+func (r *SQLUserRepository) SetLockedUntil(ctx context.Context, id int64, until time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.SetLockedUntil", "users", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE users SET locked_until = $2 WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, until); err != nil {
+		queryFailed(ctx, span, "set_locked_until", err)
+		return fmt.Errorf("set locked until: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.ResetFailedLogins", "users", "UPDATE")
+	defer span.End()
+
 	const query = `
-		SELECT id, full_name, email, password_hash, university, created_at, updated_at
-		FROM users
-		WHERE email = $1
+		UPDATE users
+		SET failed_login_attempts = 0, locked_until = NULL
+		WHERE id = $1 AND (failed_login_attempts <> 0 OR locked_until IS NOT NULL)
 	`
 
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.FullName,
-		&user.Email,
-		&user.PasswordHash,
-		&user.University,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+		queryFailed(ctx, span, "reset_failed_logins", err)
+		return fmt.Errorf("reset failed logins: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) GetDeleted(ctx context.Context, id int64) (*models.User, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.GetDeleted", "users", "SELECT")
+	defer span.End()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
-		return nil, fmt.Errorf("get user by email: %w", err)
+		queryFailed(ctx, span, "get_deleted_user", err)
+		return nil, fmt.Errorf("get deleted user: %w", err)
 	}
 
 	return user, nil
 }
 
-func (r *PostgresUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+func (r *SQLUserRepository) SoftDelete(ctx context.Context, id int64, at time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.SoftDelete", "users", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE users SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, at.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "soft_delete_user", err)
+		return fmt.Errorf("soft delete user: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "soft_delete_user", err)
+		return fmt.Errorf("soft delete user: %w", err)
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) Restore(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.Restore", "users", "UPDATE")
+	defer span.End()
+
+	const query = `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		queryFailed(ctx, span, "restore_user", err)
+		return fmt.Errorf("restore user: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "restore_user", err)
+		return fmt.Errorf("restore user: %w", err)
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) Anonymize(ctx context.Context, id int64, at time.Time) error {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.Anonymize", "users", "UPDATE")
+	defer span.End()
+
+	// The placeholder address keeps email unique and NOT NULL; .invalid can
+	// never receive mail. An empty hash matches no password.
 	const query = `
-		SELECT id, full_name, email, password_hash, university, created_at, updated_at
+		UPDATE users
+		SET full_name = 'Deleted user',
+			email = 'deleted-' || id || '@deleted.invalid',
+			password_hash = '',
+			university = '',
+			is_moderator = FALSE,
+			failed_login_attempts = 0,
+			locked_until = NULL,
+			updated_at = $2,
+			deleted_at = $2,
+			anonymized_at = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, at.UTC())
+	if err != nil {
+		queryFailed(ctx, span, "anonymize_user", err)
+		return fmt.Errorf("anonymize user: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		queryFailed(ctx, span, "anonymize_user", err)
+		return fmt.Errorf("anonymize user: %w", err)
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) ListDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error) {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.ListDeleted", "users", "SELECT")
+	defer span.End()
+
+	const query = `
+		SELECT id
 		FROM users
-		WHERE id = $1
+		WHERE deleted_at < $1 AND anonymized_at IS NULL
+		ORDER BY deleted_at, id
+		LIMIT $2
 	`
 
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, deletedBefore.UTC(), limit)
+	if err != nil {
+		queryFailed(ctx, span, "list_deleted_users", err)
+		return nil, fmt.Errorf("list deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			queryFailed(ctx, span, "scan_deleted_user", err)
+			return nil, fmt.Errorf("scan deleted user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		queryFailed(ctx, span, "iterate_deleted_users", err)
+		return nil, fmt.Errorf("iterate deleted users: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *SQLUserRepository) Purge(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, r.dialect, "UserRepository.Purge", "users", "DELETE")
+	defer span.End()
+
+	const query = `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+		queryFailed(ctx, span, "purge_user", err)
+		return fmt.Errorf("purge user: %w", err)
+	}
+
+	return nil
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.FullName,
 		&user.Email,
 		&user.PasswordHash,
 		&user.University,
+		&user.IsModerator,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.FailedLoginAttempts,
+		&user.LockedUntil,
+		&user.DeletedAt,
+		&user.AnonymizedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrUserNotFound
-		}
-		return nil, fmt.Errorf("get user by id: %w", err)
+		return nil, err
 	}
-
 	return user, nil
 }
@@ -0,0 +1,157 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"uniswap-campus-marketplace/apierror"
+)
+
+// Middleware wraps an http.Handler with additional behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Route describes a single method + path pattern served by the API.
+type Route struct {
+	Method      string
+	Pattern     string
+	Handler     http.HandlerFunc
+	RequireAuth bool
+	Middleware  []Middleware
+	Doc         *Doc
+}
+
+// Doc carries the API documentation for a route. It is read by the openapi
+// package to generate the published specification.
+type Doc struct {
+	Summary string
+	Tags    []string
+	// Request is a zero value of the JSON request body model, if any.
+	Request interface{}
+	// Upload names the multipart form field for file upload routes;
+	// FormFields are its other, optional text fields.
+	Upload     string
+	FormFields []Param
+	// Response is a zero value of the success envelope's data payload. Map
+	// values are documented using their keys as properties.
+	Response interface{}
+	// Status is the success status code; it defaults to 200.
+	Status int
+	// Errors lists the error status codes the route can answer with.
+	Errors []int
+	Query  []Param
+	// Headers are request headers the route reads, such as conditional
+	// request headers.
+	Headers []Param
+	// Raw is the content type of routes whose success body is not the JSON
+	// envelope, such as the specification itself or HTML pages.
+	Raw string
+}
+
+// Param documents a query string parameter or request header.
+type Param struct {
+	Name        string
+	Description string
+}
+
+// Router builds an http.ServeMux from a route table. Routes that share a path
+// but not a method answer with 405 and an Allow header listing the methods
+// registered for that path.
+type Router struct {
+	mux *http.ServeMux
+	// paths answers for every pattern regardless of method, with 405. It is
+	// a mux of its own so that a method-less pattern never conflicts with a
+	// route, such as "/uploads/private/{key...}" with "GET /uploads/{key...}".
+	paths   *http.ServeMux
+	auth    Middleware
+	routes  []Route
+	allowed map[string][]string
+}
+
+func New(auth Middleware) *Router {
+	return &Router{
+		mux:     http.NewServeMux(),
+		paths:   http.NewServeMux(),
+		auth:    auth,
+		allowed: make(map[string][]string),
+	}
+}
+
+// Handle registers a route. Per-route middleware runs in the order given,
+// after authentication when RequireAuth is set.
+func (rt *Router) Handle(route Route) {
+	var handler http.Handler = route.Handler
+	for i := len(route.Middleware) - 1; i >= 0; i-- {
+		handler = route.Middleware[i](handler)
+	}
+	if route.RequireAuth && rt.auth != nil {
+		handler = rt.auth(handler)
+	}
+
+	rt.mux.Handle(route.Method+" "+route.Pattern, handler)
+	rt.routes = append(rt.routes, route)
+
+	if _, seen := rt.allowed[route.Pattern]; !seen {
+		pattern := route.Pattern
+		rt.paths.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			rt.methodNotAllowed(w, r, pattern)
+		})
+	}
+	rt.allowed[route.Pattern] = append(rt.allowed[route.Pattern], route.Method)
+}
+
+// Routes returns the registered route table in registration order.
+func (rt *Router) Routes() []Route {
+	routes := make([]Route, len(rt.routes))
+	copy(routes, rt.routes)
+	return routes
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mux := rt.mux
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		mux = rt.paths
+		_, pattern = mux.Handler(r)
+	}
+	if slot, ok := r.Context().Value(patternContextKey{}).(*string); ok {
+		*slot = pattern
+	}
+
+	if pattern == "" {
+		apierror.Write(w, r, apierror.ErrNotFound)
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
+
+type patternContextKey struct{}
+
+// TrackPattern lets middleware wrapping the router learn which pattern
+// served a request, e.g. "GET /api/listings/{id}", without seeing the raw
+// path. Call the returned func after the request has been served; it
+// returns "" when no route matched.
+func TrackPattern(r *http.Request) (*http.Request, func() string) {
+	if slot, ok := r.Context().Value(patternContextKey{}).(*string); ok {
+		return r, func() string { return *slot }
+	}
+
+	slot := new(string)
+	ctx := context.WithValue(r.Context(), patternContextKey{}, slot)
+	return r.WithContext(ctx), func() string { return *slot }
+}
+
+func (rt *Router) methodNotAllowed(w http.ResponseWriter, r *http.Request, pattern string) {
+	methods := append([]string(nil), rt.allowed[pattern]...)
+	for _, method := range methods {
+		if method == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+			break
+		}
+	}
+	sort.Strings(methods)
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	apierror.Write(w, r, apierror.ErrMethodNotAllowed)
+}
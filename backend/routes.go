@@ -0,0 +1,361 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"uniswap-campus-marketplace/handlers"
+	"uniswap-campus-marketplace/health"
+	"uniswap-campus-marketplace/metrics"
+	"uniswap-campus-marketplace/middleware"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/openapi"
+	"uniswap-campus-marketplace/ratelimit"
+	"uniswap-campus-marketplace/router"
+)
+
+var apiInfo = openapi.Info{
+	Title:       "UniSwap Campus Marketplace API",
+	Version:     "1.0.0",
+	Description: "REST API for buying and selling items within a campus community.",
+}
+
+// registerRoutes is the single place that declares every API route, its
+// method and whether it requires a bearer token.
+func (a *app) registerRoutes(
+	rt *router.Router,
+	authHandler *handlers.AuthHandler,
+	listingHandler *handlers.ListingHandler,
+	uploadHandler *handlers.UploadHandler,
+	moderationHandler *handlers.ModerationHandler,
+	accountHandler *handlers.AccountHandler,
+) {
+	limit := func(policy middleware.RateLimitPolicy) []router.Middleware {
+		return []router.Middleware{middleware.RateLimit(a.rateLimits, policy)}
+	}
+	moderators := []router.Middleware{a.requireModerator}
+	idempotencyKey := router.Param{
+		Name:        middleware.IdempotencyKeyHeader,
+		Description: "Unique key for this request, e.g. a UUID; a retry with the same key and body gets the first response again, marked Idempotent-Replayed",
+	}
+
+	routes := []router.Route{
+		{
+			Method: http.MethodGet, Pattern: "/health", Handler: a.health.Live,
+			Doc: &router.Doc{
+				Summary:  "Liveness probe (alias of /health/live)",
+				Tags:     []string{"system"},
+				Response: map[string]string{"status": ""},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/health/live", Handler: a.health.Live,
+			Doc: &router.Doc{
+				Summary:  "Liveness probe",
+				Tags:     []string{"system"},
+				Response: map[string]string{"status": ""},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/health/ready", Handler: a.health.Ready,
+			Doc: &router.Doc{
+				Summary:  "Readiness probe with per-dependency checks",
+				Tags:     []string{"system"},
+				Response: health.Report{},
+				Errors:   []int{http.StatusServiceUnavailable},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/metrics", Handler: metrics.Handler().ServeHTTP,
+			Doc: &router.Doc{
+				Summary: "Prometheus metrics",
+				Tags:    []string{"system"},
+				Raw:     "text/plain",
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/api/openapi.json", Handler: openapi.SpecHandler(apiInfo, rt),
+			Doc: &router.Doc{
+				Summary: "OpenAPI specification for this API",
+				Tags:    []string{"system"},
+				Raw:     "application/json",
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/api/docs", Handler: openapi.DocsHandler(apiInfo.Title, "/api/openapi.json"),
+			Doc: &router.Doc{
+				Summary: "Interactive API documentation",
+				Tags:    []string{"system"},
+				Raw:     "text/html",
+			},
+		},
+
+		{
+			Method: http.MethodPost, Pattern: "/api/auth/register", Handler: authHandler.Register,
+			Middleware: limit(middleware.PerIP("register", ratelimit.Limit{Requests: 5, Per: time.Hour})),
+			Doc: &router.Doc{
+				Summary:  "Register a new account",
+				Tags:     []string{"auth"},
+				Request:  models.RegisterRequest{},
+				Response: map[string]string{"message": ""},
+				Status:   http.StatusCreated,
+				Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusTooManyRequests},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/auth/login", Handler: authHandler.Login,
+			Middleware: limit(middleware.PerIP("login", ratelimit.Limit{Requests: 10, Per: time.Minute})),
+			Doc: &router.Doc{
+				Summary:  "Log in and receive a bearer token (and a cookie session when enabled)",
+				Tags:     []string{"auth"},
+				Request:  models.LoginRequest{},
+				Response: map[string]string{"token": ""},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/auth/logout", Handler: authHandler.Logout,
+			Doc: &router.Doc{
+				Summary:  "End a cookie session",
+				Tags:     []string{"auth"},
+				Response: map[string]string{"message": ""},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/api/auth/me", Handler: authHandler.Me, RequireAuth: true,
+			Doc: &router.Doc{
+				Summary:  "Get the authenticated user",
+				Tags:     []string{"auth"},
+				Response: models.User{},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/api/auth/me/export", Handler: accountHandler.Export, RequireAuth: true,
+			Middleware: limit(middleware.PerUser("export_account", ratelimit.Limit{Requests: 5, Per: time.Hour})),
+			Doc: &router.Doc{
+				Summary: "Download everything stored about you: profile, listings, uploads and reports filed",
+				Tags:    []string{"auth"},
+				Query: []router.Param{
+					{Name: "format", Description: `"zip" (default), an archive with account.json and your uploaded files, or "json" for the data alone`},
+				},
+				Raw:    "application/zip",
+				Errors: []int{http.StatusBadRequest, http.StatusTooManyRequests},
+			},
+		},
+		{
+			Method: http.MethodDelete, Pattern: "/api/auth/me", Handler: accountHandler.DeleteAccount, RequireAuth: true,
+			Middleware: limit(middleware.PerUser("delete_account", ratelimit.Limit{Requests: 5, Per: time.Hour})),
+			Doc: &router.Doc{
+				Summary:  "Close your account: your listing photos are deleted, your listings taken down and your personal data erased",
+				Tags:     []string{"auth"},
+				Request:  models.DeleteAccountRequest{},
+				Response: map[string]string{"message": ""},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests},
+			},
+		},
+
+		{
+			Method: http.MethodGet, Pattern: "/api/listings", Handler: listingHandler.GetAllListings,
+			Doc: &router.Doc{
+				Summary:  "List listings, newest first",
+				Tags:     []string{"listings"},
+				Query:    []router.Param{{Name: "search", Description: "Case-insensitive title filter"}},
+				Response: []models.Listing{},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/listings", Handler: listingHandler.CreateListing, RequireAuth: true,
+			Middleware: append(limit(middleware.PerUser("create_listing", ratelimit.Limit{Requests: 30, Per: time.Hour})), a.idempotent),
+			Doc: &router.Doc{
+				Summary:  "Create a listing",
+				Tags:     []string{"listings"},
+				Headers:  []router.Param{idempotencyKey},
+				Request:  models.CreateListingRequest{},
+				Response: models.Listing{},
+				Status:   http.StatusCreated,
+				Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusTooManyRequests},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/api/listings/{id}", Handler: listingHandler.GetListingByID,
+			Doc: &router.Doc{
+				Summary: "Get a listing by ID, with its version as ETag",
+				Tags:    []string{"listings"},
+				Headers: []router.Param{
+					{Name: "If-None-Match", Description: "ETag of a copy you hold; answered with 304 Not Modified while it is current"},
+				},
+				Response: models.Listing{},
+				Errors:   []int{http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodPut, Pattern: "/api/listings/{id}", Handler: listingHandler.UpdateListing, RequireAuth: true,
+			Middleware: limit(middleware.PerUser("update_listing", ratelimit.Limit{Requests: 60, Per: time.Hour})),
+			Doc: &router.Doc{
+				Summary: "Replace one of your listings, images included",
+				Tags:    []string{"listings"},
+				Headers: []router.Param{
+					{Name: "If-Match", Description: "Required: the ETag of the listing your edit is based on, 412 if it has changed since; * to overwrite whatever version is current"},
+				},
+				Request:  models.UpdateListingRequest{},
+				Response: models.Listing{},
+				Errors: []int{
+					http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+					http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusTooManyRequests,
+				},
+			},
+		},
+		{
+			Method: http.MethodDelete, Pattern: "/api/listings/{id}", Handler: listingHandler.DeleteListing, RequireAuth: true,
+			Doc: &router.Doc{
+				Summary:  "Delete one of your listings (moderators can restore it until it is purged)",
+				Tags:     []string{"listings"},
+				Response: map[string]string{"message": ""},
+				Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/listings/{id}/report", Handler: listingHandler.ReportListing, RequireAuth: true,
+			Middleware: append(limit(middleware.PerUser("report_listing", ratelimit.Limit{Requests: 20, Per: time.Hour})), a.idempotent),
+			Doc: &router.Doc{
+				Summary:  "Report a listing to moderators",
+				Tags:     []string{"listings"},
+				Headers:  []router.Param{idempotencyKey},
+				Request:  models.CreateReportRequest{},
+				Response: models.Report{},
+				Status:   http.StatusCreated,
+				Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests},
+			},
+		},
+
+		{
+			Method: http.MethodPost, Pattern: "/api/uploads/image", Handler: uploadHandler.UploadImage, RequireAuth: true,
+			Middleware: limit(middleware.PerUser("upload_image", ratelimit.Limit{Requests: 60, Per: time.Hour})),
+			Doc: &router.Doc{
+				Summary: "Upload a JPEG, PNG, WebP or GIF image (max 10 MiB, counted against your storage quota)",
+				Tags:    []string{"uploads"},
+				Upload:  "file",
+				FormFields: []router.Param{
+					{Name: "visibility", Description: `"public" (default) or "private"; private uploads are only served through signed URLs`},
+				},
+				Response: models.UploadedImage{},
+				Status:   http.StatusCreated,
+				Errors: []int{
+					http.StatusBadRequest, http.StatusRequestEntityTooLarge,
+					http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity,
+					http.StatusTooManyRequests,
+				},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/uploads/private/sign", Handler: uploadHandler.SignURL, RequireAuth: true,
+			Doc: &router.Doc{
+				Summary:  "Get a fresh signed URL for one of your private uploads",
+				Tags:     []string{"uploads"},
+				Request:  models.SignURLRequest{},
+				Response: models.SignedURL{},
+				Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/uploads/{key...}", Handler: uploadHandler.File,
+			Doc: &router.Doc{
+				Summary: "Download a public upload or one of its variants",
+				Tags:    []string{"uploads"},
+				Raw:     "image/*",
+				Errors:  []int{http.StatusNotFound, http.StatusGone},
+			},
+		},
+		{
+			Method: http.MethodGet, Pattern: "/uploads/private/{key...}", Handler: uploadHandler.PrivateFile,
+			Middleware: []router.Middleware{a.optionalAuth},
+			Doc: &router.Doc{
+				Summary: "Download a private upload through a signed URL",
+				Tags:    []string{"uploads"},
+				Query: []router.Param{
+					{Name: "expires", Description: "Expiry as a Unix timestamp"},
+					{Name: "uid", Description: "User the URL is bound to, if any"},
+					{Name: "sig", Description: "URL signature"},
+				},
+				Raw:    "image/*",
+				Errors: []int{http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+
+		{
+			Method: http.MethodGet, Pattern: "/api/moderation/queue", Handler: moderationHandler.Queue, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Listings awaiting review, with their reports and near-duplicate images (moderators only)",
+				Tags:     []string{"moderation"},
+				Response: []models.ModerationQueueItem{},
+				Errors:   []int{http.StatusForbidden},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/moderation/matches/{id}/dismiss", Handler: moderationHandler.DismissMatch, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Dismiss a near-duplicate image match (moderators only)",
+				Tags:     []string{"moderation"},
+				Response: map[string]string{"message": ""},
+				Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/moderation/images/remove", Handler: moderationHandler.RemoveImage, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Remove an image from every listing and block re-uploads of it (moderators only)",
+				Tags:     []string{"moderation"},
+				Request:  models.RemoveImageRequest{},
+				Response: models.BlockedImage{},
+				Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodDelete, Pattern: "/api/moderation/listings/{id}", Handler: moderationHandler.DeleteListing, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Delete any listing, keeping it and its reports until it is purged (moderators only)",
+				Tags:     []string{"moderation"},
+				Response: map[string]string{"message": ""},
+				Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/moderation/listings/{id}/restore", Handler: moderationHandler.RestoreListing, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Restore a deleted listing that has not been purged yet (moderators only)",
+				Tags:     []string{"moderation"},
+				Response: models.Listing{},
+				Errors:   []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+			},
+		},
+		{
+			Method: http.MethodDelete, Pattern: "/api/moderation/users/{id}", Handler: moderationHandler.DeleteUser, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Delete an account and its listings, keeping them and their reports until they are purged (moderators only)",
+				Tags:     []string{"moderation"},
+				Response: map[string]string{"message": ""},
+				Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodPost, Pattern: "/api/moderation/users/{id}/restore", Handler: moderationHandler.RestoreUser, RequireAuth: true,
+			Middleware: moderators,
+			Doc: &router.Doc{
+				Summary:  "Restore a deleted account and the listings deleted with it (moderators only)",
+				Tags:     []string{"moderation"},
+				Response: models.User{},
+				Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		rt.Handle(route)
+	}
+}
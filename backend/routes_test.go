@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/config"
+	"uniswap-campus-marketplace/handlers"
+	"uniswap-campus-marketplace/health"
+	"uniswap-campus-marketplace/middleware"
+	"uniswap-campus-marketplace/openapi"
+	"uniswap-campus-marketplace/ratelimit"
+	"uniswap-campus-marketplace/router"
+	"uniswap-campus-marketplace/signedurl"
+	"uniswap-campus-marketplace/storage"
+)
+
+func newTestRouter() *router.Router {
+	a := &app{
+		cfg:              &config.Config{UploadDir: "uploads"},
+		health:           health.NewChecker(time.Second),
+		rateLimits:       ratelimit.NewMemoryStore(),
+		optionalAuth:     middleware.OptionalAuth(nil),
+		requireModerator: middleware.RequireModerator(nil),
+		idempotent:       middleware.Idempotency(nil),
+	}
+	rt := router.New(nil)
+	a.registerRoutes(
+		rt,
+		handlers.NewAuthHandler(nil, handlers.SessionOptions{}),
+		handlers.NewListingHandler(nil, nil),
+		handlers.NewUploadHandler(nil, storage.NewLocal(a.cfg.UploadDir, "/uploads/"), signedurl.NewSigner("test")),
+		handlers.NewModerationHandler(nil),
+		handlers.NewAccountHandler(nil, handlers.SessionOptions{}),
+	)
+	return rt
+}
+
+func TestEveryRouteIsInOpenAPISpec(t *testing.T) {
+	rt := newTestRouter()
+	doc := openapi.Generate(apiInfo, rt.Routes())
+
+	for _, route := range rt.Routes() {
+		path := openapi.PathTemplate(route.Pattern)
+		op, ok := doc.Paths[path][strings.ToLower(route.Method)]
+		if !ok {
+			t.Errorf("route %s %s is missing from the OpenAPI spec; add a Doc to it", route.Method, route.Pattern)
+			continue
+		}
+		if op.Summary == "" {
+			t.Errorf("route %s %s has no summary", route.Method, route.Pattern)
+		}
+		if route.RequireAuth && len(op.Security) == 0 {
+			t.Errorf("route %s %s requires auth but declares no security", route.Method, route.Pattern)
+		}
+	}
+}
+
+func TestOpenAPIPathParamTypes(t *testing.T) {
+	doc := openapi.Generate(apiInfo, newTestRouter().Routes())
+
+	tests := []struct {
+		path, param, typ string
+	}{
+		{"/api/listings/{id}", "id", "integer"},
+		{"/uploads/private/{key}", "key", "string"},
+	}
+	for _, tt := range tests {
+		op, ok := doc.Paths[tt.path]["get"]
+		if !ok {
+			t.Errorf("GET %s missing from the spec", tt.path)
+			continue
+		}
+		i := slices.IndexFunc(op.Parameters, func(p openapi.Parameter) bool { return p.In == "path" && p.Name == tt.param })
+		if i < 0 || op.Parameters[i].Schema.Type != tt.typ {
+			t.Errorf("GET %s parameters = %+v, want %s of type %s", tt.path, op.Parameters, tt.param, tt.typ)
+		}
+	}
+}
+
+func TestOpenAPISpecIsServed(t *testing.T) {
+	rt := newTestRouter()
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q, want 3.1.0", doc.OpenAPI)
+	}
+	for _, name := range []string{"RegisterRequest", "LoginRequest", "CreateListingRequest", "CreateReportRequest", "Listing", "apiResponse"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s missing from components", name)
+		}
+	}
+}
+
+func TestDocsPagePinsSwaggerUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	if strings.Count(body, `integrity="sha384-`) != 2 || strings.Contains(body, "swagger-ui-dist@5/") {
+		t.Errorf("docs page does not load a pinned Swagger UI with integrity hashes:\n%s", body)
+	}
+	csp := rec.Header().Get("Content-Security-Policy")
+	if strings.Contains(csp, "https://unpkg.com ") || strings.Contains(csp, "https://unpkg.com;") {
+		t.Errorf("CSP allows all of unpkg: %s", csp)
+	}
+}
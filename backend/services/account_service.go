@@ -0,0 +1,186 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/storage"
+	"uniswap-campus-marketplace/validation"
+)
+
+// ErrWrongPassword is returned when closing an account with a password that
+// does not match.
+var ErrWrongPassword = errors.New("password is incorrect")
+
+// AccountService lets users take out their data and close their account.
+type AccountService struct {
+	userRepo    repository.UserRepository
+	listingRepo repository.ListingRepository
+	reportRepo  repository.ReportRepository
+	uploadRepo  repository.UploadRepository
+	txManager   repository.TxManager
+	uploads     *UploadService
+	store       storage.Storage
+	now         func() time.Time
+}
+
+func NewAccountService(
+	userRepo repository.UserRepository,
+	listingRepo repository.ListingRepository,
+	reportRepo repository.ReportRepository,
+	uploadRepo repository.UploadRepository,
+	txManager repository.TxManager,
+	uploads *UploadService,
+	store storage.Storage,
+) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		listingRepo: listingRepo,
+		reportRepo:  reportRepo,
+		uploadRepo:  uploadRepo,
+		txManager:   txManager,
+		uploads:     uploads,
+		store:       store,
+		now:         time.Now,
+	}
+}
+
+// Export gathers the user's profile, listings, uploads and the reports they
+// filed.
+func (s *AccountService) Export(ctx context.Context, userID int64) (*models.AccountExport, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.Export")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	listings, err := s.listingRepo.ListBySeller(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	uploads, err := s.uploadRepo.ListByOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.reportRepo.ListByReporter(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "account_service.export: success", "user_id", userID, "listings", len(listings), "uploads", len(uploads))
+	return &models.AccountExport{
+		ExportedAt: s.now().UTC(),
+		Profile:    *user,
+		Listings:   listings,
+		Uploads:    uploads,
+		Reports:    reports,
+	}, nil
+}
+
+// WriteArchive writes export as a ZIP holding account.json and the original
+// of every upload under uploads/. Files missing from storage are left out
+// rather than failing the whole archive.
+func (s *AccountService) WriteArchive(ctx context.Context, w io.Writer, export *models.AccountExport) error {
+	ctx, span := tracer.Start(ctx, "AccountService.WriteArchive")
+	defer span.End()
+
+	archive := zip.NewWriter(w)
+
+	f, err := archive.Create("account.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return fmt.Errorf("encode account: %w", err)
+	}
+
+	for _, upload := range export.Uploads {
+		if err := s.archiveUpload(ctx, archive, upload); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				slog.WarnContext(ctx, "account_service.write_archive: upload missing from storage", "upload_id", upload.ID, "key", upload.Key)
+				continue
+			}
+			return fmt.Errorf("archive upload %d: %w", upload.ID, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+func (s *AccountService) archiveUpload(ctx context.Context, archive *zip.Writer, upload models.Upload) error {
+	body, _, err := s.store.Get(ctx, upload.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     path.Join("uploads", upload.Key),
+		Method:   zip.Store, // images are already compressed
+		Modified: upload.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, body)
+	return err
+}
+
+// Delete closes the account once the password is confirmed. The user's
+// public files are deleted, their listings taken down and their personal
+// data erased; the account row stays, anonymized, so reports and moderation
+// records that reference it keep making sense. Private files and images a
+// moderator removed are kept as evidence until the retention purge.
+func (s *AccountService) Delete(ctx context.Context, userID int64, req models.DeleteAccountRequest) error {
+	ctx, span := tracer.Start(ctx, "AccountService.Delete")
+	defer span.End()
+
+	v := validation.New()
+	v.Required("password", req.Password)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		slog.WarnContext(ctx, "account_service.delete: password mismatch", "user_id", userID)
+		return ErrWrongPassword
+	}
+
+	// Files go first: if this fails part way the account is still there and
+	// the request can simply be repeated.
+	if err := s.uploads.DeletePublicOwnedBy(ctx, userID); err != nil {
+		return err
+	}
+
+	now := s.now()
+	err = s.txManager.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Anonymize(ctx, userID, now); err != nil {
+			return err
+		}
+		return s.listingRepo.SoftDeleteBySeller(ctx, userID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "account_service.delete: success", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/storage"
+	"uniswap-campus-marketplace/validation"
+)
+
+func TestExportAccount(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	user := e.createUser(t, "ada@example.edu")
+	other := e.createUser(t, "bob@example.edu")
+
+	image := e.saveImage(t, user, 0)
+	listing := e.createListing(t, user, image)
+	otherListing := e.createListing(t, other)
+	e.createListing(t, other, e.saveImage(t, other, 1))
+	if _, err := e.report.Create(ctx, otherListing.ID, user, models.CreateReportRequest{Reason: "scam"}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	export, err := e.account.Export(ctx, user)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if export.Profile.ID != user || len(export.Listings) != 1 || export.Listings[0].ID != listing.ID ||
+		len(export.Uploads) != 1 || export.Uploads[0].ID != image.ID || len(export.Reports) != 1 {
+		t.Fatalf("export = %+v, want only the user's own data", export)
+	}
+
+	var buf bytes.Buffer
+	if err := e.account.WriteArchive(ctx, &buf, export); err != nil {
+		t.Fatalf("WriteArchive: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	if f := files["uploads/"+image.Key]; f == nil || f.UncompressedSize64 == 0 {
+		t.Errorf("archive lacks the upload; files = %v", files)
+	}
+	f, err := files["account.json"].Open()
+	if err != nil {
+		t.Fatalf("open account.json: %v", err)
+	}
+	defer f.Close()
+	var decoded models.AccountExport
+	if err := json.NewDecoder(f).Decode(&decoded); err != nil {
+		t.Fatalf("decode account.json: %v", err)
+	}
+	if decoded.Profile.Email != "ada@example.edu" || len(decoded.Listings) != 1 {
+		t.Errorf("account.json = %+v", decoded)
+	}
+
+	// A file gone from storage is skipped rather than failing the export.
+	if err := e.store.Delete(ctx, image.Key); err != nil {
+		t.Fatalf("delete file: %v", err)
+	}
+	buf.Reset()
+	if err := e.account.WriteArchive(ctx, &buf, export); err != nil {
+		t.Errorf("WriteArchive with a missing file: %v", err)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user, err := e.users.Create(ctx, &models.User{FullName: "Ada Lovelace", Email: "ada@example.edu", PasswordHash: string(hash), University: "Test University"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	other := e.createUser(t, "bob@example.edu")
+
+	image := e.saveImage(t, user.ID, 0)
+	listing := e.createListing(t, user.ID, image)
+	otherListing := e.createListing(t, other)
+	report, err := e.report.Create(ctx, otherListing.ID, user.ID, models.CreateReportRequest{Reason: "scam"})
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	var fieldErrs validation.Errors
+	if err := e.account.Delete(ctx, user.ID, models.DeleteAccountRequest{}); !errors.As(err, &fieldErrs) {
+		t.Errorf("Delete without password err = %v, want validation error", err)
+	}
+	if err := e.account.Delete(ctx, user.ID, models.DeleteAccountRequest{Password: "wrong"}); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Delete with wrong password err = %v, want ErrWrongPassword", err)
+	}
+	if _, err := e.users.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("account deleted despite wrong password: %v", err)
+	}
+
+	if err := e.account.Delete(ctx, user.ID, models.DeleteAccountRequest{Password: "secret-password"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := e.users.GetByEmail(ctx, "ada@example.edu"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("GetByEmail after delete err = %v, want ErrUserNotFound", err)
+	}
+	if _, err := e.listing.GetByID(ctx, listing.ID); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("GetByID(listing) err = %v, want ErrListingNotFound", err)
+	}
+	if _, err := e.uploads.GetByKey(ctx, image.Key); !errors.Is(err, repository.ErrUploadNotFound) {
+		t.Errorf("GetByKey(upload) err = %v, want ErrUploadNotFound", err)
+	}
+	if _, _, err := e.store.Get(ctx, image.Key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("stored file err = %v, want ErrNotFound", err)
+	}
+	// Moderation keeps the report the user filed.
+	if filed, _ := e.reports.ListByReporter(ctx, user.ID); len(filed) != 1 || filed[0].ID != report.ID {
+		t.Errorf("reports filed = %+v, want the report kept", filed)
+	}
+
+	// The account can be neither restored nor purged.
+	if _, err := e.moderation.RestoreUser(ctx, other, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("RestoreUser(anonymized) err = %v, want ErrUserNotFound", err)
+	}
+	if err := e.account.Delete(ctx, user.ID, models.DeleteAccountRequest{Password: "secret-password"}); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("second Delete err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestDeleteAccountKeepsEvidenceUntilPurge(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user, err := e.users.Create(ctx, &models.User{FullName: "Eve", Email: "eve@example.edu", PasswordHash: string(hash)})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	moderator := e.createUser(t, "mod@example.edu")
+
+	photo := e.saveImage(t, user.ID, 0)
+	removed := e.saveImage(t, user.ID, 1)
+	e.createListing(t, user.ID, photo, removed)
+	if _, err := e.moderation.RemoveImage(ctx, moderator, models.RemoveImageRequest{ImageURL: "/uploads/" + removed.Key}); err != nil {
+		t.Fatalf("remove image: %v", err)
+	}
+	_, img := testImage(t, 2)
+	private, err := e.upload.Save(ctx, user.ID, models.UploadPrivate, img, nil)
+	if err != nil {
+		t.Fatalf("save private upload: %v", err)
+	}
+
+	if err := e.account.Delete(ctx, user.ID, models.DeleteAccountRequest{Password: "secret-password"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := e.uploads.GetByKey(ctx, photo.Key); !errors.Is(err, repository.ErrUploadNotFound) {
+		t.Errorf("GetByKey(listing photo) err = %v, want ErrUploadNotFound", err)
+	}
+	for _, kept := range []*models.Upload{removed, private} {
+		if _, err := e.uploads.GetByKey(ctx, kept.Key); err != nil {
+			t.Errorf("GetByKey(%s) after delete: %v", kept.Key, err)
+		}
+		if _, err := e.store.Stat(ctx, kept.Key); err != nil {
+			t.Errorf("stat %s after delete: %v", kept.Key, err)
+		}
+	}
+
+	// The retention purge deletes them once the account has been closed
+	// for long enough.
+	e.retention.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := e.retention.Purge(ctx, time.Hour); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	for _, kept := range []*models.Upload{removed, private} {
+		if _, err := e.uploads.GetByKey(ctx, kept.Key); !errors.Is(err, repository.ErrUploadNotFound) {
+			t.Errorf("GetByKey(%s) after purge err = %v, want ErrUploadNotFound", kept.Key, err)
+		}
+		if _, err := e.store.Stat(ctx, kept.Key); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("stat %s after purge err = %v, want ErrNotFound", kept.Key, err)
+		}
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"strings"
 	"time"
@@ -12,39 +12,84 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"uniswap-campus-marketplace/metrics"
 	"uniswap-campus-marketplace/models"
 	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
 )
 
 var ErrInvalidCredentials = errors.New("invalid email or password")
-var ErrValidation = errors.New("validation failed")
+var ErrTokenInvalid = errors.New("invalid token")
+var ErrTokenExpired = errors.New("token expired")
+var ErrAccountLocked = errors.New("account temporarily locked")
+
+// AccountLockedError reports when a locked account may try again. It
+// matches ErrAccountLocked with errors.Is.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// ErrValidation matches every validation.Errors returned by the services.
+var ErrValidation = validation.ErrInvalid
+
+// Column limits from db/migrations. Passwords are capped at the 72 bytes
+// bcrypt hashes.
+const (
+	maxFullNameLength   = 120
+	maxEmailLength      = 255
+	maxUniversityLength = 150
+	minPasswordLength   = 6
+	maxPasswordBytes    = 72
+)
+
+// Progressive lockout: once an account reaches lockoutThreshold consecutive
+// failed logins it is locked for lockoutBase, doubling with every further
+// failure up to lockoutMax.
+const (
+	lockoutThreshold = 5
+	lockoutBase      = time.Minute
+	lockoutMax       = time.Hour
+)
+
+// TokenTTL is how long issued tokens, and cookie sessions holding them, stay
+// valid.
+const TokenTTL = 24 * time.Hour
 
 type AuthService struct {
 	userRepo  repository.UserRepository
 	jwtSecret string
+	now       func() time.Time
 }
 
 func NewAuthService(userRepo repository.UserRepository, jwtSecret string) *AuthService {
 	return &AuthService{
 		userRepo:  userRepo,
 		jwtSecret: jwtSecret,
+		now:       time.Now,
 	}
 }
 
 func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest) (*models.AuthResponse, error) {
-	log.Printf("auth_service.register: validating request email=%s", req.Email)
-	if strings.TrimSpace(req.FullName) == "" || strings.TrimSpace(req.Email) == "" || strings.TrimSpace(req.Password) == "" {
-		log.Printf("auth_service.register: validation failed missing required fields")
-		return nil, fmt.Errorf("%w: full_name, email, and password are required", ErrValidation)
-	}
-	if len(req.Password) < 6 {
-		log.Printf("auth_service.register: validation failed short password email=%s", req.Email)
-		return nil, fmt.Errorf("%w: password must be at least 6 characters", ErrValidation)
+	ctx, span := tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	slog.DebugContext(ctx, "auth_service.register: validating request", "email", req.Email)
+	if err := validateRegister(req); err != nil {
+		slog.WarnContext(ctx, "auth_service.register: validation failed", "email", req.Email, "err", err)
+		return nil, err
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("auth_service.register: password hashing failed email=%s err=%v", req.Email, err)
+		slog.ErrorContext(ctx, "auth_service.register: password hashing failed", "email", req.Email, "err", err)
 		return nil, fmt.Errorf("hash password: %w", err)
 	}
 
@@ -57,17 +102,18 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 
 	createdUser, err := s.userRepo.Create(ctx, user)
 	if err != nil {
-		log.Printf("auth_service.register: user creation failed email=%s err=%v", user.Email, err)
+		slog.WarnContext(ctx, "auth_service.register: user creation failed", "email", user.Email, "err", err)
 		return nil, err
 	}
 
 	token, err := s.generateToken(createdUser)
 	if err != nil {
-		log.Printf("auth_service.register: token generation failed user_id=%d err=%v", createdUser.ID, err)
+		slog.ErrorContext(ctx, "auth_service.register: token generation failed", "user_id", createdUser.ID, "err", err)
 		return nil, err
 	}
 
-	log.Printf("auth_service.register: success user_id=%d email=%s", createdUser.ID, createdUser.Email)
+	metrics.Registrations.Inc()
+	slog.InfoContext(ctx, "auth_service.register: success", "user_id", createdUser.ID, "email", createdUser.Email)
 	return &models.AuthResponse{
 		Token: token,
 		User:  *createdUser,
@@ -75,102 +121,210 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 }
 
 func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*models.AuthResponse, error) {
-	log.Printf("auth_service.login: validating request email=%s", req.Email)
-	if strings.TrimSpace(req.Email) == "" || strings.TrimSpace(req.Password) == "" {
-		log.Printf("auth_service.login: validation failed missing email or password")
-		return nil, fmt.Errorf("%w: email and password are required", ErrValidation)
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	slog.DebugContext(ctx, "auth_service.login: validating request", "email", req.Email)
+	v := validation.New()
+	v.Required("email", req.Email)
+	v.Required("password", req.Password)
+	if err := v.Err(); err != nil {
+		slog.WarnContext(ctx, "auth_service.login: validation failed missing email or password")
+		metrics.LoginsFailed.WithLabelValues("validation").Inc()
+		return nil, err
 	}
 
 	user, err := s.userRepo.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(req.Email)))
 	if err != nil {
-		log.Printf("auth_service.login: user lookup failed email=%s err=%v", req.Email, err)
+		slog.WarnContext(ctx, "auth_service.login: user lookup failed", "email", req.Email, "err", err)
 		if errors.Is(err, repository.ErrUserNotFound) {
+			metrics.LoginsFailed.WithLabelValues("unknown_email").Inc()
 			return nil, ErrInvalidCredentials
 		}
+		metrics.LoginsFailed.WithLabelValues("error").Inc()
 		return nil, err
 	}
 
+	if user.LockedUntil != nil && s.now().Before(*user.LockedUntil) {
+		slog.WarnContext(ctx, "auth_service.login: account locked", "user_id", user.ID, "locked_until", *user.LockedUntil)
+		metrics.LoginsFailed.WithLabelValues("locked").Inc()
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		log.Printf("auth_service.login: password mismatch user_id=%d email=%s", user.ID, user.Email)
+		slog.WarnContext(ctx, "auth_service.login: password mismatch", "user_id", user.ID, "email", user.Email)
+		metrics.LoginsFailed.WithLabelValues("wrong_password").Inc()
+		s.recordFailedLogin(ctx, user.ID)
 		return nil, ErrInvalidCredentials
 	}
 
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			slog.ErrorContext(ctx, "auth_service.login: reset failed logins failed", "user_id", user.ID, "err", err)
+		}
+	}
+
 	token, err := s.generateToken(user)
 	if err != nil {
-		log.Printf("auth_service.login: token generation failed user_id=%d err=%v", user.ID, err)
+		slog.ErrorContext(ctx, "auth_service.login: token generation failed", "user_id", user.ID, "err", err)
 		return nil, err
 	}
 
-	log.Printf("auth_service.login: success user_id=%d email=%s", user.ID, user.Email)
+	slog.InfoContext(ctx, "auth_service.login: success", "user_id", user.ID, "email", user.Email)
 	return &models.AuthResponse{
 		Token: token,
 		User:  *user,
 	}, nil
 }
 
+// recordFailedLogin counts a wrong password and locks the account once the
+// threshold is reached. Failures here are logged rather than returned so the
+// caller still answers with invalid credentials.
+func (s *AuthService) recordFailedLogin(ctx context.Context, userID int64) {
+	attempts, err := s.userRepo.IncrementFailedLogins(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "auth_service.login: record failed login failed", "user_id", userID, "err", err)
+		return
+	}
+	if attempts < lockoutThreshold {
+		return
+	}
+
+	until := s.now().Add(lockoutDuration(attempts))
+	if err := s.userRepo.SetLockedUntil(ctx, userID, until); err != nil {
+		slog.ErrorContext(ctx, "auth_service.login: lock account failed", "user_id", userID, "err", err)
+		return
+	}
+	slog.WarnContext(ctx, "auth_service.login: account locked", "user_id", userID, "attempts", attempts, "locked_until", until)
+}
+
+func lockoutDuration(attempts int) time.Duration {
+	doublings := attempts - lockoutThreshold
+	if doublings >= 6 {
+		return lockoutMax
+	}
+	return min(lockoutBase<<doublings, lockoutMax)
+}
+
+func validateRegister(req models.RegisterRequest) error {
+	fullName := strings.TrimSpace(req.FullName)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	v := validation.New()
+	v.Required("full_name", fullName)
+	v.MaxLength("full_name", fullName, maxFullNameLength)
+	v.Required("email", email)
+	v.MaxLength("email", email, maxEmailLength)
+	v.Email("email", email)
+	v.Required("password", req.Password)
+	v.MinLength("password", req.Password, minPasswordLength)
+	v.MaxBytes("password", req.Password, maxPasswordBytes)
+	v.MaxLength("university", strings.TrimSpace(req.University), maxUniversityLength)
+	return v.Err()
+}
+
 func (s *AuthService) generateToken(user *models.User) (string, error) {
-	log.Printf("auth_service.generate_token: creating token user_id=%d", user.ID)
+	slog.Debug("auth_service.generate_token: creating token", "user_id", user.ID)
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"exp":     time.Now().Add(TokenTTL).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString([]byte(s.jwtSecret))
 	if err != nil {
-		log.Printf("auth_service.generate_token: signing failed user_id=%d err=%v", user.ID, err)
+		slog.Error("auth_service.generate_token: signing failed", "user_id", user.ID, "err", err)
 		return "", fmt.Errorf("sign token: %w", err)
 	}
 
-	log.Printf("auth_service.generate_token: success user_id=%d", user.ID)
+	slog.Debug("auth_service.generate_token: success", "user_id", user.ID)
 	return signedToken, nil
 }
 
 func (s *AuthService) ParseToken(tokenString string) (int64, error) {
-	log.Printf("auth_service.parse_token: parsing token")
+	slog.Debug("auth_service.parse_token: parsing token")
 	parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodHS256 {
-			log.Printf("auth_service.parse_token: unexpected signing method method=%v", token.Method)
-			return nil, ErrInvalidCredentials
+			slog.Warn("auth_service.parse_token: unexpected signing method", "method", token.Method)
+			return nil, ErrTokenInvalid
 		}
 		return []byte(s.jwtSecret), nil
 	})
 	if err != nil || !parsedToken.Valid {
-		log.Printf("auth_service.parse_token: invalid token err=%v", err)
-		return 0, ErrInvalidCredentials
+		slog.Warn("auth_service.parse_token: invalid token", "err", err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return 0, ErrTokenExpired
+		}
+		return 0, ErrTokenInvalid
 	}
 
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		log.Printf("auth_service.parse_token: invalid claims type")
-		return 0, ErrInvalidCredentials
+		slog.Warn("auth_service.parse_token: invalid claims type")
+		return 0, ErrTokenInvalid
 	}
 
 	userIDValue, ok := claims["user_id"]
 	if !ok {
-		log.Printf("auth_service.parse_token: user_id claim missing")
-		return 0, ErrInvalidCredentials
+		slog.Warn("auth_service.parse_token: user_id claim missing")
+		return 0, ErrTokenInvalid
 	}
 
 	userIDFloat, ok := userIDValue.(float64)
 	if !ok || userIDFloat <= 0 || userIDFloat > math.MaxInt64 {
-		log.Printf("auth_service.parse_token: invalid user_id claim value=%v", userIDValue)
-		return 0, ErrInvalidCredentials
+		slog.Warn("auth_service.parse_token: invalid user_id claim", "value", userIDValue)
+		return 0, ErrTokenInvalid
 	}
 
-	log.Printf("auth_service.parse_token: success user_id=%d", int64(userIDFloat))
+	slog.Debug("auth_service.parse_token: success", "user_id", int64(userIDFloat))
 	return int64(userIDFloat), nil
 }
 
+// Authenticate parses a token and checks that its user still has an
+// account: tokens of deleted users stop working at once rather than when
+// they expire.
+func (s *AuthService) Authenticate(ctx context.Context, tokenString string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	userID, err := s.ParseToken(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			slog.WarnContext(ctx, "auth_service.authenticate: user deleted", "user_id", userID)
+			return 0, ErrTokenInvalid
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
 func (s *AuthService) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
-	log.Printf("auth_service.get_user_by_id: fetching user user_id=%d", id)
+	ctx, span := tracer.Start(ctx, "AuthService.GetUserByID")
+	defer span.End()
+
+	slog.DebugContext(ctx, "auth_service.get_user_by_id: fetching user", "user_id", id)
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
-		log.Printf("auth_service.get_user_by_id: lookup failed user_id=%d err=%v", id, err)
+		slog.WarnContext(ctx, "auth_service.get_user_by_id: lookup failed", "user_id", id, "err", err)
 		return nil, err
 	}
-	log.Printf("auth_service.get_user_by_id: success user_id=%d", id)
+	slog.DebugContext(ctx, "auth_service.get_user_by_id: success", "user_id", id)
 	return user, nil
 }
+
+// IsModerator reports whether the user may work the moderation queue.
+func (s *AuthService) IsModerator(ctx context.Context, userID int64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.IsModerator")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsModerator, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
+)
+
+func newTestAuthService() (*AuthService, *repository.MemoryUserRepository) {
+	users := repository.NewMemoryUserRepository()
+	return NewAuthService(users, "test-secret"), users
+}
+
+func register(t *testing.T, s *AuthService, email string) *models.AuthResponse {
+	t.Helper()
+	resp, err := s.Register(context.Background(), models.RegisterRequest{
+		FullName: "Ada Lovelace",
+		Email:    email,
+		Password: "correct horse",
+	})
+	if err != nil {
+		t.Fatalf("register %s: %v", email, err)
+	}
+	return resp
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s, _ := newTestAuthService()
+	ctx := context.Background()
+
+	registered := register(t, s, "  Ada@Example.EDU ")
+	if registered.User.Email != "ada@example.edu" {
+		t.Errorf("email = %q, want it trimmed and lower-cased", registered.User.Email)
+	}
+	if userID, err := s.ParseToken(registered.Token); err != nil || userID != registered.User.ID {
+		t.Errorf("ParseToken = %d, %v; want %d", userID, err, registered.User.ID)
+	}
+
+	loggedIn, err := s.Login(ctx, models.LoginRequest{Email: "ADA@example.edu", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if loggedIn.User.ID != registered.User.ID || loggedIn.Token == "" {
+		t.Errorf("login = %+v", loggedIn)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	s, _ := newTestAuthService()
+
+	_, err := s.Register(context.Background(), models.RegisterRequest{Email: "not-an-email", Password: "short"})
+	var fieldErrs validation.Errors
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("err = %v, want validation errors", err)
+	}
+	fields := make(map[string]bool)
+	for _, fe := range fieldErrs {
+		fields[fe.Field] = true
+	}
+	for _, field := range []string{"full_name", "email", "password"} {
+		if !fields[field] {
+			t.Errorf("no validation error for %s in %v", field, fieldErrs)
+		}
+	}
+}
+
+func TestRegisterDuplicateEmail(t *testing.T) {
+	s, _ := newTestAuthService()
+	register(t, s, "ada@example.edu")
+
+	_, err := s.Register(context.Background(), models.RegisterRequest{
+		FullName: "Someone Else",
+		Email:    "ADA@example.edu",
+		Password: "another password",
+	})
+	if !errors.Is(err, repository.ErrEmailAlreadyExists) {
+		t.Errorf("err = %v, want ErrEmailAlreadyExists", err)
+	}
+}
+
+func TestLoginRejectsBadCredentials(t *testing.T) {
+	s, _ := newTestAuthService()
+	register(t, s, "ada@example.edu")
+	ctx := context.Background()
+
+	if _, err := s.Login(ctx, models.LoginRequest{Email: "ada@example.edu", Password: "wrong"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.Login(ctx, models.LoginRequest{Email: "nobody@example.edu", Password: "wrong"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown email err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.Login(ctx, models.LoginRequest{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("empty request err = %v, want validation error", err)
+	}
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	s, users := newTestAuthService()
+	registered := register(t, s, "ada@example.edu")
+	ctx := context.Background()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < lockoutThreshold; i++ {
+		if _, err := s.Login(ctx, models.LoginRequest{Email: "ada@example.edu", Password: "wrong"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d err = %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+
+	_, err := s.Login(ctx, models.LoginRequest{Email: "ada@example.edu", Password: "correct horse"})
+	var locked *AccountLockedError
+	if !errors.As(err, &locked) || !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("err = %v, want AccountLockedError", err)
+	}
+	if want := now.Add(lockoutBase); !locked.Until.Equal(want) {
+		t.Errorf("locked until %v, want %v", locked.Until, want)
+	}
+
+	now = now.Add(lockoutBase + time.Second)
+	if _, err := s.Login(ctx, models.LoginRequest{Email: "ada@example.edu", Password: "correct horse"}); err != nil {
+		t.Fatalf("login after lock expired: %v", err)
+	}
+	user, _ := users.GetByID(ctx, registered.User.ID)
+	if user.FailedLoginAttempts != 0 || user.LockedUntil != nil {
+		t.Errorf("after successful login attempts = %d, locked until %v; want reset", user.FailedLoginAttempts, user.LockedUntil)
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{lockoutThreshold, lockoutBase},
+		{lockoutThreshold + 1, 2 * lockoutBase},
+		{lockoutThreshold + 2, 4 * lockoutBase},
+		{lockoutThreshold + 3, 8 * lockoutBase},
+		{lockoutThreshold + 5, 32 * lockoutBase},
+		{lockoutThreshold + 6, lockoutMax},
+		{lockoutThreshold + 50, lockoutMax},
+		{lockoutThreshold + 100, lockoutMax},
+	}
+	for _, tt := range tests {
+		if got := lockoutDuration(tt.attempts); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestParseTokenRejectsForeignTokens(t *testing.T) {
+	s, _ := newTestAuthService()
+	other := NewAuthService(repository.NewMemoryUserRepository(), "other-secret")
+	token := register(t, other, "ada@example.edu").Token
+
+	if _, err := s.ParseToken(token); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("token signed with another secret err = %v, want ErrTokenInvalid", err)
+	}
+	if _, err := s.ParseToken("garbage"); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("garbage token err = %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestIsModerator(t *testing.T) {
+	s, users := newTestAuthService()
+	ctx := context.Background()
+	user := register(t, s, "ada@example.edu").User
+
+	if ok, err := s.IsModerator(ctx, user.ID); err != nil || ok {
+		t.Errorf("IsModerator = %v, %v; want false", ok, err)
+	}
+	users.SetModerator(user.ID, true)
+	if ok, err := s.IsModerator(ctx, user.ID); err != nil || !ok {
+		t.Errorf("IsModerator = %v, %v; want true", ok, err)
+	}
+	if _, err := s.IsModerator(ctx, user.ID+1); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("IsModerator(missing) err = %v, want ErrUserNotFound", err)
+	}
+}
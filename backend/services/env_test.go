@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/storage"
+)
+
+const testQuotaBytes = 10 << 20
+
+// testEnv wires every service to in-memory repositories and a local store in
+// a temporary directory.
+type testEnv struct {
+	users           *repository.MemoryUserRepository
+	listings        *repository.MemoryListingRepository
+	reports         *repository.MemoryReportRepository
+	uploads         *repository.MemoryUploadRepository
+	moderationRepo  *repository.MemoryModerationRepository
+	idempotencyRepo *repository.MemoryIdempotencyRepository
+	txManager       *repository.MemoryTxManager
+	store           storage.Storage
+
+	auth        *AuthService
+	listing     *ListingService
+	report      *ReportService
+	upload      *UploadService
+	moderation  *ModerationService
+	retention   *RetentionService
+	account     *AccountService
+	idempotency *IdempotencyService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := storage.NewLocal(t.TempDir(), "/uploads/")
+	users := repository.NewMemoryUserRepository()
+	e := &testEnv{
+		users:           users,
+		listings:        repository.NewMemoryListingRepository(users),
+		reports:         repository.NewMemoryReportRepository(),
+		uploads:         repository.NewMemoryUploadRepository(users),
+		idempotencyRepo: repository.NewMemoryIdempotencyRepository(),
+		store:           store,
+	}
+	e.moderationRepo = repository.NewMemoryModerationRepository(e.uploads)
+	e.txManager = repository.NewMemoryTxManager(e.users, e.listings, e.reports, e.uploads, e.moderationRepo)
+	e.auth = NewAuthService(e.users, "test-secret")
+	e.moderation = NewModerationService(e.moderationRepo, e.uploads, e.listings, e.reports, e.users, e.txManager, 10)
+	e.listing = NewListingService(e.listings, e.uploads, e.txManager, e.moderation)
+	e.report = NewReportService(e.reports, e.listings)
+	e.upload = NewUploadService(e.uploads, e.moderationRepo, store, testQuotaBytes, true)
+	e.retention = NewRetentionService(e.users, e.listings, e.uploads, e.txManager, e.upload)
+	e.account = NewAccountService(e.users, e.listings, e.reports, e.uploads, e.txManager, e.upload, store)
+	e.idempotency = NewIdempotencyService(e.idempotencyRepo, 24*time.Hour)
+	return e
+}
+
+// createUser stores a user directly, skipping the slow password hashing of
+// Register.
+func (e *testEnv) createUser(t *testing.T, email string) int64 {
+	t.Helper()
+	user, err := e.users.Create(context.Background(), &models.User{FullName: "Test User", Email: email, PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user.ID
+}
+
+// saveImage runs a generated picture through the upload pipeline.
+func (e *testEnv) saveImage(t *testing.T, ownerID int64, pattern int) *models.Upload {
+	t.Helper()
+	decoded, img := testImage(t, pattern)
+	variants, err := imaging.GenerateVariants(decoded, img.Extension)
+	if err != nil {
+		t.Fatalf("generate variants: %v", err)
+	}
+	upload, err := e.upload.Save(context.Background(), ownerID, models.UploadPublic, img, variants)
+	if err != nil {
+		t.Fatalf("save upload: %v", err)
+	}
+	return upload
+}
+
+func (e *testEnv) createListing(t *testing.T, sellerID int64, uploads ...*models.Upload) *models.Listing {
+	t.Helper()
+	req := models.CreateListingRequest{Title: "Road bike", Description: "Barely used", Price: 120, Category: "Other"}
+	for _, upload := range uploads {
+		req.ImageURLs = append(req.ImageURLs, "/uploads/"+upload.Key)
+	}
+	listing, err := e.listing.Create(context.Background(), sellerID, req)
+	if err != nil {
+		t.Fatalf("create listing: %v", err)
+	}
+	return listing
+}
+
+// testImage returns a processed 320x240 PNG. Different patterns give
+// pictures far apart by perceptual hash; the same pattern gives identical
+// pictures.
+func testImage(t *testing.T, pattern int) (image.Image, *imaging.Image) {
+	t.Helper()
+	src := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			var v uint8
+			switch pattern % 3 {
+			case 0:
+				v = uint8(255 - x*255/320)
+			case 1:
+				v = uint8(y * 255 / 240)
+			default:
+				v = uint8((x / 40 % 2) * 255)
+			}
+			src.Set(x, y, color.RGBA{v, v, uint8(pattern), 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("encode test image: %v", err)
+	}
+	decoded, img, err := imaging.Process(buf.Bytes(), imaging.DefaultLimits)
+	if err != nil {
+		t.Fatalf("process test image: %v", err)
+	}
+	return decoded, img
+}
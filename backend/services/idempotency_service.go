@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent
+	// again with a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+	// ErrIdempotencyInProgress is returned while the first request sent with
+	// an idempotency key is still being processed.
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// idempotencyLockTimeout is how long a request may hold its idempotency key
+// without finishing. A key still unfinished after that, e.g. because the
+// server crashed, is given to the next request that sends it.
+const idempotencyLockTimeout = time.Minute
+
+// IdempotencyService remembers the response to each request sent with an
+// idempotency key so that a retry gets the same response instead of
+// repeating the request's effect.
+type IdempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// Begin claims key for the user's request identified by fingerprint. It
+// returns nil when the request should be processed, the stored response when
+// it already was, or an error when the key belongs to another request or
+// the first one is still being processed.
+func (s *IdempotencyService) Begin(ctx context.Context, userID int64, key, fingerprint string) (*models.StoredResponse, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	now := s.now()
+	existing, err := s.repo.Claim(ctx, &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+	}, now.Add(-s.ttl), now.Add(-idempotencyLockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		slog.WarnContext(ctx, "idempotency_service.begin: key reused for a different request", "user_id", userID)
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.Response == nil {
+		return nil, ErrIdempotencyInProgress
+	}
+	slog.InfoContext(ctx, "idempotency_service.begin: replaying stored response", "user_id", userID, "status", existing.Response.Status)
+	return existing.Response, nil
+}
+
+// Finish stores the response to a request Begin let through.
+func (s *IdempotencyService) Finish(ctx context.Context, userID int64, key string, response *models.StoredResponse) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Finish")
+	defer span.End()
+
+	return s.repo.Complete(ctx, userID, key, response)
+}
+
+// Abandon frees the key of a request Begin let through without storing its
+// response, so that a retry is processed afresh.
+func (s *IdempotencyService) Abandon(ctx context.Context, userID int64, key string) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Abandon")
+	defer span.End()
+
+	return s.repo.Release(ctx, userID, key)
+}
+
+// Purge deletes the records of keys older than the TTL.
+func (s *IdempotencyService) Purge(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Purge")
+	defer span.End()
+
+	deleted, err := s.repo.DeleteExpired(ctx, s.now().Add(-s.ttl))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "idempotency_service.purge: deleted expired keys", "keys", deleted)
+	}
+	return deleted, nil
+}
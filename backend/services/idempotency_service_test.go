@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+)
+
+func TestIdempotency(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	user := e.createUser(t, "ada@example.edu")
+	other := e.createUser(t, "bob@example.edu")
+
+	if stored, err := e.idempotency.Begin(ctx, user, "key", "request-a"); err != nil || stored != nil {
+		t.Fatalf("Begin = %+v, %v, want the request let through", stored, err)
+	}
+	if _, err := e.idempotency.Begin(ctx, user, "key", "request-a"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Begin(in progress) err = %v, want ErrIdempotencyInProgress", err)
+	}
+
+	response := &models.StoredResponse{Status: 201, Body: []byte(`{"id":1}`)}
+	if err := e.idempotency.Finish(ctx, user, "key", response); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	stored, err := e.idempotency.Begin(ctx, user, "key", "request-a")
+	if err != nil || stored == nil || stored.Status != 201 || string(stored.Body) != `{"id":1}` {
+		t.Errorf("Begin(retry) = %+v, %v, want the stored response", stored, err)
+	}
+	if _, err := e.idempotency.Begin(ctx, user, "key", "request-b"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Begin(different request) err = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if stored, err := e.idempotency.Begin(ctx, other, "key", "request-b"); err != nil || stored != nil {
+		t.Errorf("Begin(other user) = %+v, %v, want the request let through", stored, err)
+	}
+
+	// An abandoned request can be retried.
+	if err := e.idempotency.Abandon(ctx, other, "key"); err != nil {
+		t.Fatalf("Abandon: %v", err)
+	}
+	if stored, err := e.idempotency.Begin(ctx, other, "key", "request-b"); err != nil || stored != nil {
+		t.Errorf("Begin(after Abandon) = %+v, %v, want the request let through", stored, err)
+	}
+
+	// A request that never finished stops holding its key after the lock
+	// timeout, and every key expires after the TTL.
+	e.idempotency.now = func() time.Time { return time.Now().Add(2 * idempotencyLockTimeout) }
+	if stored, err := e.idempotency.Begin(ctx, other, "key", "request-c"); err != nil || stored != nil {
+		t.Errorf("Begin(after lock timeout) = %+v, %v, want the request let through", stored, err)
+	}
+	e.idempotency.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	if deleted, err := e.idempotency.Purge(ctx); err != nil || deleted != 2 {
+		t.Errorf("Purge = %d, %v, want 2", deleted, err)
+	}
+	if stored, err := e.idempotency.Begin(ctx, user, "key", "request-b"); err != nil || stored != nil {
+		t.Errorf("Begin(after expiry) = %+v, %v, want the request let through", stored, err)
+	}
+}
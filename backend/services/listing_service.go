@@ -2,47 +2,295 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"uniswap-campus-marketplace/metrics"
 	"uniswap-campus-marketplace/models"
 	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
 )
 
+// Column limits from db/migrations; price is NUMERIC(10,2).
+const (
+	maxTitleLength    = 150
+	maxCategoryLength = 80
+	pricePrecision    = 10
+	priceScale        = 2
+	maxListingImages  = 8
+)
+
+// uploadURLPattern matches URLs handed out by the upload endpoint, so a
+// listing can only show images that went through the upload pipeline.
+var uploadURLPattern = regexp.MustCompile(`^/uploads/[0-9a-f]{32}\.(jpg|png)$`)
+
+var ErrNotListingOwner = errors.New("listing belongs to another seller")
+
 type ListingService struct {
 	listingRepo repository.ListingRepository
+	uploadRepo  repository.UploadRepository
+	txManager   repository.TxManager
+	moderation  *ModerationService
+	now         func() time.Time
 }
 
-func NewListingService(listingRepo repository.ListingRepository) *ListingService {
-	return &ListingService{listingRepo: listingRepo}
+func NewListingService(
+	listingRepo repository.ListingRepository,
+	uploadRepo repository.UploadRepository,
+	txManager repository.TxManager,
+	moderation *ModerationService,
+) *ListingService {
+	return &ListingService{
+		listingRepo: listingRepo,
+		uploadRepo:  uploadRepo,
+		txManager:   txManager,
+		moderation:  moderation,
+		now:         time.Now,
+	}
 }
 
 func (s *ListingService) Create(ctx context.Context, userID int64, req models.CreateListingRequest) (*models.Listing, error) {
-	if strings.TrimSpace(req.Title) == "" {
-		return nil, fmt.Errorf("%w: title is required", ErrValidation)
+	ctx, span := tracer.Start(ctx, "ListingService.Create")
+	defer span.End()
+
+	listing, keys, err := buildListing(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ownership check, the listing and the attachment commit together,
+	// so a failed attach leaves no listing behind showing images that the
+	// orphan sweeper will later delete.
+	var (
+		created *models.Listing
+		uploads []models.Upload
+	)
+	err = s.txManager.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if uploads, err = s.ownedUploads(ctx, userID, 0, keys); err != nil {
+			return err
+		}
+		if created, err = s.listingRepo.Create(ctx, listing); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			return s.uploadRepo.Attach(ctx, keys, created.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed duplicate check must not fail the listing; it only means the
+	// listing skips the moderation queue.
+	if len(uploads) > 0 {
+		if err := s.moderation.FlagDuplicates(ctx, created.ID, userID, uploads); err != nil {
+			slog.ErrorContext(ctx, "listing_service.create: duplicate check failed", "listing_id", created.ID, "err", err)
+		}
+	}
+
+	metrics.ListingsCreated.Inc()
+	slog.InfoContext(ctx, "listing_service.create: success", "listing_id", created.ID, "user_id", userID)
+	return created, nil
+}
+
+// Update replaces one of the seller's listings, provided it is still at
+// version, the version the seller's edit started from. Otherwise it fails
+// with repository.ErrListingVersionMismatch rather than overwrite a change
+// made in the meantime, e.g. an image a moderator removed. A zero version
+// replaces whichever version is current.
+func (s *ListingService) Update(ctx context.Context, userID, listingID, version int64, req models.UpdateListingRequest) (*models.Listing, error) {
+	ctx, span := tracer.Start(ctx, "ListingService.Update")
+	defer span.End()
+
+	listing, keys, err := buildListing(userID, models.CreateListingRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	listing.ID = listingID
+
+	// Images dropped from the listing are detached for the orphan sweeper
+	// to delete; only those newly added go through the duplicate check.
+	var (
+		updated *models.Listing
+		added   []models.Upload
+	)
+	err = s.txManager.WithTx(ctx, func(ctx context.Context) error {
+		current, err := s.listingRepo.GetByID(ctx, listingID)
+		if err != nil {
+			return err
+		}
+		if current.UserID != userID {
+			slog.WarnContext(ctx, "listing_service.update: not the seller", "listing_id", listingID, "user_id", userID)
+			return ErrNotListingOwner
+		}
+		if version == 0 {
+			version = current.Version
+		}
+		uploads, err := s.ownedUploads(ctx, userID, listingID, keys)
+		if err != nil {
+			return err
+		}
+		if updated, err = s.listingRepo.Update(ctx, listing, version); err != nil {
+			return err
+		}
+		if err := s.uploadRepo.Detach(ctx, []int64{listingID}); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := s.uploadRepo.Attach(ctx, keys, listingID); err != nil {
+				return err
+			}
+		}
+
+		for _, upload := range uploads {
+			if !slices.ContainsFunc(current.Images, func(image models.ListingImage) bool { return image.URL == "/uploads/"+upload.Key }) {
+				added = append(added, upload)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(added) > 0 {
+		if err := s.moderation.FlagDuplicates(ctx, listingID, userID, added); err != nil {
+			slog.ErrorContext(ctx, "listing_service.update: duplicate check failed", "listing_id", listingID, "err", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "listing_service.update: success", "listing_id", listingID, "user_id", userID, "version", updated.Version)
+	return updated, nil
+}
+
+// buildListing validates a create or update request and returns the listing
+// it describes along with the storage keys of its images.
+func buildListing(userID int64, req models.CreateListingRequest) (*models.Listing, []string, error) {
+	title := strings.TrimSpace(req.Title)
+	category := strings.TrimSpace(req.Category)
+
+	v := validation.New()
+	v.Required("title", title)
+	v.MaxLength("title", title, maxTitleLength)
+	v.Required("category", category)
+	v.MaxLength("category", category, maxCategoryLength)
+	v.OneOf("category", category, models.ListingCategories)
+	v.Decimal("price", req.Price, pricePrecision, priceScale)
+	if len(req.ImageURLs) > maxListingImages {
+		v.Add("image_urls", validation.CodeTooLong, fmt.Sprintf("at most %d images are allowed", maxListingImages))
 	}
-	if strings.TrimSpace(req.Category) == "" {
-		return nil, fmt.Errorf("%w: category is required", ErrValidation)
+	for i, url := range req.ImageURLs {
+		if !uploadURLPattern.MatchString(url) {
+			v.Add(fmt.Sprintf("image_urls[%d]", i), validation.CodeInvalidFormat, "must be a URL returned by the upload endpoint")
+		}
 	}
-	if req.Price < 0 {
-		return nil, fmt.Errorf("%w: price must be greater than or equal to 0", ErrValidation)
+	if err := v.Err(); err != nil {
+		return nil, nil, err
 	}
 
+	keys := make([]string, len(req.ImageURLs))
+	for i, url := range req.ImageURLs {
+		keys[i] = strings.TrimPrefix(url, "/uploads/")
+	}
 	listing := &models.Listing{
 		UserID:      userID,
-		Title:       strings.TrimSpace(req.Title),
+		Title:       title,
 		Description: strings.TrimSpace(req.Description),
 		Price:       req.Price,
-		Category:    strings.TrimSpace(req.Category),
+		Category:    models.CanonicalCategory(category),
+	}
+	for i, url := range req.ImageURLs {
+		listing.Images = append(listing.Images, models.ListingImage{URL: url, IsPrimary: i == 0})
+	}
+	return listing, keys, nil
+}
+
+// ownedUploads returns the uploads behind keys, rejecting images the user
+// did not upload, so a listing cannot claim, and keep from being swept,
+// someone else's file, images already used by a listing other than
+// listingID, which is zero for a new listing, and images a moderator
+// removed.
+func (s *ListingService) ownedUploads(ctx context.Context, userID, listingID int64, keys []string) ([]models.Upload, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	uploads, err := s.uploadRepo.GetByKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.Upload, len(uploads))
+	for _, upload := range uploads {
+		byKey[upload.Key] = upload
 	}
 
-	return s.listingRepo.Create(ctx, listing)
+	v := validation.New()
+	for i, key := range keys {
+		upload, ok := byKey[key]
+		switch {
+		case !ok || upload.OwnerID != userID || upload.Visibility != models.UploadPublic:
+			v.Add(fmt.Sprintf("image_urls[%d]", i), validation.CodeInvalidChoice, "must be an image you uploaded")
+		case upload.ListingID != nil && *upload.ListingID != listingID:
+			v.Add(fmt.Sprintf("image_urls[%d]", i), validation.CodeInvalidChoice, "is already used by another listing")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	for _, upload := range uploads {
+		blocked, err := s.moderation.isBlocked(ctx, upload)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			slog.WarnContext(ctx, "listing_service.owned_uploads: image removed by a moderator", "upload_id", upload.ID, "user_id", userID)
+			return nil, ErrImageBlocked
+		}
+	}
+	return uploads, nil
 }
 
 func (s *ListingService) GetAll(ctx context.Context, search string) ([]models.Listing, error) {
+	ctx, span := tracer.Start(ctx, "ListingService.GetAll")
+	defer span.End()
+
 	return s.listingRepo.GetAll(ctx, search)
 }
 
 func (s *ListingService) GetByID(ctx context.Context, listingID int64) (*models.Listing, error) {
+	ctx, span := tracer.Start(ctx, "ListingService.GetByID")
+	defer span.End()
+
 	return s.listingRepo.GetByID(ctx, listingID)
 }
+
+// Delete soft-deletes one of the seller's own listings. It stays out of
+// every listing query, reports against it are kept, and a moderator can
+// restore it until the retention job purges it.
+func (s *ListingService) Delete(ctx context.Context, userID, listingID int64) error {
+	ctx, span := tracer.Start(ctx, "ListingService.Delete")
+	defer span.End()
+
+	listing, err := s.listingRepo.GetByID(ctx, listingID)
+	if err != nil {
+		return err
+	}
+	if listing.UserID != userID {
+		slog.WarnContext(ctx, "listing_service.delete: not the seller", "listing_id", listingID, "user_id", userID)
+		return ErrNotListingOwner
+	}
+
+	if err := s.listingRepo.SoftDelete(ctx, listingID, s.now()); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "listing_service.delete: success", "listing_id", listingID, "user_id", userID)
+	return nil
+}
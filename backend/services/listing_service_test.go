@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
+)
+
+func TestCreateListing(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	first := e.saveImage(t, seller, 0)
+	second := e.saveImage(t, seller, 1)
+
+	listing, err := e.listing.Create(ctx, seller, models.CreateListingRequest{
+		Title:       "  Road bike ",
+		Description: "Barely used",
+		Price:       120.5,
+		Category:    "other",
+		ImageURLs:   []string{"/uploads/" + first.Key, "/uploads/" + second.Key},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if listing.Title != "Road bike" || listing.Category != "Other" || listing.UserID != seller {
+		t.Errorf("listing = %+v, want trimmed title and canonical category", listing)
+	}
+	if len(listing.Images) != 2 || !listing.Images[0].IsPrimary || listing.Images[1].IsPrimary {
+		t.Errorf("images = %+v, want the first primary", listing.Images)
+	}
+
+	attached, _ := e.uploads.GetByKey(ctx, first.Key)
+	if attached.ListingID == nil || *attached.ListingID != listing.ID {
+		t.Errorf("upload listing = %v, want %d", attached.ListingID, listing.ID)
+	}
+
+	got, err := e.listing.GetByID(ctx, listing.ID)
+	if err != nil || got.ID != listing.ID {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+	all, err := e.listing.GetAll(ctx, "road")
+	if err != nil || len(all) != 1 {
+		t.Errorf("GetAll(road) = %+v, %v", all, err)
+	}
+	if _, err := e.listing.GetByID(ctx, listing.ID+1); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("GetByID(missing) err = %v, want ErrListingNotFound", err)
+	}
+}
+
+func TestCreateListingValidation(t *testing.T) {
+	e := newTestEnv(t)
+	seller := e.createUser(t, "ada@example.edu")
+
+	tests := []struct {
+		name  string
+		req   models.CreateListingRequest
+		field string
+		code  string
+	}{
+		{"missing title", models.CreateListingRequest{Category: "Books", Price: 1}, "title", validation.CodeRequired},
+		{"unknown category", models.CreateListingRequest{Title: "Lamp", Category: "Cars", Price: 1}, "category", validation.CodeInvalidChoice},
+		{"bad image URL", models.CreateListingRequest{Title: "Lamp", Category: "Books", ImageURLs: []string{"https://evil.example/x.jpg"}}, "image_urls[0]", validation.CodeInvalidFormat},
+		{"someone else's image", models.CreateListingRequest{Title: "Lamp", Category: "Books", ImageURLs: []string{"/uploads/0123456789abcdef0123456789abcdef.jpg"}}, "image_urls[0]", validation.CodeInvalidChoice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.listing.Create(context.Background(), seller, tt.req)
+			var fieldErrs validation.Errors
+			if !errors.As(err, &fieldErrs) {
+				t.Fatalf("err = %v, want validation errors", err)
+			}
+			for _, fe := range fieldErrs {
+				if fe.Field == tt.field && fe.Code == tt.code {
+					return
+				}
+			}
+			t.Errorf("errors = %+v, want %s on %s", fieldErrs, tt.code, tt.field)
+		})
+	}
+}
+
+func TestCreateListingRejectsOtherUsersUpload(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser(t, "ada@example.edu")
+	thief := e.createUser(t, "eve@example.edu")
+	upload := e.saveImage(t, owner, 0)
+
+	_, err := e.listing.Create(context.Background(), thief, models.CreateListingRequest{
+		Title:     "Stolen bike",
+		Category:  "Other",
+		ImageURLs: []string{"/uploads/" + upload.Key},
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("err = %v, want validation error", err)
+	}
+}
+
+func TestCreateListingRejectsUploadOfAnotherListing(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	upload := e.saveImage(t, seller, 0)
+	first := e.createListing(t, seller, upload)
+
+	_, err := e.listing.Create(ctx, seller, models.CreateListingRequest{
+		Title:     "Same bike again",
+		Category:  "Other",
+		ImageURLs: []string{"/uploads/" + upload.Key},
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("err = %v, want validation error", err)
+	}
+
+	attached, _ := e.uploads.GetByKey(ctx, upload.Key)
+	if attached.ListingID == nil || *attached.ListingID != first.ID {
+		t.Errorf("upload listing = %v, want %d", attached.ListingID, first.ID)
+	}
+}
+
+// failingAttach is an UploadRepository whose Attach always fails.
+type failingAttach struct {
+	repository.UploadRepository
+}
+
+var errAttach = errors.New("attach failed")
+
+func (failingAttach) Attach(ctx context.Context, keys []string, listingID int64) error {
+	return errAttach
+}
+
+func TestCreateListingRollsBackWhenAttachFails(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	upload := e.saveImage(t, seller, 0)
+
+	svc := NewListingService(e.listings, failingAttach{e.uploads}, e.txManager, e.moderation)
+	_, err := svc.Create(ctx, seller, models.CreateListingRequest{
+		Title: "Road bike", Price: 100, Category: "Other", ImageURLs: []string{"/uploads/" + upload.Key},
+	})
+	if !errors.Is(err, errAttach) {
+		t.Fatalf("create err = %v, want %v", err, errAttach)
+	}
+
+	all, err := e.listing.GetAll(ctx, "")
+	if err != nil || len(all) != 0 {
+		t.Errorf("GetAll = %+v, %v, want no listing after rollback", all, err)
+	}
+}
+
+func TestCreateListingFlagsDuplicateImages(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	scammer := e.createUser(t, "eve@example.edu")
+	seller := e.createUser(t, "ada@example.edu")
+
+	original := e.createListing(t, seller, e.saveImage(t, seller, 0))
+	// The same seller reusing a photo is not flagged, nor are unrelated
+	// pictures.
+	e.createListing(t, seller, e.saveImage(t, seller, 0))
+	e.createListing(t, scammer, e.saveImage(t, scammer, 1))
+	copied := e.createListing(t, scammer, e.saveImage(t, scammer, 0))
+
+	matches, err := e.moderationRepo.ListOpenImageMatches(ctx, 10)
+	if err != nil {
+		t.Fatalf("list matches: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("matches = %+v, want the copy matched against both of the seller's listings", matches)
+	}
+	for _, match := range matches {
+		if match.ListingID != copied.ID || match.MatchedSellerID != seller || match.Distance != 0 {
+			t.Errorf("match = %+v, want listing %d matched to seller %d", match, copied.ID, seller)
+		}
+	}
+	if matches[0].MatchedListingID != original.ID && matches[1].MatchedListingID != original.ID {
+		t.Errorf("matches = %+v, want one against listing %d", matches, original.ID)
+	}
+}
+
+func TestDeleteListing(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	other := e.createUser(t, "bob@example.edu")
+	listing := e.createListing(t, seller)
+
+	if err := e.listing.Delete(ctx, other, listing.ID); !errors.Is(err, ErrNotListingOwner) {
+		t.Fatalf("Delete by another user err = %v, want ErrNotListingOwner", err)
+	}
+	if err := e.listing.Delete(ctx, seller, listing.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := e.listing.GetByID(ctx, listing.ID); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("GetByID(deleted) err = %v, want ErrListingNotFound", err)
+	}
+	if err := e.listing.Delete(ctx, seller, listing.ID); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("Delete(deleted) err = %v, want ErrListingNotFound", err)
+	}
+}
+
+func TestUpdateListing(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	other := e.createUser(t, "bob@example.edu")
+	kept := e.saveImage(t, seller, 0)
+	dropped := e.saveImage(t, seller, 1)
+	added := e.saveImage(t, seller, 2)
+	listing := e.createListing(t, seller, kept, dropped)
+
+	req := models.UpdateListingRequest{
+		Title:     "Road bike, new tyres",
+		Price:     140,
+		Category:  "other",
+		ImageURLs: []string{"/uploads/" + added.Key, "/uploads/" + kept.Key},
+	}
+	if _, err := e.listing.Update(ctx, other, listing.ID, listing.Version, req); !errors.Is(err, ErrNotListingOwner) {
+		t.Fatalf("Update by another user err = %v, want ErrNotListingOwner", err)
+	}
+
+	updated, err := e.listing.Update(ctx, seller, listing.ID, listing.Version, req)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Title != req.Title || updated.Category != "Other" || updated.Version != listing.Version+1 {
+		t.Errorf("updated = %+v", updated)
+	}
+	if len(updated.Images) != 2 || updated.Images[0].URL != req.ImageURLs[0] || !updated.Images[0].IsPrimary {
+		t.Errorf("images = %+v, want the added image first and primary", updated.Images)
+	}
+
+	// The dropped image is left for the orphan sweeper; the others stay
+	// attached.
+	for _, tc := range []struct {
+		upload   *models.Upload
+		attached bool
+	}{{kept, true}, {dropped, false}, {added, true}} {
+		upload, err := e.uploads.GetByKey(ctx, tc.upload.Key)
+		if err != nil {
+			t.Fatalf("GetByKey: %v", err)
+		}
+		if attached := upload.ListingID != nil && *upload.ListingID == listing.ID; attached != tc.attached {
+			t.Errorf("upload %d attached = %v, want %v", upload.ID, attached, tc.attached)
+		}
+	}
+
+	// An edit based on the old version loses instead of overwriting.
+	req.Title = "Stale edit"
+	if _, err := e.listing.Update(ctx, seller, listing.ID, listing.Version, req); !errors.Is(err, repository.ErrListingVersionMismatch) {
+		t.Errorf("Update(stale) err = %v, want ErrListingVersionMismatch", err)
+	}
+	req.Title = ""
+	var fieldErrs validation.Errors
+	if _, err := e.listing.Update(ctx, seller, listing.ID, updated.Version, req); !errors.As(err, &fieldErrs) {
+		t.Errorf("Update(invalid) err = %v, want validation errors", err)
+	}
+	if got, _ := e.listing.GetByID(ctx, listing.ID); got.Title != "Road bike, new tyres" {
+		t.Errorf("title = %q, want the first update kept", got.Title)
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/metrics"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
+)
+
+// similarUploadLimit bounds how many near-duplicates are recorded per image;
+// a stock photo reposted dozens of times needs only a few examples for a
+// moderator to act on. queueLimit bounds each source of the moderation
+// queue.
+const (
+	similarUploadLimit = 20
+	queueLimit         = 200
+)
+
+// ErrSellerDeleted is returned when restoring a listing whose seller's
+// account is itself deleted; the account has to be restored first.
+var ErrSellerDeleted = errors.New("seller account is deleted")
+
+type ModerationService struct {
+	moderationRepo repository.ModerationRepository
+	uploadRepo     repository.UploadRepository
+	listingRepo    repository.ListingRepository
+	reportRepo     repository.ReportRepository
+	userRepo       repository.UserRepository
+	txManager      repository.TxManager
+	// maxDistance is the largest Hamming distance between two perceptual
+	// hashes still treated as the same picture.
+	maxDistance int
+	now         func() time.Time
+}
+
+func NewModerationService(
+	moderationRepo repository.ModerationRepository,
+	uploadRepo repository.UploadRepository,
+	listingRepo repository.ListingRepository,
+	reportRepo repository.ReportRepository,
+	userRepo repository.UserRepository,
+	txManager repository.TxManager,
+	maxDistance int,
+) *ModerationService {
+	return &ModerationService{
+		moderationRepo: moderationRepo,
+		uploadRepo:     uploadRepo,
+		listingRepo:    listingRepo,
+		reportRepo:     reportRepo,
+		userRepo:       userRepo,
+		txManager:      txManager,
+		maxDistance:    maxDistance,
+		now:            time.Now,
+	}
+}
+
+// FlagDuplicates compares the images of a new listing with those on other
+// sellers' listings and queues every near-match for review.
+func (s *ModerationService) FlagDuplicates(ctx context.Context, listingID, sellerID int64, uploads []models.Upload) error {
+	ctx, span := tracer.Start(ctx, "ModerationService.FlagDuplicates")
+	defer span.End()
+
+	var matches []models.ImageMatch
+	for _, upload := range uploads {
+		if upload.PHash == nil {
+			continue
+		}
+		similar, err := s.uploadRepo.FindSimilar(ctx, *upload.PHash, sellerID, s.maxDistance, similarUploadLimit)
+		if err != nil {
+			return err
+		}
+		for _, other := range similar {
+			matches = append(matches, models.ImageMatch{
+				ListingID:       listingID,
+				UploadID:        upload.ID,
+				MatchedUploadID: other.ID,
+				Distance:        imaging.HammingDistance(uint64(*upload.PHash), uint64(*other.PHash)),
+			})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	created, err := s.moderationRepo.CreateImageMatches(ctx, matches)
+	if err != nil {
+		return err
+	}
+
+	metrics.ImageMatchesFlagged.Add(float64(created))
+	slog.InfoContext(ctx, "moderation_service.flag_duplicates: listing flagged", "listing_id", listingID, "seller_id", sellerID, "matches", created)
+	return nil
+}
+
+// isBlocked reports whether a moderator removed the image in upload.
+func (s *ModerationService) isBlocked(ctx context.Context, upload models.Upload) (bool, error) {
+	return s.moderationRepo.IsImageBlocked(ctx, upload.SHA256)
+}
+
+// Queue returns the listings awaiting review, with their reports and open
+// image matches, those with the most signals first.
+func (s *ModerationService) Queue(ctx context.Context) ([]models.ModerationQueueItem, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.Queue")
+	defer span.End()
+
+	matches, err := s.moderationRepo.ListOpenImageMatches(ctx, queueLimit)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.reportRepo.ListRecent(ctx, queueLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[int64]*models.ModerationQueueItem)
+	item := func(listingID int64) *models.ModerationQueueItem {
+		if items[listingID] == nil {
+			items[listingID] = &models.ModerationQueueItem{
+				Reports:      make([]models.Report, 0),
+				ImageMatches: make([]models.ImageMatch, 0),
+			}
+		}
+		return items[listingID]
+	}
+	for _, match := range matches {
+		it := item(match.ListingID)
+		it.ImageMatches = append(it.ImageMatches, match)
+	}
+	for _, report := range reports {
+		it := item(report.ListingID)
+		it.Reports = append(it.Reports, report)
+	}
+	if len(items) == 0 {
+		return make([]models.ModerationQueueItem, 0), nil
+	}
+
+	ids := make([]int64, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	listings, err := s.listingRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := make([]models.ModerationQueueItem, 0, len(listings))
+	for _, listing := range listings {
+		it := items[listing.ID]
+		it.Listing = listing
+		queue = append(queue, *it)
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		si := len(queue[i].Reports) + len(queue[i].ImageMatches)
+		sj := len(queue[j].Reports) + len(queue[j].ImageMatches)
+		if si != sj {
+			return si > sj
+		}
+		return queue[i].Listing.ID < queue[j].Listing.ID
+	})
+
+	return queue, nil
+}
+
+// DismissMatch closes a match the moderator judged harmless, such as the
+// same seller using two accounts or a legitimately shared photo.
+func (s *ModerationService) DismissMatch(ctx context.Context, moderatorID, matchID int64) error {
+	ctx, span := tracer.Start(ctx, "ModerationService.DismissMatch")
+	defer span.End()
+
+	if err := s.moderationRepo.ResolveImageMatch(ctx, matchID, models.MatchDismissed, moderatorID); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "moderation_service.dismiss_match: success", "match_id", matchID, "moderator_id", moderatorID)
+	return nil
+}
+
+// RemoveImage takes an image off every listing showing it, closes its
+// matches and records its hash so the same file cannot be uploaded or
+// shown again. The upload is held, so the stored file is kept as evidence
+// rather than swept, but it is no longer served.
+func (s *ModerationService) RemoveImage(ctx context.Context, moderatorID int64, req models.RemoveImageRequest) (*models.BlockedImage, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.RemoveImage")
+	defer span.End()
+
+	url := strings.TrimSpace(req.ImageURL)
+	v := validation.New()
+	v.Required("image_url", url)
+	if url != "" && !uploadURLPattern.MatchString(url) {
+		v.Add("image_url", validation.CodeInvalidFormat, "must be a listing image URL")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.uploadRepo.GetByKey(ctx, strings.TrimPrefix(url, "/uploads/"))
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := &models.BlockedImage{
+		SHA256:    upload.SHA256,
+		PHash:     upload.PHash,
+		Reason:    strings.TrimSpace(req.Reason),
+		BlockedBy: moderatorID,
+	}
+	var affected int64
+	err = s.txManager.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if affected, err = s.listingRepo.RemoveImage(ctx, url); err != nil {
+			return err
+		}
+		if err := s.uploadRepo.Hold(ctx, upload.ID); err != nil {
+			return err
+		}
+		if err := s.moderationRepo.BlockImage(ctx, blocked); err != nil {
+			return err
+		}
+		return s.moderationRepo.ResolveImageMatchesForUpload(ctx, upload.ID, models.MatchRemoved, moderatorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "moderation_service.remove_image: success", "upload_id", upload.ID, "moderator_id", moderatorID, "listings", affected)
+	return blocked, nil
+}
+
+// DeleteListing soft-deletes any listing, keeping it and its reports as
+// evidence until the retention job purges it.
+func (s *ModerationService) DeleteListing(ctx context.Context, moderatorID, listingID int64) error {
+	ctx, span := tracer.Start(ctx, "ModerationService.DeleteListing")
+	defer span.End()
+
+	if err := s.listingRepo.SoftDelete(ctx, listingID, s.now()); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "moderation_service.delete_listing: success", "listing_id", listingID, "moderator_id", moderatorID)
+	return nil
+}
+
+// RestoreListing undoes the soft delete of a listing whose seller still has
+// an account.
+func (s *ModerationService) RestoreListing(ctx context.Context, moderatorID, listingID int64) (*models.Listing, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.RestoreListing")
+	defer span.End()
+
+	listing, err := s.listingRepo.GetDeleted(ctx, listingID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.GetByID(ctx, listing.UserID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrSellerDeleted
+		}
+		return nil, err
+	}
+
+	if err := s.listingRepo.Restore(ctx, listingID); err != nil {
+		return nil, err
+	}
+	listing.DeletedAt = nil
+
+	slog.InfoContext(ctx, "moderation_service.restore_listing: success", "listing_id", listingID, "moderator_id", moderatorID)
+	return listing, nil
+}
+
+// DeleteUser soft-deletes an account together with its listings. The
+// account can no longer log in; reports it filed or received are kept.
+func (s *ModerationService) DeleteUser(ctx context.Context, moderatorID, userID int64) error {
+	ctx, span := tracer.Start(ctx, "ModerationService.DeleteUser")
+	defer span.End()
+
+	now := s.now()
+	err := s.txManager.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.SoftDelete(ctx, userID, now); err != nil {
+			return err
+		}
+		return s.listingRepo.SoftDeleteBySeller(ctx, userID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "moderation_service.delete_user: success", "user_id", userID, "moderator_id", moderatorID)
+	return nil
+}
+
+// RestoreUser undoes the soft delete of an account and of the listings
+// deleted with it. Listings the seller had deleted before stay deleted.
+func (s *ModerationService) RestoreUser(ctx context.Context, moderatorID, userID int64) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.RestoreUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetDeleted(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Restore(ctx, userID); err != nil {
+			return err
+		}
+		return s.listingRepo.RestoreBySeller(ctx, userID, *user.DeletedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.DeletedAt = nil
+
+	slog.InfoContext(ctx, "moderation_service.restore_user: success", "user_id", userID, "moderator_id", moderatorID)
+	return user, nil
+}
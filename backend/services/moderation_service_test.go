@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
+)
+
+func TestModerationQueue(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	scammer := e.createUser(t, "eve@example.edu")
+	reporter := e.createUser(t, "bob@example.edu")
+
+	e.createListing(t, seller, e.saveImage(t, seller, 0))
+	copied := e.createListing(t, scammer, e.saveImage(t, scammer, 0))
+	reported := e.createListing(t, seller)
+	e.createListing(t, seller)
+	if _, err := e.report.Create(ctx, reported.ID, reporter, models.CreateReportRequest{Reason: "wrong category"}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if _, err := e.report.Create(ctx, copied.ID, reporter, models.CreateReportRequest{Reason: "stolen photo"}); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	queue, err := e.moderation.Queue(ctx)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	if len(queue) != 2 {
+		t.Fatalf("queue = %+v, want the copied and the reported listing", queue)
+	}
+	if queue[0].Listing.ID != copied.ID || len(queue[0].ImageMatches) != 1 || len(queue[0].Reports) != 1 {
+		t.Errorf("first item = %+v, want listing %d with a match and a report", queue[0], copied.ID)
+	}
+	if queue[1].Listing.ID != reported.ID || len(queue[1].ImageMatches) != 0 || len(queue[1].Reports) != 1 {
+		t.Errorf("second item = %+v, want listing %d with a report", queue[1], reported.ID)
+	}
+}
+
+func TestModerationQueueEmpty(t *testing.T) {
+	e := newTestEnv(t)
+	queue, err := e.moderation.Queue(context.Background())
+	if err != nil || queue == nil || len(queue) != 0 {
+		t.Errorf("queue = %#v, %v; want empty, non-nil", queue, err)
+	}
+}
+
+func TestDismissMatch(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	scammer := e.createUser(t, "eve@example.edu")
+	moderator := e.createUser(t, "mod@example.edu")
+	e.createListing(t, seller, e.saveImage(t, seller, 0))
+	e.createListing(t, scammer, e.saveImage(t, scammer, 0))
+
+	matches, _ := e.moderationRepo.ListOpenImageMatches(ctx, 10)
+	if len(matches) != 1 {
+		t.Fatalf("matches = %+v, want 1", matches)
+	}
+	if err := e.moderation.DismissMatch(ctx, moderator, matches[0].ID); err != nil {
+		t.Fatalf("dismiss: %v", err)
+	}
+	if err := e.moderation.DismissMatch(ctx, moderator, matches[0].ID); !errors.Is(err, repository.ErrImageMatchNotFound) {
+		t.Errorf("dismiss again err = %v, want ErrImageMatchNotFound", err)
+	}
+	if queue, _ := e.moderation.Queue(ctx); len(queue) != 0 {
+		t.Errorf("queue = %+v, want empty after dismissing", queue)
+	}
+}
+
+func TestRemoveImage(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	scammer := e.createUser(t, "eve@example.edu")
+	moderator := e.createUser(t, "mod@example.edu")
+	e.createListing(t, seller, e.saveImage(t, seller, 0))
+	stolen := e.saveImage(t, scammer, 0)
+	own := e.saveImage(t, scammer, 1)
+	copied := e.createListing(t, scammer, stolen, own)
+
+	blocked, err := e.moderation.RemoveImage(ctx, moderator, models.RemoveImageRequest{ImageURL: "/uploads/" + stolen.Key, Reason: "stock photo"})
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if blocked.SHA256 != stolen.SHA256 || blocked.BlockedBy != moderator || blocked.Reason != "stock photo" {
+		t.Errorf("blocked = %+v", blocked)
+	}
+
+	listing, _ := e.listings.GetByID(ctx, copied.ID)
+	if len(listing.Images) != 1 || listing.Images[0].URL != "/uploads/"+own.Key || !listing.Images[0].IsPrimary {
+		t.Errorf("images = %+v, want only the seller's own photo, now primary", listing.Images)
+	}
+	if matches, _ := e.moderationRepo.ListOpenImageMatches(ctx, 10); len(matches) != 0 {
+		t.Errorf("open matches = %+v, want none", matches)
+	}
+}
+
+func TestRemovedImageStaysOffListingsAndIsKept(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "eve@example.edu")
+	moderator := e.createUser(t, "mod@example.edu")
+	removed := e.saveImage(t, seller, 0)
+	own := e.saveImage(t, seller, 1)
+	listing := e.createListing(t, seller, removed, own)
+
+	if _, err := e.moderation.RemoveImage(ctx, moderator, models.RemoveImageRequest{ImageURL: "/uploads/" + removed.Key}); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	listing, _ = e.listings.GetByID(ctx, listing.ID)
+
+	// The seller cannot put the image back.
+	req := models.UpdateListingRequest{
+		Title:     listing.Title,
+		Price:     listing.Price,
+		Category:  listing.Category,
+		ImageURLs: []string{"/uploads/" + own.Key, "/uploads/" + removed.Key},
+	}
+	if _, err := e.listing.Update(ctx, seller, listing.ID, listing.Version, req); !errors.Is(err, ErrImageBlocked) {
+		t.Fatalf("re-adding err = %v, want ErrImageBlocked", err)
+	}
+	req.ImageURLs = []string{"/uploads/" + own.Key}
+	if _, err := e.listing.Update(ctx, seller, listing.ID, listing.Version, req); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// Nor does the sweeper delete the evidence once it is off every listing.
+	e.upload.now = func() time.Time { return time.Now().Add(72 * time.Hour) }
+	if deleted, err := e.upload.Sweep(ctx, 48*time.Hour); err != nil || deleted != 0 {
+		t.Errorf("Sweep = %d, %v; want 0", deleted, err)
+	}
+	held, err := e.uploads.GetByKey(ctx, removed.Key)
+	if err != nil {
+		t.Fatalf("removed upload record was swept: %v", err)
+	}
+	if held.HeldAt == nil || held.ListingID != nil {
+		t.Errorf("upload = %+v, want held and detached", held)
+	}
+	if _, err := e.store.Stat(ctx, removed.Key); err != nil {
+		t.Errorf("removed upload file was swept: %v", err)
+	}
+}
+
+func TestRemoveImageValidation(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	moderator := e.createUser(t, "mod@example.edu")
+
+	var fieldErrs validation.Errors
+	if _, err := e.moderation.RemoveImage(ctx, moderator, models.RemoveImageRequest{ImageURL: "https://example.com/x.jpg"}); !errors.As(err, &fieldErrs) {
+		t.Errorf("bad URL err = %v, want validation errors", err)
+	}
+	if _, err := e.moderation.RemoveImage(ctx, moderator, models.RemoveImageRequest{ImageURL: "/uploads/0123456789abcdef0123456789abcdef.jpg"}); !errors.Is(err, repository.ErrUploadNotFound) {
+		t.Errorf("unknown image err = %v, want ErrUploadNotFound", err)
+	}
+}
+
+func TestDeleteAndRestoreUser(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	moderator := e.createUser(t, "mod@example.edu")
+	deletedBefore := e.createListing(t, seller)
+	listing := e.createListing(t, seller)
+
+	if err := e.listing.Delete(ctx, seller, deletedBefore.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	e.moderation.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if err := e.moderation.DeleteUser(ctx, moderator, seller); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := e.users.GetByID(ctx, seller); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("GetByID(deleted user) err = %v, want ErrUserNotFound", err)
+	}
+	if _, err := e.listing.GetByID(ctx, listing.ID); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("GetByID(listing of deleted user) err = %v, want ErrListingNotFound", err)
+	}
+	if _, err := e.moderation.RestoreListing(ctx, moderator, listing.ID); !errors.Is(err, ErrSellerDeleted) {
+		t.Errorf("RestoreListing err = %v, want ErrSellerDeleted", err)
+	}
+
+	restored, err := e.moderation.RestoreUser(ctx, moderator, seller)
+	if err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+	if restored.ID != seller || restored.DeletedAt != nil {
+		t.Errorf("restored = %+v", restored)
+	}
+	if _, err := e.listing.GetByID(ctx, listing.ID); err != nil {
+		t.Errorf("listing deleted with the user was not restored: %v", err)
+	}
+	// The listing the seller deleted themselves stays deleted.
+	if _, err := e.listing.GetByID(ctx, deletedBefore.ID); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("GetByID(listing deleted earlier) err = %v, want ErrListingNotFound", err)
+	}
+}
@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"strings"
 
+	"uniswap-campus-marketplace/metrics"
 	"uniswap-campus-marketplace/models"
 	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/validation"
 )
 
 type ReportService struct {
@@ -22,8 +24,13 @@ func NewReportService(reportRepo repository.ReportRepository, listingRepo reposi
 }
 
 func (s *ReportService) Create(ctx context.Context, listingID, reporterUserID int64, req models.CreateReportRequest) (*models.Report, error) {
-	if strings.TrimSpace(req.Reason) == "" {
-		return nil, fmt.Errorf("%w: reason is required", ErrValidation)
+	ctx, span := tracer.Start(ctx, "ReportService.Create")
+	defer span.End()
+
+	v := validation.New()
+	v.Required("reason", req.Reason)
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
 
 	// Confirm listing exists to return a clean 404 from handler logic.
@@ -37,5 +44,12 @@ func (s *ReportService) Create(ctx context.Context, listingID, reporterUserID in
 		Reason:         strings.TrimSpace(req.Reason),
 	}
 
-	return s.reportRepo.Create(ctx, report)
+	created, err := s.reportRepo.Create(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ReportsFiled.Inc()
+	slog.InfoContext(ctx, "report_service.create: success", "report_id", created.ID, "listing_id", listingID, "reporter_user_id", reporterUserID)
+	return created, nil
 }
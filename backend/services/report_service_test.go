@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+)
+
+func TestCreateReport(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	reporter := e.createUser(t, "bob@example.edu")
+	listing := e.createListing(t, seller)
+
+	report, err := e.report.Create(ctx, listing.ID, reporter, models.CreateReportRequest{Reason: "  looks like a scam "})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if report.ListingID != listing.ID || report.ReporterUserID != reporter || report.Reason != "looks like a scam" {
+		t.Errorf("report = %+v", report)
+	}
+
+	if _, err := e.report.Create(ctx, listing.ID, reporter, models.CreateReportRequest{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("missing reason err = %v, want validation error", err)
+	}
+	if _, err := e.report.Create(ctx, listing.ID+1, reporter, models.CreateReportRequest{Reason: "scam"}); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("missing listing err = %v, want ErrListingNotFound", err)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"uniswap-campus-marketplace/metrics"
+	"uniswap-campus-marketplace/repository"
+)
+
+// purgeBatchSize bounds how many users, listings and uploads one purge
+// deletes; whatever is left waits for the next run.
+const purgeBatchSize = 100
+
+// RetentionService deletes soft-deleted users and listings for good once
+// they have been deleted for longer than the retention period.
+type RetentionService struct {
+	userRepo    repository.UserRepository
+	listingRepo repository.ListingRepository
+	uploadRepo  repository.UploadRepository
+	txManager   repository.TxManager
+	uploads     *UploadService
+	now         func() time.Time
+}
+
+func NewRetentionService(
+	userRepo repository.UserRepository,
+	listingRepo repository.ListingRepository,
+	uploadRepo repository.UploadRepository,
+	txManager repository.TxManager,
+	uploads *UploadService,
+) *RetentionService {
+	return &RetentionService{
+		userRepo:    userRepo,
+		listingRepo: listingRepo,
+		uploadRepo:  uploadRepo,
+		txManager:   txManager,
+		uploads:     uploads,
+		now:         time.Now,
+	}
+}
+
+// Purge deletes users and listings soft-deleted more than retention ago,
+// returning how many of each it deleted. The images of purged listings are
+// handed to the upload sweeper; a purged user's uploads are deleted here,
+// since the schema cascades their records away with the account. So are
+// the files kept as evidence when a user closed their account, whose
+// anonymized row itself is never purged.
+func (s *RetentionService) Purge(ctx context.Context, retention time.Duration) (users, listings int, err error) {
+	ctx, span := tracer.Start(ctx, "RetentionService.Purge")
+	defer span.End()
+
+	cutoff := s.now().Add(-retention)
+
+	listingIDs, err := s.listingRepo.ListDeleted(ctx, cutoff, purgeBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(listingIDs) > 0 {
+		err := s.txManager.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.uploadRepo.Detach(ctx, listingIDs); err != nil {
+				return err
+			}
+			return s.listingRepo.Purge(ctx, listingIDs)
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		listings = len(listingIDs)
+		metrics.RecordsPurged.WithLabelValues("listings").Add(float64(listings))
+	}
+
+	userIDs, err := s.userRepo.ListDeleted(ctx, cutoff, purgeBatchSize)
+	if err != nil {
+		return 0, listings, err
+	}
+	for _, id := range userIDs {
+		if err := s.uploads.DeleteOwnedBy(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "retention_service.purge: deleting uploads failed", "user_id", id, "err", err)
+			continue
+		}
+		if err := s.userRepo.Purge(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "retention_service.purge: purge user failed", "user_id", id, "err", err)
+			continue
+		}
+		users++
+	}
+	metrics.RecordsPurged.WithLabelValues("users").Add(float64(users))
+
+	kept, err := s.uploadRepo.ListOwnedByAnonymized(ctx, cutoff, purgeBatchSize)
+	if err != nil {
+		return users, listings, err
+	}
+	if err := s.uploads.removeAll(ctx, kept); err != nil {
+		slog.ErrorContext(ctx, "retention_service.purge: deleting uploads of closed accounts failed", "err", err)
+	} else if len(kept) > 0 {
+		metrics.RecordsPurged.WithLabelValues("uploads").Add(float64(len(kept)))
+		slog.InfoContext(ctx, "retention_service.purge: purged uploads of closed accounts", "uploads", len(kept))
+	}
+
+	if users > 0 || listings > 0 {
+		slog.InfoContext(ctx, "retention_service.purge: purged soft-deleted records", "users", users, "listings", listings)
+	}
+	return users, listings, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/storage"
+)
+
+func TestRetentionPurge(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	seller := e.createUser(t, "ada@example.edu")
+	leaver := e.createUser(t, "bob@example.edu")
+	moderator := e.createUser(t, "mod@example.edu")
+
+	kept := e.createListing(t, seller)
+	image := e.saveImage(t, seller, 0)
+	deleted := e.createListing(t, seller, image)
+	leaverImage := e.saveImage(t, leaver, 1)
+	e.createListing(t, leaver, leaverImage)
+
+	if err := e.listing.Delete(ctx, seller, deleted.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := e.moderation.DeleteUser(ctx, moderator, leaver); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	// Nothing has been deleted for long enough yet.
+	if users, listings, err := e.retention.Purge(ctx, time.Hour); err != nil || users != 0 || listings != 0 {
+		t.Fatalf("Purge = %d, %d, %v; want nothing purged", users, listings, err)
+	}
+
+	e.retention.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	users, listings, err := e.retention.Purge(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	// The leaver's listing was deleted with the account, so both listings
+	// are purged; in the database the leaver's would go with the account.
+	if users != 1 || listings != 2 {
+		t.Errorf("purged %d users and %d listings, want 1 and 2", users, listings)
+	}
+
+	if _, err := e.listings.GetDeleted(ctx, deleted.ID); !errors.Is(err, repository.ErrListingNotFound) {
+		t.Errorf("GetDeleted(purged listing) err = %v, want ErrListingNotFound", err)
+	}
+	if _, err := e.listing.GetByID(ctx, kept.ID); err != nil {
+		t.Errorf("live listing was purged: %v", err)
+	}
+	if _, err := e.users.GetDeleted(ctx, leaver); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("GetDeleted(purged user) err = %v, want ErrUserNotFound", err)
+	}
+
+	// The purged listing's image is left for the upload sweeper.
+	upload, err := e.uploads.GetByKey(ctx, image.Key)
+	if err != nil {
+		t.Fatalf("GetByKey: %v", err)
+	}
+	if upload.ListingID != nil || upload.AttachedAt != nil {
+		t.Errorf("upload of purged listing = %+v, want detached", upload)
+	}
+
+	// The purged user's files are gone.
+	if _, err := e.uploads.GetByKey(ctx, leaverImage.Key); !errors.Is(err, repository.ErrUploadNotFound) {
+		t.Errorf("GetByKey(purged user's upload) err = %v, want ErrUploadNotFound", err)
+	}
+	if _, err := e.store.Stat(ctx, leaverImage.Key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("stat purged user's upload err = %v, want ErrNotFound", err)
+	}
+}
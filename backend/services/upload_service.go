@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/metrics"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/storage"
+)
+
+var ErrUploadQuotaExceeded = repository.ErrUploadQuotaExceeded
+var ErrImageBlocked = errors.New("image was removed by a moderator")
+
+// ErrImageRemoved is returned when serving an image a moderator removed. Its
+// file is kept as evidence but no longer public.
+var ErrImageRemoved = errors.New("image is no longer available")
+
+// QuotaExceededError reports how much of their quota a user has left. It
+// matches ErrUploadQuotaExceeded with errors.Is.
+type QuotaExceededError struct {
+	Quota     int64
+	Used      int64
+	Requested int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s: %d of %d bytes used, %d requested", ErrUploadQuotaExceeded, e.Used, e.Quota, e.Requested)
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrUploadQuotaExceeded
+}
+
+func (e *QuotaExceededError) Remaining() int64 {
+	return max(e.Quota-e.Used, 0)
+}
+
+// Private uploads live under PrivateKeyPrefix/<owner id>/ and are only ever
+// served through signed URLs.
+const PrivateKeyPrefix = "private/"
+
+// sweepBatchSize bounds how many orphans one sweep deletes, so a backlog is
+// worked off over several runs rather than in one long burst.
+const sweepBatchSize = 500
+
+type UploadService struct {
+	uploadRepo     repository.UploadRepository
+	moderationRepo repository.ModerationRepository
+	store          storage.Storage
+	quotaBytes     int64
+	// blockRemoved refuses uploads identical to an image a moderator has
+	// removed.
+	blockRemoved bool
+	now          func() time.Time
+}
+
+func NewUploadService(uploadRepo repository.UploadRepository, moderationRepo repository.ModerationRepository, store storage.Storage, quotaBytes int64, blockRemoved bool) *UploadService {
+	return &UploadService{
+		uploadRepo:     uploadRepo,
+		moderationRepo: moderationRepo,
+		store:          store,
+		quotaBytes:     quotaBytes,
+		blockRemoved:   blockRemoved,
+		now:            time.Now,
+	}
+}
+
+// Save stores an uploaded image and its variants under a new random key and
+// records it against the owner's quota. The record is written first, so the
+// quota is reserved before any bytes reach storage.
+func (s *UploadService) Save(ctx context.Context, ownerID int64, visibility string, img *imaging.Image, variants map[string]*imaging.Image) (*models.Upload, error) {
+	ctx, span := tracer.Start(ctx, "UploadService.Save")
+	defer span.End()
+
+	key := randomKey(img.Extension)
+	if visibility == models.UploadPrivate {
+		key = PrivateKeyPrefix + strconv.FormatInt(ownerID, 10) + "/" + key
+	}
+
+	objects := map[string]*imaging.Image{key: img}
+	for name, variant := range variants {
+		objects[imaging.VariantFilename(key, name)] = variant
+	}
+	var size int64
+	for _, object := range objects {
+		size += int64(len(object.Data))
+	}
+	sum := sha256.Sum256(img.Data)
+	digest := hex.EncodeToString(sum[:])
+	// The bits are stored as is; only their Hamming distance matters.
+	phash := int64(img.PHash)
+
+	if s.blockRemoved {
+		blocked, err := s.moderationRepo.IsImageBlocked(ctx, digest)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			metrics.UploadsBlocked.Inc()
+			slog.WarnContext(ctx, "upload_service.save: blocked image re-uploaded", "user_id", ownerID, "sha256", digest)
+			return nil, ErrImageBlocked
+		}
+	}
+
+	upload, err := s.uploadRepo.Create(ctx, &models.Upload{
+		OwnerID:     ownerID,
+		Key:         key,
+		SizeBytes:   size,
+		SHA256:      digest,
+		PHash:       &phash,
+		ContentType: img.ContentType,
+		Visibility:  visibility,
+	}, s.quotaBytes)
+	if err != nil {
+		if errors.Is(err, repository.ErrUploadQuotaExceeded) {
+			return nil, s.quotaError(ctx, ownerID, size)
+		}
+		return nil, err
+	}
+
+	var stored []string
+	for objectKey, object := range objects {
+		if err := s.store.Put(ctx, objectKey, bytes.NewReader(object.Data), object.ContentType); err != nil {
+			if rmErr := s.remove(ctx, upload.ID, stored); rmErr != nil {
+				slog.ErrorContext(ctx, "upload_service.save: cleanup failed", "upload_id", upload.ID, "err", rmErr)
+			}
+			return nil, fmt.Errorf("store %s: %w", objectKey, err)
+		}
+		stored = append(stored, objectKey)
+	}
+
+	slog.InfoContext(ctx, "upload_service.save: success", "upload_id", upload.ID, "user_id", ownerID, "key", key, "size", size)
+	return upload, nil
+}
+
+func (s *UploadService) quotaError(ctx context.Context, ownerID, requested int64) error {
+	used, err := s.uploadRepo.UsageByOwner(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	slog.WarnContext(ctx, "upload_service.save: quota exceeded", "user_id", ownerID, "used", used, "requested", requested)
+	return &QuotaExceededError{Quota: s.quotaBytes, Used: used, Requested: requested}
+}
+
+func (s *UploadService) GetByKey(ctx context.Context, key string) (*models.Upload, error) {
+	ctx, span := tracer.Start(ctx, "UploadService.GetByKey")
+	defer span.End()
+
+	return s.uploadRepo.GetByKey(ctx, key)
+}
+
+// CheckServable returns ErrImageRemoved when key, or the original of a
+// variant key, is an image a moderator removed. Keys without an upload
+// record are left for the store to answer.
+func (s *UploadService) CheckServable(ctx context.Context, key string) error {
+	ctx, span := tracer.Start(ctx, "UploadService.CheckServable")
+	defer span.End()
+
+	upload, err := s.uploadRepo.GetByKey(ctx, imaging.OriginalFilename(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrUploadNotFound) {
+			return nil
+		}
+		return err
+	}
+	blocked, err := s.moderationRepo.IsImageBlocked(ctx, upload.SHA256)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrImageRemoved
+	}
+	return nil
+}
+
+// Sweep deletes public uploads that were never attached to a listing and
+// are older than maxAge, returning how many it deleted.
+func (s *UploadService) Sweep(ctx context.Context, maxAge time.Duration) (int, error) {
+	ctx, span := tracer.Start(ctx, "UploadService.Sweep")
+	defer span.End()
+
+	orphans, err := s.uploadRepo.ListUnattached(ctx, s.now().Add(-maxAge), sweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// An orphan may be attached to a listing between listing and deleting
+	// it, so the record goes first, and only if still unattached: the files
+	// of an upload that was claimed in the meantime are kept. A failure to
+	// delete the files then leaves them untracked, which beats a listing
+	// showing images that are gone.
+	deleted := 0
+	for _, upload := range orphans {
+		if err := s.uploadRepo.DeleteUnattached(ctx, upload.ID); err != nil {
+			if !errors.Is(err, repository.ErrUploadNotFound) {
+				slog.ErrorContext(ctx, "upload_service.sweep: delete failed", "upload_id", upload.ID, "key", upload.Key, "err", err)
+			}
+			continue
+		}
+		deleted++
+		for _, key := range storedKeys(upload) {
+			if err := s.store.Delete(ctx, key); err != nil {
+				slog.ErrorContext(ctx, "upload_service.sweep: delete file failed", "upload_id", upload.ID, "key", key, "err", err)
+			}
+		}
+	}
+
+	if deleted > 0 {
+		slog.InfoContext(ctx, "upload_service.sweep: deleted orphaned uploads", "count", deleted)
+	}
+	return deleted, nil
+}
+
+// DeleteOwnedBy deletes every upload of a user, stored files included.
+func (s *UploadService) DeleteOwnedBy(ctx context.Context, ownerID int64) error {
+	ctx, span := tracer.Start(ctx, "UploadService.DeleteOwnedBy")
+	defer span.End()
+
+	uploads, err := s.uploadRepo.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	if err := s.removeAll(ctx, uploads); err != nil {
+		return err
+	}
+
+	if len(uploads) > 0 {
+		slog.InfoContext(ctx, "upload_service.delete_owned_by: success", "user_id", ownerID, "count", len(uploads))
+	}
+	return nil
+}
+
+// DeletePublicOwnedBy deletes the public uploads of a user, stored files
+// included, except images a moderator removed. Those and private uploads,
+// which may be report evidence, are kept until DeleteOwnedBy purges them.
+func (s *UploadService) DeletePublicOwnedBy(ctx context.Context, ownerID int64) error {
+	ctx, span := tracer.Start(ctx, "UploadService.DeletePublicOwnedBy")
+	defer span.End()
+
+	owned, err := s.uploadRepo.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	var uploads []models.Upload
+	for _, upload := range owned {
+		if upload.Visibility != models.UploadPublic || upload.HeldAt != nil {
+			continue
+		}
+		blocked, err := s.moderationRepo.IsImageBlocked(ctx, upload.SHA256)
+		if err != nil {
+			return err
+		}
+		if !blocked {
+			uploads = append(uploads, upload)
+		}
+	}
+	if err := s.removeAll(ctx, uploads); err != nil {
+		return err
+	}
+
+	if len(uploads) > 0 {
+		slog.InfoContext(ctx, "upload_service.delete_public_owned_by: success", "user_id", ownerID, "count", len(uploads), "kept", len(owned)-len(uploads))
+	}
+	return nil
+}
+
+// removeAll removes uploads one at a time, stopping at the first failure.
+func (s *UploadService) removeAll(ctx context.Context, uploads []models.Upload) error {
+	for _, upload := range uploads {
+		if err := s.remove(ctx, upload.ID, storedKeys(upload)); err != nil {
+			return fmt.Errorf("delete upload %d: %w", upload.ID, err)
+		}
+	}
+	return nil
+}
+
+// storedKeys returns the keys of an upload's file and its variants.
+func storedKeys(upload models.Upload) []string {
+	keys := []string{upload.Key}
+	for _, v := range imaging.Variants {
+		keys = append(keys, imaging.VariantFilename(upload.Key, v.Name))
+	}
+	return keys
+}
+
+// remove deletes stored objects, then the record, so a failure leaves the
+// record behind for the next sweep rather than an untracked file.
+func (s *UploadService) remove(ctx context.Context, uploadID int64, keys []string) error {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+	}
+	return s.uploadRepo.Delete(ctx, uploadID)
+}
+
+// randomKey returns an unguessable key, so uploads cannot be found by
+// enumerating timestamps or IDs.
+func randomKey(ext string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b) + ext
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"uniswap-campus-marketplace/imaging"
+	"uniswap-campus-marketplace/models"
+	"uniswap-campus-marketplace/repository"
+	"uniswap-campus-marketplace/storage"
+)
+
+func TestUploadSaveStoresImageAndVariants(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	owner := e.createUser(t, "ada@example.edu")
+
+	upload := e.saveImage(t, owner, 0)
+	if upload.OwnerID != owner || upload.Visibility != models.UploadPublic || upload.PHash == nil || len(upload.SHA256) != 64 {
+		t.Errorf("upload = %+v", upload)
+	}
+
+	keys := []string{upload.Key}
+	for _, v := range imaging.Variants {
+		keys = append(keys, imaging.VariantFilename(upload.Key, v.Name))
+	}
+	var stored int64
+	for _, key := range keys {
+		obj, err := e.store.Stat(ctx, key)
+		if err != nil {
+			t.Errorf("stat %s: %v", key, err)
+			continue
+		}
+		stored += obj.Size
+	}
+	if stored != upload.SizeBytes {
+		t.Errorf("size = %d, want the %d bytes stored across original and variants", upload.SizeBytes, stored)
+	}
+}
+
+func TestUploadSavePrivate(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser(t, "ada@example.edu")
+	_, img := testImage(t, 0)
+
+	upload, err := e.upload.Save(context.Background(), owner, models.UploadPrivate, img, nil)
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !strings.HasPrefix(upload.Key, PrivateKeyPrefix) {
+		t.Errorf("key = %q, want it under %s", upload.Key, PrivateKeyPrefix)
+	}
+}
+
+func TestUploadQuotaExceeded(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser(t, "ada@example.edu")
+	_, img := testImage(t, 0)
+	e.upload.quotaBytes = int64(len(img.Data))*2 - 1
+
+	ctx := context.Background()
+	if _, err := e.upload.Save(ctx, owner, models.UploadPrivate, img, nil); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+	_, err := e.upload.Save(ctx, owner, models.UploadPrivate, img, nil)
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) || !errors.Is(err, ErrUploadQuotaExceeded) {
+		t.Fatalf("err = %v, want QuotaExceededError", err)
+	}
+	size := int64(len(img.Data))
+	if quotaErr.Used != size || quotaErr.Requested != size || quotaErr.Remaining() != size-1 {
+		t.Errorf("quota error = %+v, remaining %d", quotaErr, quotaErr.Remaining())
+	}
+
+	// Nothing is stored for a refused upload.
+	objects, err := e.store.List(ctx, PrivateKeyPrefix)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(objects) != 1 {
+		t.Errorf("stored %d objects, want 1", len(objects))
+	}
+}
+
+func TestUploadSaveRefusesBlockedImage(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	owner := e.createUser(t, "ada@example.edu")
+	moderator := e.createUser(t, "mod@example.edu")
+
+	upload := e.saveImage(t, owner, 0)
+	e.createListing(t, owner, upload)
+	if _, err := e.moderation.RemoveImage(ctx, moderator, models.RemoveImageRequest{ImageURL: "/uploads/" + upload.Key}); err != nil {
+		t.Fatalf("remove image: %v", err)
+	}
+
+	decoded, img := testImage(t, 0)
+	variants, _ := imaging.GenerateVariants(decoded, img.Extension)
+	if _, err := e.upload.Save(ctx, owner, models.UploadPublic, img, variants); !errors.Is(err, ErrImageBlocked) {
+		t.Errorf("err = %v, want ErrImageBlocked", err)
+	}
+
+	e.upload.blockRemoved = false
+	if _, err := e.upload.Save(ctx, owner, models.UploadPublic, img, variants); err != nil {
+		t.Errorf("save with blocking disabled: %v", err)
+	}
+}
+
+func TestUploadSweepDeletesOldOrphans(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	owner := e.createUser(t, "ada@example.edu")
+
+	orphan := e.saveImage(t, owner, 0)
+	attached := e.saveImage(t, owner, 1)
+	e.createListing(t, owner, attached)
+
+	// Nothing is old enough yet.
+	if deleted, err := e.upload.Sweep(ctx, time.Hour); err != nil || deleted != 0 {
+		t.Fatalf("Sweep = %d, %v; want 0", deleted, err)
+	}
+
+	e.upload.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	deleted, err := e.upload.Sweep(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+
+	if _, err := e.uploads.GetByKey(ctx, orphan.Key); err == nil {
+		t.Error("orphan record still exists")
+	}
+	if _, err := e.store.Stat(ctx, orphan.Key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("stat orphan err = %v, want ErrNotFound", err)
+	}
+	if _, err := e.store.Stat(ctx, imaging.VariantFilename(orphan.Key, "thumb")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("stat orphan thumb err = %v, want ErrNotFound", err)
+	}
+
+	rc, _, err := e.store.Get(ctx, attached.Key)
+	if err != nil {
+		t.Fatalf("attached upload was swept: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, rc)
+	rc.Close()
+}
+
+// staleOrphans is an UploadRepository that lists uploads as orphans even
+// after they were attached, as happens when a listing claims one mid-sweep.
+type staleOrphans struct {
+	repository.UploadRepository
+	orphans []models.Upload
+}
+
+func (r staleOrphans) ListUnattached(ctx context.Context, createdBefore time.Time, limit int) ([]models.Upload, error) {
+	return r.orphans, nil
+}
+
+func TestUploadSweepSkipsUploadsAttachedMeanwhile(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	owner := e.createUser(t, "ada@example.edu")
+	upload := e.saveImage(t, owner, 0)
+	e.createListing(t, owner, upload)
+
+	svc := NewUploadService(staleOrphans{e.uploads, []models.Upload{*upload}}, e.moderationRepo, e.store, testQuotaBytes, true)
+	deleted, err := svc.Sweep(ctx, time.Hour)
+	if err != nil || deleted != 0 {
+		t.Fatalf("Sweep = %d, %v; want 0", deleted, err)
+	}
+
+	if _, err := e.uploads.GetByKey(ctx, upload.Key); err != nil {
+		t.Errorf("attached upload record was swept: %v", err)
+	}
+	if _, err := e.store.Stat(ctx, upload.Key); err != nil {
+		t.Errorf("attached upload file was swept: %v", err)
+	}
+}
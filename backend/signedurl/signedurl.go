@@ -0,0 +1,82 @@
+// Package signedurl issues and verifies HMAC-signed, expiring links to
+// private objects. A link may be bound to a user, in which case it is only
+// honoured for requests authenticated as that user.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalid   = errors.New("invalid signature")
+	ErrExpired   = errors.New("signed url expired")
+	ErrWrongUser = errors.New("signed url issued to another user")
+)
+
+// Query parameters carried by a signed URL.
+const (
+	paramExpires   = "expires"
+	paramUser      = "uid"
+	paramSignature = "sig"
+)
+
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret), now: time.Now}
+}
+
+// Sign returns path with a signature granting access to key until ttl from
+// now. A userID of 0 makes the link usable by anyone who holds it.
+func (s *Signer) Sign(path, key string, userID int64, ttl time.Duration) (string, time.Time) {
+	expires := s.now().Add(ttl).Truncate(time.Second)
+	query := url.Values{}
+	query.Set(paramExpires, strconv.FormatInt(expires.Unix(), 10))
+	if userID != 0 {
+		query.Set(paramUser, strconv.FormatInt(userID, 10))
+	}
+	query.Set(paramSignature, s.signature(key, expires.Unix(), userID))
+	return path + "?" + query.Encode(), expires
+}
+
+// Verify checks the signature in query for key. requesterID is the
+// authenticated user making the request, or 0 when anonymous.
+func (s *Signer) Verify(key string, query url.Values, requesterID int64) error {
+	expires, err := strconv.ParseInt(query.Get(paramExpires), 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	var userID int64
+	if uid := query.Get(paramUser); uid != "" {
+		if userID, err = strconv.ParseInt(uid, 10, 64); err != nil || userID <= 0 {
+			return ErrInvalid
+		}
+	}
+
+	want := s.signature(key, expires, userID)
+	if !hmac.Equal([]byte(query.Get(paramSignature)), []byte(want)) {
+		return ErrInvalid
+	}
+	if !s.now().Before(time.Unix(expires, 0)) {
+		return ErrExpired
+	}
+	if userID != 0 && userID != requesterID {
+		return ErrWrongUser
+	}
+	return nil
+}
+
+func (s *Signer) signature(key string, expires, userID int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10) + "\n" + strconv.FormatInt(userID, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,93 @@
+// Package storage stores uploaded files as objects addressed by key, on
+// local disk or in an S3-compatible bucket, so that several replicas can
+// share the same uploads.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Storage is a flat object store. Keys are slash-separated relative paths
+// such as "3f2a….jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get returns the object's content, which the caller must close.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List returns the objects whose keys start with prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// URL is where clients can fetch the object.
+	URL(key string) string
+}
+
+// Backends selectable in configuration.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Config selects and configures a backend.
+type Config struct {
+	Backend string
+	// BaseURL prefixes keys to build object URLs.
+	BaseURL string
+
+	LocalDir string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// New returns the backend named in cfg.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case BackendLocal:
+		return NewLocal(cfg.LocalDir, cfg.BaseURL), nil
+	case BackendS3:
+		return NewS3(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			BaseURL:   cfg.BaseURL,
+		})
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
+
+// ValidateKey rejects keys that could escape the store's root or that
+// backends would interpret differently.
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
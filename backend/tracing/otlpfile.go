@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// otlpFileClient is an otlptrace.Client that writes each batch of spans as
+// one line of OTLP/JSON, an ExportTraceServiceRequest, which is the format
+// the OpenTelemetry Collector's file exporter writes and its otlpjsonfile
+// receiver reads.
+type otlpFileClient struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (c *otlpFileClient) Start(context.Context) error { return nil }
+
+func (c *otlpFileClient) Stop(context.Context) error { return nil }
+
+func (c *otlpFileClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	line, err := protojson.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return fmt.Errorf("encode spans: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write spans: %w", err)
+	}
+	return nil
+}
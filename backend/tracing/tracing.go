@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const serviceName = "uniswap-backend"
+
+// Exporter names accepted by Setup. Both stdout exporters write the
+// OpenTelemetry SDK's stdouttrace JSON, meant for reading and grepping; it
+// is not OTLP, so collectors cannot ingest it. The otlp-file exporter writes
+// OTLP/JSON lines that a collector can.
+const (
+	ExporterNone       = "none"
+	ExporterStdout     = "stdout"
+	ExporterStdoutFile = "stdout-file"
+	ExporterOTLPFile   = "otlp-file"
+)
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. Spans are exported as stdouttrace JSON to stdout or to file,
+// or as OTLP/JSON to file, depending on exporter; "none" keeps propagation
+// but records nothing. The returned function flushes pending spans and must
+// be called on shutdown.
+func Setup(exporter, file string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var (
+		out     io.Writer
+		closeFn = func() error { return nil }
+	)
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		out = os.Stdout
+	case ExporterStdoutFile, ExporterOTLPFile:
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file %s: %w", file, err)
+		}
+		out, closeFn = f, f.Close
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	if exporter == ExporterOTLPFile {
+		exp, err = otlptrace.New(context.Background(), &otlpFileClient{out: out})
+	} else {
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	}
+	if err != nil {
+		_ = closeFn()
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeFn(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
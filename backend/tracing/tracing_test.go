@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestSetupOTLPFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(ExporterOTLPFile, file)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "ListingService.Create")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("open trace file: %v", err)
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var req coltracepb.ExportTraceServiceRequest
+		if err := protojson.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatalf("line %q is not an ExportTraceServiceRequest: %v", scanner.Text(), err)
+		}
+		for _, rs := range req.ResourceSpans {
+			var service string
+			for _, attr := range rs.Resource.GetAttributes() {
+				if attr.Key == "service.name" {
+					service = attr.Value.GetStringValue()
+				}
+			}
+			if service != serviceName {
+				t.Errorf("service.name = %q, want %q", service, serviceName)
+			}
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					names = append(names, s.Name)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read trace file: %v", err)
+	}
+	if len(names) != 1 || names[0] != "ListingService.Create" {
+		t.Errorf("spans = %v, want the one span started", names)
+	}
+}
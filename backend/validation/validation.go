@@ -0,0 +1,140 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalid is matched by errors.Is for every Errors value.
+var ErrInvalid = errors.New("validation failed")
+
+// Error codes reported in FieldError.Code.
+const (
+	CodeRequired         = "required"
+	CodeTooShort         = "too_short"
+	CodeTooLong          = "too_long"
+	CodeInvalidFormat    = "invalid_format"
+	CodeInvalidType      = "invalid_type"
+	CodeOutOfRange       = "out_of_range"
+	CodeInvalidPrecision = "invalid_precision"
+	CodeInvalidChoice    = "invalid_choice"
+)
+
+// FieldError describes why a single request field was rejected. Field uses
+// the JSON name so clients can highlight the matching input.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of field failures returned by a Validator.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Message)
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e Errors) Unwrap() error {
+	return ErrInvalid
+}
+
+// Validator collects field errors. Only the first failure per field is
+// kept, so later checks on an already invalid field are skipped.
+type Validator struct {
+	errors Errors
+	failed map[string]bool
+}
+
+func New() *Validator {
+	return &Validator{failed: make(map[string]bool)}
+}
+
+// Err returns the collected errors, or nil when every check passed.
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+// Add records a failure for field unless it already has one.
+func (v *Validator) Add(field, code, message string) {
+	if v.failed[field] {
+		return
+	}
+	v.failed[field] = true
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (v *Validator) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, CodeRequired, fmt.Sprintf("%s is required", field))
+	}
+}
+
+// MinLength and MaxLength count characters, matching VARCHAR(n) limits.
+func (v *Validator) MinLength(field, value string, min int) {
+	if utf8.RuneCountInString(value) < min {
+		v.Add(field, CodeTooShort, fmt.Sprintf("%s must be at least %d characters", field, min))
+	}
+}
+
+func (v *Validator) MaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, CodeTooLong, fmt.Sprintf("%s must be at most %d characters", field, max))
+	}
+}
+
+// MaxBytes limits the encoded size, e.g. the 72 bytes bcrypt accepts.
+func (v *Validator) MaxBytes(field, value string, max int) {
+	if len(value) > max {
+		v.Add(field, CodeTooLong, fmt.Sprintf("%s must be at most %d bytes", field, max))
+	}
+}
+
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@")+1:], ".") {
+		v.Add(field, CodeInvalidFormat, fmt.Sprintf("%s must be a valid email address", field))
+	}
+}
+
+// Decimal checks value fits a NUMERIC(precision, scale) column and is not
+// negative.
+func (v *Validator) Decimal(field string, value float64, precision, scale int) {
+	max := math.Pow10(precision-scale) - math.Pow10(-scale)
+	if math.IsNaN(value) || value < 0 || value > max {
+		v.Add(field, CodeOutOfRange, fmt.Sprintf("%s must be between 0 and %.*f", field, scale, max))
+		return
+	}
+
+	scaled := value * math.Pow10(scale)
+	if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+		v.Add(field, CodeInvalidPrecision, fmt.Sprintf("%s must have at most %d decimal places", field, scale))
+	}
+}
+
+// OneOf checks value is one of choices, ignoring case.
+func (v *Validator) OneOf(field, value string, choices []string) {
+	if value == "" {
+		return
+	}
+	for _, choice := range choices {
+		if strings.EqualFold(value, choice) {
+			return
+		}
+	}
+	v.Add(field, CodeInvalidChoice, fmt.Sprintf("%s must be one of: %s", field, strings.Join(choices, ", ")))
+}